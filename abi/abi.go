@@ -0,0 +1,343 @@
+package abi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/vm"
+
+	"golang.org/x/crypto/sha3"
+)
+
+//The ABI defines how clients call contract functions. The calldata (FundsTx.Data) is the encoding read by the VM's
+//CALLDATA opcode and produced by CALLEXT: every element is prefixed by its length minus one. The arguments come first,
+//the 4 byte function selector last, such that CALLDATA leaves the selector on top of the arguments:
+//
+//	[len-1][arg 1] ... [len-1][arg n] [3][selector]
+//
+//A function is declared by its signature, e.g., "transfer(address,int)" or "balance(address) returns (int)". The
+//selector is the first 4 bytes of the SHA3-256 hash of the canonical signature without the return types, the hash the
+//SHA3 opcode computes. The return values are the elements on the stack when the contract halts, the last return value
+//on top.
+
+const (
+	SELECTOR_SIZE    = 4
+	MAX_ELEMENT_SIZE = 256
+	ADDRESS_SIZE     = 32
+)
+
+type Type int
+
+const (
+	INT     Type = iota + 1 //Signed integer as the VM's arithmetic expects it: sign byte and big-endian magnitude
+	BYTES                   //1 to 256 bytes as they are
+	ADDRESS                 //32 byte account hash
+	BOOL                    //1 byte, 0 or 1
+)
+
+var typeNames = map[Type]string{
+	INT:     "int",
+	BYTES:   "bytes",
+	ADDRESS: "address",
+	BOOL:    "bool",
+}
+
+func (t Type) String() string {
+	if name, exists := typeNames[t]; exists {
+		return name
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+//Returns the type with the given name.
+func ParseType(name string) (Type, error) {
+	for t, typeName := range typeNames {
+		if typeName == name {
+			return t, nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("Unknown type %v", name))
+}
+
+type Function struct {
+	Name    string
+	Inputs  []Type
+	Outputs []Type
+}
+
+//Parses a signature of the form "name(type,...)" with an optional " returns (type,...)".
+func ParseFunction(signature string) (*Function, error) {
+	signature = strings.TrimSpace(signature)
+
+	var outputs string
+	if i := strings.Index(signature, ")"); i >= 0 {
+		rest := strings.TrimSpace(signature[i+1:])
+		signature = signature[:i+1]
+		if rest != "" {
+			if !strings.HasPrefix(rest, "returns") {
+				return nil, errors.New(fmt.Sprintf("Unexpected %v after the arguments", rest))
+			}
+			outputs = strings.TrimSpace(strings.TrimPrefix(rest, "returns"))
+			if !strings.HasPrefix(outputs, "(") || !strings.HasSuffix(outputs, ")") {
+				return nil, errors.New(fmt.Sprintf("Return types %v are not in parentheses", outputs))
+			}
+		}
+	}
+
+	open := strings.Index(signature, "(")
+	if open < 0 || !strings.HasSuffix(signature, ")") {
+		return nil, errors.New(fmt.Sprintf("Signature %v has no argument list", signature))
+	}
+
+	function := &Function{Name: strings.TrimSpace(signature[:open])}
+	if !isIdentifier(function.Name) {
+		return nil, errors.New(fmt.Sprintf("Invalid function name %v", function.Name))
+	}
+
+	var err error
+	if function.Inputs, err = parseTypes(signature[open:]); err != nil {
+		return nil, err
+	}
+	if outputs != "" {
+		if function.Outputs, err = parseTypes(outputs); err != nil {
+			return nil, err
+		}
+	}
+
+	return function, nil
+}
+
+//Parses a parenthesized, comma separated list of types.
+func parseTypes(list string) (types []Type, err error) {
+	list = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(list, "("), ")"))
+	if list == "" {
+		return nil, nil
+	}
+
+	for _, name := range strings.Split(list, ",") {
+		t, err := ParseType(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+//Returns the canonical signature, i.e., without spaces and return types, e.g., "transfer(address,int)".
+func (f *Function) Signature() string {
+	names := make([]string, len(f.Inputs))
+	for i, t := range f.Inputs {
+		names[i] = t.String()
+	}
+	return f.Name + "(" + strings.Join(names, ",") + ")"
+}
+
+func (f *Function) Selector() (selector [SELECTOR_SIZE]byte) {
+	hasher := sha3.New256()
+	hasher.Write([]byte(f.Signature()))
+	copy(selector[:], hasher.Sum(nil))
+	return selector
+}
+
+func (f *Function) String() string {
+	if len(f.Outputs) == 0 {
+		return f.Signature()
+	}
+	names := make([]string, len(f.Outputs))
+	for i, t := range f.Outputs {
+		names[i] = t.String()
+	}
+	return f.Signature() + " returns (" + strings.Join(names, ",") + ")"
+}
+
+//Encodes the arguments and the selector into the transaction data of a call of the function.
+func (f *Function) EncodeCall(args ...interface{}) ([]byte, error) {
+	if len(args) != len(f.Inputs) {
+		return nil, errors.New(fmt.Sprintf("%v takes %v arguments, got %v", f.Name, len(f.Inputs), len(args)))
+	}
+
+	var data []byte
+	for i, arg := range args {
+		element, err := EncodeValue(f.Inputs[i], arg)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Argument %v of %v: %v", i+1, f.Name, err))
+		}
+		data = appendElement(data, element)
+	}
+
+	selector := f.Selector()
+	return appendElement(data, selector[:]), nil
+}
+
+//Decodes the arguments of a call of the function, the selector must be the function's.
+func (f *Function) DecodeCall(data []byte) ([]interface{}, error) {
+	elements, err := SplitData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := f.Selector()
+	if len(elements) == 0 || !bytes.Equal(elements[len(elements)-1], selector[:]) {
+		return nil, errors.New(fmt.Sprintf("Data is not a call of %v", f.Signature()))
+	}
+
+	return decodeValues(f.Inputs, elements[:len(elements)-1])
+}
+
+//Decodes the return values from the stack of the halted contract (bottom first, as vm.GetStack returns it).
+func (f *Function) DecodeOutputs(stack []protocol.ByteArray) ([]interface{}, error) {
+	if len(stack) < len(f.Outputs) {
+		return nil, errors.New(fmt.Sprintf("%v returns %v values, the stack has %v elements", f.Name, len(f.Outputs), len(stack)))
+	}
+
+	elements := make([][]byte, len(f.Outputs))
+	for i, element := range stack[len(stack)-len(f.Outputs):] {
+		elements[i] = element
+	}
+	return decodeValues(f.Outputs, elements)
+}
+
+func decodeValues(types []Type, elements [][]byte) ([]interface{}, error) {
+	if len(elements) != len(types) {
+		return nil, errors.New(fmt.Sprintf("Expected %v values, got %v", len(types), len(elements)))
+	}
+
+	values := make([]interface{}, len(types))
+	for i, element := range elements {
+		value, err := DecodeValue(types[i], element)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Value %v: %v", i+1, err))
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+//Splits transaction data into its elements, the reverse of appending the elements with their length prefix.
+func SplitData(data []byte) (elements [][]byte, err error) {
+	for i := 0; i < len(data); {
+		length := int(data[i]) + 1
+		if i+1+length > len(data) {
+			return nil, errors.New(fmt.Sprintf("Element at %v exceeds the data", i))
+		}
+		elements = append(elements, data[i+1:i+1+length])
+		i += 1 + length
+	}
+	return elements, nil
+}
+
+func appendElement(data []byte, element []byte) []byte {
+	data = append(data, byte(len(element)-1))
+	return append(data, element...)
+}
+
+//Encodes a value of the type as a stack element. Integers can be given as *big.Int, big.Int, int, int64 or uint64,
+//bytes as []byte or protocol.ByteArray and addresses as [32]byte or []byte.
+func EncodeValue(t Type, value interface{}) (element []byte, err error) {
+	switch t {
+	case INT:
+		var bigInt *big.Int
+		switch v := value.(type) {
+		case *big.Int:
+			bigInt = v
+		case big.Int:
+			bigInt = &v
+		case int:
+			bigInt = big.NewInt(int64(v))
+		case int64:
+			bigInt = big.NewInt(v)
+		case uint64:
+			bigInt = new(big.Int).SetUint64(v)
+		}
+		if bigInt == nil {
+			return nil, errors.New(fmt.Sprintf("%T is not an int", value))
+		}
+		element = vm.SignedByteArrayConversion(*bigInt)
+	case BYTES:
+		switch v := value.(type) {
+		case []byte:
+			element = v
+		case protocol.ByteArray:
+			element = v
+		default:
+			return nil, errors.New(fmt.Sprintf("%T is not bytes", value))
+		}
+		if len(element) == 0 {
+			return nil, errors.New("Empty bytes cannot be encoded")
+		}
+	case ADDRESS:
+		switch v := value.(type) {
+		case [ADDRESS_SIZE]byte:
+			element = v[:]
+		case []byte:
+			element = v
+		default:
+			return nil, errors.New(fmt.Sprintf("%T is not an address", value))
+		}
+		if len(element) != ADDRESS_SIZE {
+			return nil, errors.New(fmt.Sprintf("Address has %v bytes instead of %v", len(element), ADDRESS_SIZE))
+		}
+	case BOOL:
+		v, ok := value.(bool)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("%T is not a bool", value))
+		}
+		element = vm.BoolToByteArray(v)
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown type %v", t))
+	}
+
+	if len(element) > MAX_ELEMENT_SIZE {
+		return nil, errors.New(fmt.Sprintf("Value exceeds %v bytes", MAX_ELEMENT_SIZE))
+	}
+	return element, nil
+}
+
+//Decodes a stack element of the type. Integers are returned as *big.Int, bytes as []byte, addresses as [32]byte and
+//bools as bool.
+func DecodeValue(t Type, element []byte) (interface{}, error) {
+	switch t {
+	case INT:
+		if len(element) == 0 || element[0] > 1 {
+			return nil, errors.New("Int has no valid sign byte")
+		}
+		value := new(big.Int).SetBytes(element[1:])
+		if element[0] == 1 {
+			value.Neg(value)
+		}
+		return value, nil
+	case BYTES:
+		return element, nil
+	case ADDRESS:
+		if len(element) != ADDRESS_SIZE {
+			return nil, errors.New(fmt.Sprintf("Address has %v bytes instead of %v", len(element), ADDRESS_SIZE))
+		}
+		var address [ADDRESS_SIZE]byte
+		copy(address[:], element)
+		return address, nil
+	case BOOL:
+		if len(element) != 1 || element[0] > 1 {
+			return nil, errors.New(fmt.Sprintf("%x is not a bool", element))
+		}
+		return element[0] == 1, nil
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown type %v", t))
+	}
+}
@@ -0,0 +1,180 @@
+package abi
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/vm"
+)
+
+func TestParseFunction(t *testing.T) {
+	function, err := ParseFunction(" transfer( address, int ) returns (bool)")
+	if err != nil {
+		t.Fatalf("Could not parse: %v", err)
+	}
+
+	expected := &Function{Name: "transfer", Inputs: []Type{ADDRESS, INT}, Outputs: []Type{BOOL}}
+	if !reflect.DeepEqual(expected, function) {
+		t.Errorf("Expected function to be '%v' but was '%v'", expected, function)
+	}
+
+	if signature := function.Signature(); signature != "transfer(address,int)" {
+		t.Errorf("Expected signature to be 'transfer(address,int)' but was '%v'", signature)
+	}
+	if s := function.String(); s != "transfer(address,int) returns (bool)" {
+		t.Errorf("Expected string to be 'transfer(address,int) returns (bool)' but was '%v'", s)
+	}
+
+	function, err = ParseFunction("total()")
+	if err != nil || function.Name != "total" || len(function.Inputs) != 0 || len(function.Outputs) != 0 {
+		t.Errorf("Expected function without arguments but got '%v', %v", function, err)
+	}
+}
+
+func TestParseFunctionErrors(t *testing.T) {
+	invalid := []string{
+		"transfer",
+		"transfer(uint)",
+		"1transfer(int)",
+		"(int)",
+		"transfer(int) bool",
+		"transfer(int) returns bool",
+		"transfer(int,)",
+	}
+
+	for _, signature := range invalid {
+		if function, err := ParseFunction(signature); err == nil {
+			t.Errorf("'%v' has been parsed to '%v'", signature, function)
+		}
+	}
+}
+
+//The selector is the hash computed by the SHA3 opcode.
+func TestFunction_Selector(t *testing.T) {
+	function, _ := ParseFunction("transfer(address,int)")
+
+	code := []byte{vm.PUSH, byte(len(function.Signature()) - 1)}
+	code = append(code, function.Signature()...)
+	code = append(code, vm.SHA3, vm.HALT)
+
+	virtualMachine := vm.NewVM(protocol.NewContext(protocol.Account{Contract: code}, protocol.FundsTx{Fee: 1000}))
+	if !virtualMachine.Exec(false) {
+		t.Fatalf("Execution failed: %v", virtualMachine.GetErrorMsg())
+	}
+
+	stack := virtualMachine.GetStack()
+	selector := function.Selector()
+	if !bytes.Equal(selector[:], stack[0][:SELECTOR_SIZE]) {
+		t.Errorf("Expected selector to be '%x' but was '%x'", stack[0][:SELECTOR_SIZE], selector)
+	}
+}
+
+func TestFunction_EncodeCall(t *testing.T) {
+	function, _ := ParseFunction("f(int,bytes,address,bool)")
+	address := [32]byte{1, 2, 3}
+
+	data, err := function.EncodeCall(-300, []byte("Hi"), address, true)
+	if err != nil {
+		t.Fatalf("Could not encode: %v", err)
+	}
+
+	selector := function.Selector()
+	expected := []byte{2, 1, 1, 44, 1, 'H', 'i', 31}
+	expected = append(expected, address[:]...)
+	expected = append(expected, 0, 1, 3)
+	expected = append(expected, selector[:]...)
+	if !bytes.Equal(expected, data) {
+		t.Errorf("Expected data to be '%v' but was '%v'", expected, data)
+	}
+
+	args, err := function.DecodeCall(data)
+	if err != nil {
+		t.Fatalf("Could not decode: %v", err)
+	}
+
+	expectedArgs := []interface{}{big.NewInt(-300), []byte("Hi"), address, true}
+	if !reflect.DeepEqual(expectedArgs, args) {
+		t.Errorf("Expected arguments to be '%v' but were '%v'", expectedArgs, args)
+	}
+}
+
+//The VM's CALLDATA opcode pushes the arguments and the selector on top.
+func TestFunction_EncodeCall_Calldata(t *testing.T) {
+	function, _ := ParseFunction("f(int,bytes)")
+	data, _ := function.EncodeCall(big.NewInt(5), []byte{7})
+
+	context := protocol.NewContext(protocol.Account{Contract: []byte{vm.CALLDATA, vm.HALT}}, protocol.FundsTx{Fee: 1000, Data: data})
+	virtualMachine := vm.NewVM(context)
+	if !virtualMachine.Exec(false) {
+		t.Fatalf("Execution failed: %v", virtualMachine.GetErrorMsg())
+	}
+
+	selector := function.Selector()
+	expected := []protocol.ByteArray{{0, 5}, {7}, selector[:]}
+	if stack := virtualMachine.GetStack(); !reflect.DeepEqual(expected, stack) {
+		t.Errorf("Expected stack to be '%v' but was '%v'", expected, stack)
+	}
+}
+
+func TestFunction_EncodeCallErrors(t *testing.T) {
+	function, _ := ParseFunction("f(int,bytes,address,bool)")
+
+	invalid := [][]interface{}{
+		{1, []byte{1}, [32]byte{}},
+		{"1", []byte{1}, [32]byte{}, true},
+		{1, []byte{}, [32]byte{}, true},
+		{1, make([]byte, MAX_ELEMENT_SIZE+1), [32]byte{}, true},
+		{1, []byte{1}, []byte{1}, true},
+		{1, []byte{1}, [32]byte{}, 1},
+		{new(big.Int).Lsh(big.NewInt(1), 8*MAX_ELEMENT_SIZE), []byte{1}, [32]byte{}, true},
+	}
+
+	for _, args := range invalid {
+		if data, err := function.EncodeCall(args...); err == nil {
+			t.Errorf("%v has been encoded to %v", args, data)
+		}
+	}
+}
+
+func TestFunction_DecodeCallErrors(t *testing.T) {
+	function, _ := ParseFunction("f(int)")
+	other, _ := ParseFunction("g(int)")
+
+	otherData, _ := other.EncodeCall(1)
+	selector := function.Selector()
+
+	invalid := map[string][]byte{
+		"other function": otherData,
+		"no data":        {},
+		"truncated":      {3, 1, 2},
+		"missing value":  append([]byte{3}, selector[:]...),
+		"invalid int":    append([]byte{0, 2, 3}, selector[:]...),
+	}
+
+	for name, data := range invalid {
+		if args, err := function.DecodeCall(data); err == nil {
+			t.Errorf("%v: %v has been decoded to %v", name, data, args)
+		}
+	}
+}
+
+func TestFunction_DecodeOutputs(t *testing.T) {
+	function, _ := ParseFunction("f() returns (int,bool)")
+
+	outputs, err := function.DecodeOutputs([]protocol.ByteArray{{9}, {1, 2}, {1}})
+	if err != nil {
+		t.Fatalf("Could not decode: %v", err)
+	}
+
+	expected := []interface{}{big.NewInt(-2), true}
+	if !reflect.DeepEqual(expected, outputs) {
+		t.Errorf("Expected outputs to be '%v' but were '%v'", expected, outputs)
+	}
+
+	if outputs, err := function.DecodeOutputs([]protocol.ByteArray{{1}}); err == nil {
+		t.Errorf("Outputs have been decoded from a too small stack: %v", outputs)
+	}
+}
@@ -0,0 +1,60 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//Prefix of the labels the dispatcher defines itself, the contract must not use labels with it.
+const DISPATCH_LABEL_PREFIX = "abi_"
+
+//Generates the assembly of a dispatcher, which is put in front of the contract's source. It reads the calldata and
+//jumps to the label with the name of the called function. At the label, the arguments are on the stack, the last
+//argument on top, the selector has been popped. Calls of unknown functions and calls without data fail.
+//
+//	calldata
+//	dup
+//	push 0x<selector>     // balance(address)
+//	eq
+//	jmpif abi_balance
+//	errhalt
+//	abi_balance:
+//	pop
+//	jmp balance
+func Dispatcher(functions []*Function) (string, error) {
+	names := make(map[string]bool)
+	selectors := make(map[[SELECTOR_SIZE]byte]string)
+	for _, function := range functions {
+		if names[function.Name] {
+			return "", errors.New(fmt.Sprintf("Function %v is defined twice", function.Name))
+		}
+		names[function.Name] = true
+
+		selector := function.Selector()
+		if other, exists := selectors[selector]; exists {
+			return "", errors.New(fmt.Sprintf("Functions %v and %v have the same selector", other, function.Signature()))
+		}
+		selectors[selector] = function.Signature()
+	}
+
+	var source strings.Builder
+	source.WriteString("// ABI dispatcher\n")
+	source.WriteString("\tcalldata\n")
+	for _, function := range functions {
+		selector := function.Selector()
+		fmt.Fprintf(&source, "\tdup\n")
+		fmt.Fprintf(&source, "\tpush 0x%x // %v\n", selector, function)
+		fmt.Fprintf(&source, "\teq\n")
+		fmt.Fprintf(&source, "\tjmpif %v%v\n", DISPATCH_LABEL_PREFIX, function.Name)
+	}
+	source.WriteString("\terrhalt\n")
+
+	for _, function := range functions {
+		fmt.Fprintf(&source, "%v%v:\n", DISPATCH_LABEL_PREFIX, function.Name)
+		fmt.Fprintf(&source, "\tpop\n")
+		fmt.Fprintf(&source, "\tjmp %v\n", function.Name)
+	}
+
+	return source.String(), nil
+}
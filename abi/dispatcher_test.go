@@ -0,0 +1,78 @@
+package abi
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/asm"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/vm"
+)
+
+func TestDispatcher(t *testing.T) {
+	add, _ := ParseFunction("add(int,int) returns (int)")
+	negate, _ := ParseFunction("negate(bool) returns (bool)")
+
+	dispatcher, err := Dispatcher([]*Function{add, negate})
+	if err != nil {
+		t.Fatalf("Could not generate the dispatcher: %v", err)
+	}
+
+	code, err := asm.Assemble(dispatcher + `
+		add:
+			add
+			halt
+		negate:
+			push 0x00
+			eq
+			halt
+	`)
+	if err != nil {
+		t.Fatalf("Could not assemble: %v\n%v", err, dispatcher)
+	}
+
+	tests := []struct {
+		function *Function
+		args     []interface{}
+		expected []interface{}
+	}{
+		{add, []interface{}{2, -5}, []interface{}{big.NewInt(-3)}},
+		{negate, []interface{}{false}, []interface{}{true}},
+		{negate, []interface{}{true}, []interface{}{false}},
+	}
+
+	for _, test := range tests {
+		data, _ := test.function.EncodeCall(test.args...)
+		context := protocol.NewContext(protocol.Account{Contract: code}, protocol.FundsTx{Fee: 1000, Data: data})
+		virtualMachine := vm.NewVM(context)
+		if !virtualMachine.Exec(false) {
+			t.Fatalf("Execution of %v failed: %v", test.function, virtualMachine.GetErrorMsg())
+		}
+
+		outputs, err := test.function.DecodeOutputs(virtualMachine.GetStack())
+		if err != nil {
+			t.Fatalf("Could not decode the outputs of %v: %v", test.function, err)
+		}
+		if !reflect.DeepEqual(test.expected, outputs) {
+			t.Errorf("Expected outputs of %v%v to be '%v' but were '%v'", test.function.Name, test.args, test.expected, outputs)
+		}
+	}
+
+	unknown, _ := ParseFunction("unknown(int)")
+	data, _ := unknown.EncodeCall(1)
+	virtualMachine := vm.NewVM(protocol.NewContext(protocol.Account{Contract: code}, protocol.FundsTx{Fee: 1000, Data: data}))
+	if virtualMachine.Exec(false) {
+		t.Errorf("Call of an unknown function succeeded")
+	}
+}
+
+func TestDispatcherErrors(t *testing.T) {
+	f, _ := ParseFunction("f(int)")
+	overloaded, _ := ParseFunction("f(bytes)")
+
+	if dispatcher, err := Dispatcher([]*Function{f, overloaded}); err == nil || !strings.Contains(err.Error(), "twice") {
+		t.Errorf("Dispatcher of overloaded functions has been generated (%v):\n%v", err, dispatcher)
+	}
+}
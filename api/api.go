@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"log"
+	"net/http"
+)
+
+var (
+	logger *log.Logger
+)
+
+//Entry function for the api package. Serves the HTTP/JSON interface on the given address in the background.
+func Init(ipport string) {
+	logger = storage.InitLogger()
+
+	server := &http.Server{
+		Addr:    ipport,
+		Handler: newRouter(),
+	}
+
+	go func() {
+		logger.Printf("Starting HTTP API at %v\n", ipport)
+		if err := server.ListenAndServe(); err != nil {
+			logger.Printf("HTTP API stopped: %v\n", err)
+		}
+	}()
+}
+
+func newRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/block/last", getLastBlock)
+	mux.HandleFunc("/block/hash/", getBlockByHash)
+	mux.HandleFunc("/block/height/", getBlockByHeight)
+	mux.HandleFunc("/tx/", handleTx)
+	mux.HandleFunc("/account/", getAccount)
+	mux.HandleFunc("/call/", callContract)
+	mux.HandleFunc("/estimate/", estimateGas)
+	mux.HandleFunc("/receipt/", getReceipt)
+	mux.HandleFunc("/receipts", getReceiptsByTopic)
+	mux.HandleFunc("/parameters", getParameters)
+
+	return mux
+}
+
+type errorResponse struct {
+	Error  string `json:"error"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, content interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(content); err != nil {
+		logger.Printf("Could not encode HTTP response: %v\n", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+//Only GET is allowed on query endpoints.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		writeError(w, http.StatusMethodNotAllowed, errors.New(fmt.Sprintf("Method %v not allowed.", r.Method)))
+		return false
+	}
+
+	return true
+}
+
+func decodeHash(s string) (hash [32]byte, err error) {
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return hash, errors.New(fmt.Sprintf("Invalid hex string: %v", s))
+	}
+	if len(decoded) != 32 {
+		return hash, errors.New(fmt.Sprintf("Hash must be 32 bytes, was %v.", len(decoded)))
+	}
+	copy(hash[:], decoded)
+
+	return hash, nil
+}
+
+func decodeAddress(s string) (address [64]byte, err error) {
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return address, errors.New(fmt.Sprintf("Invalid hex string: %v", s))
+	}
+	if len(decoded) != 64 {
+		return address, errors.New(fmt.Sprintf("Address must be 64 bytes, was %v.", len(decoded)))
+	}
+	copy(address[:], decoded)
+
+	return address, nil
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/hex"
+	"github.com/bazo-blockchain/bazo-miner/miner"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/vm"
+)
+
+//Blocks, accounts and transactions are served in their JSON representation of the protocol package. Accounts and
+//transactions are wrapped, such that their hash and what the node knows about them is part of the response.
+type accountJSON struct {
+	Hash    string            `json:"hash"`
+	IsRoot  bool              `json:"isRoot"`
+	Account *protocol.Account `json:"account"`
+}
+
+type txJSON struct {
+	Type string               `json:"type"`
+	Hash string               `json:"hash"`
+	Tx   protocol.Transaction `json:"tx"`
+}
+
+func toAccountJSON(acc *protocol.Account, isRoot bool) accountJSON {
+	accHash := acc.Hash()
+	return accountJSON{
+		Hash:    hex.EncodeToString(accHash[:]),
+		IsRoot:  isRoot,
+		Account: acc,
+	}
+}
+
+func toTxJSON(tx protocol.Transaction) txJSON {
+	txHash := tx.Hash()
+	response := txJSON{Hash: hex.EncodeToString(txHash[:]), Tx: tx}
+
+	switch tx.(type) {
+	case *protocol.FundsTx:
+		response.Type = "funds"
+	case *protocol.AccTx:
+		response.Type = "acc"
+	case *protocol.ConfigTx:
+		response.Type = "config"
+	case *protocol.StakeTx:
+		response.Type = "stake"
+	case *protocol.AggTx:
+		response.Type = "aggregation"
+	}
+
+	return response
+}
+
+type callJSON struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	Result  string `json:"result"`
+	GasUsed uint64 `json:"gasUsed"`
+}
+
+func toCallJSON(result *miner.CallResult) callJSON {
+	return callJSON{
+		Success: result.Success,
+		Error:   result.Error,
+		Result:  hex.EncodeToString(result.Result),
+		GasUsed: result.GasUsed,
+	}
+}
+
+type estimateJSON struct {
+	Success bool                    `json:"success"`
+	Error   string                  `json:"error,omitempty"`
+	GasUsed uint64                  `json:"gasUsed"`
+	OpCodes map[string]vm.OpCodeGas `json:"opCodes"`
+}
+
+func toEstimateJSON(estimate *miner.GasEstimate) estimateJSON {
+	return estimateJSON{
+		Success: estimate.Success,
+		Error:   estimate.Error,
+		GasUsed: estimate.GasUsed,
+		OpCodes: estimate.OpCodes,
+	}
+}
+
+//Receipts and events are served with their hashes and bytes in hex.
+type receiptJSON struct {
+	TxHash  string      `json:"txHash"`
+	GasUsed uint64      `json:"gasUsed"`
+	Events  []eventJSON `json:"events"`
+}
+
+type eventJSON struct {
+	Account string   `json:"account"`
+	Topics  []string `json:"topics"`
+	Data    string   `json:"data"`
+}
+
+func toReceiptJSON(receipt *protocol.Receipt) receiptJSON {
+	response := receiptJSON{
+		TxHash:  hex.EncodeToString(receipt.TxHash[:]),
+		GasUsed: receipt.GasUsed,
+		Events:  []eventJSON{},
+	}
+
+	for _, event := range receipt.Events {
+		eventResponse := eventJSON{Account: hex.EncodeToString(event.Account[:]), Topics: []string{}, Data: hex.EncodeToString(event.Data)}
+		for _, topic := range event.Topics {
+			eventResponse.Topics = append(eventResponse.Topics, hex.EncodeToString(topic))
+		}
+		response.Events = append(response.Events, eventResponse)
+	}
+
+	return response
+}
+
+//Contract history entries are served with their hashes in hex, the type as "upgrade" or "selfdestruct".
+type contractHistoryJSON struct {
+	Type        string `json:"type"`
+	BlockHeight uint32 `json:"blockHeight"`
+	TxHash      string `json:"txHash"`
+	OldCode     string `json:"oldCode"`
+	NewCode     string `json:"newCode"`
+	Beneficiary string `json:"beneficiary,omitempty"`
+	Amount      uint64 `json:"amount,omitempty"`
+}
+
+func toContractHistoryJSON(entries []*protocol.ContractHistoryEntry) []contractHistoryJSON {
+	response := []contractHistoryJSON{}
+	for _, entry := range entries {
+		entryResponse := contractHistoryJSON{
+			BlockHeight: entry.BlockHeight,
+			TxHash:      hex.EncodeToString(entry.TxHash[:]),
+			OldCode:     hex.EncodeToString(entry.OldCode[:]),
+			NewCode:     hex.EncodeToString(entry.NewCode[:]),
+		}
+
+		switch entry.Type {
+		case protocol.CONTRACT_UPGRADE:
+			entryResponse.Type = "upgrade"
+		case protocol.CONTRACT_SELFDESTRUCT:
+			entryResponse.Type = "selfdestruct"
+			entryResponse.Beneficiary = hex.EncodeToString(entry.Beneficiary[:])
+			entryResponse.Amount = entry.Amount
+		}
+		response = append(response, entryResponse)
+	}
+
+	return response
+}
+
+type parametersJSON struct {
+	BlockHash          string `json:"blockHash"`
+	FeeMinimum         uint64 `json:"feeMinimum"`
+	BlockSize          uint64 `json:"blockSize"`
+	DiffInterval       uint64 `json:"diffInterval"`
+	BlockInterval      uint64 `json:"blockInterval"`
+	BlockReward        uint64 `json:"blockReward"`
+	StakingMinimum     uint64 `json:"stakingMinimum"`
+	WaitingMinimum     uint64 `json:"waitingMinimum"`
+	AcceptedTimeDiff   uint64 `json:"acceptedTimeDiff"`
+	SlashingWindowSize uint64 `json:"slashingWindowSize"`
+	SlashReward        uint64 `json:"slashReward"`
+}
+
+func toParametersJSON(params *miner.Parameters) parametersJSON {
+	return parametersJSON{
+		BlockHash:          hex.EncodeToString(params.BlockHash[:]),
+		FeeMinimum:         params.Fee_minimum,
+		BlockSize:          params.Block_size,
+		DiffInterval:       params.Diff_interval,
+		BlockInterval:      params.Block_interval,
+		BlockReward:        params.Block_reward,
+		StakingMinimum:     params.Staking_minimum,
+		WaitingMinimum:     params.Waiting_minimum,
+		AcceptedTimeDiff:   params.Accepted_time_diff,
+		SlashingWindowSize: params.Slashing_window_size,
+		SlashReward:        params.Slash_reward,
+	}
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+)
+
+const (
+	TestDBFileName = "test.db"
+	TestIpPort     = "127.0.0.1:8000"
+)
+
+func TestMain(m *testing.M) {
+	storage.Init(TestDBFileName, TestIpPort)
+	storage.DeleteAll()
+
+	//We don't want logging msgs when testing, we have designated messages
+	logger = log.New(ioutil.Discard, "", 0)
+	retCode := m.Run()
+
+	//Teardown
+	storage.TearDown()
+	os.Remove(TestDBFileName)
+	os.Exit(retCode)
+}
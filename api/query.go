@@ -0,0 +1,287 @@
+package api
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/miner"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//GET /block/last
+func getLastBlock(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	block := storage.ReadLastClosedBlock()
+	if block == nil {
+		writeError(w, http.StatusNotFound, errors.New("No closed block available."))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, block)
+}
+
+//GET /block/hash/<hash>, the hash may either be the block hash or the hash without transactions.
+func getBlockByHash(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	hash, err := decodeHash(strings.TrimPrefix(r.URL.Path, "/block/hash/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	block := storage.ReadClosedBlock(hash)
+	if block == nil {
+		block = storage.ReadClosedBlockWithoutTx(hash)
+	}
+	if block == nil {
+		writeError(w, http.StatusNotFound, errors.New(fmt.Sprintf("Block (%x) not found.", hash[0:8])))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, block)
+}
+
+//GET /block/height/<height>
+func getBlockByHeight(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	height, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/block/height/"), 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf("Invalid block height: %v", err)))
+		return
+	}
+
+	block := storage.ReadClosedBlockByHeight(uint32(height))
+	if block == nil {
+		writeError(w, http.StatusNotFound, errors.New(fmt.Sprintf("Block at height %v not found.", height)))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, block)
+}
+
+//Transactions are queried with GET and submitted with POST on the same path.
+func handleTx(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		submitTx(w, r)
+	} else {
+		getTx(w, r)
+	}
+}
+
+//GET /tx/<hash>, only closed transactions are served.
+func getTx(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	hash, err := decodeHash(strings.TrimPrefix(r.URL.Path, "/tx/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tx := storage.ReadClosedTx(hash)
+	if tx == nil {
+		writeError(w, http.StatusNotFound, errors.New(fmt.Sprintf("Tx (%x) not found.", hash[0:8])))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toTxJSON(tx))
+}
+
+//GET /account/<hash>, accepts the account hash as well as the 64 byte address. GET /account/<hash>/history returns
+//the upgrades and the self-destruct of the account's contract.
+func getAccount(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	param := strings.TrimPrefix(r.URL.Path, "/account/")
+	history := strings.HasSuffix(param, "/history")
+	param = strings.TrimSuffix(param, "/history")
+
+	var hash [32]byte
+	var err error
+	if len(param) == 128 {
+		var address [64]byte
+		if address, err = decodeAddress(param); err == nil {
+			hash = protocol.SerializeHashContent(address)
+		}
+	} else {
+		hash, err = decodeHash(param)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	acc, isRoot, err := miner.GetAccount(hash)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if history {
+		writeJSON(w, http.StatusOK, toContractHistoryJSON(storage.ReadContractHistory(hash)))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toAccountJSON(acc, isRoot))
+}
+
+//GET /receipt/<hash> returns the receipt of the contract execution of the tx.
+func getReceipt(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	txHash, err := decodeHash(strings.TrimPrefix(r.URL.Path, "/receipt/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	receipt := storage.ReadReceipt(txHash)
+	if receipt == nil {
+		writeError(w, http.StatusNotFound, errors.New(fmt.Sprintf("Receipt of tx (%x) not found.", txHash[0:8])))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toReceiptJSON(receipt))
+}
+
+//GET /receipts?topic=<hex> returns the receipts with an event which has the topic.
+func getReceiptsByTopic(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	topic, err := hex.DecodeString(r.URL.Query().Get("topic"))
+	if err != nil || len(topic) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf("Invalid topic: %v", r.URL.Query().Get("topic"))))
+		return
+	}
+
+	receipts := []receiptJSON{}
+	for _, receipt := range storage.ReadReceiptsByTopic(topic) {
+		receipts = append(receipts, toReceiptJSON(receipt))
+	}
+
+	writeJSON(w, http.StatusOK, receipts)
+}
+
+//GET /parameters
+func getParameters(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	params := miner.GetActiveParameters()
+	if params == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("Miner not initialized yet."))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toParametersJSON(params))
+}
+
+//Parameters of a contract execution without tx, taken from the path /<endpoint>/<hash> and the query.
+type callQuery struct {
+	contract [32]byte
+	caller   [32]byte
+	amount   uint64
+	data     []byte
+	fee      uint64
+}
+
+//Parses the query of a contract execution, writes the error response if it is invalid.
+func parseCallQuery(w http.ResponseWriter, r *http.Request, prefix string) (call callQuery, ok bool) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return call, false
+	}
+
+	contract, err := decodeHash(strings.TrimPrefix(r.URL.Path, prefix))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return call, false
+	}
+	call.contract = contract
+
+	query := r.URL.Query()
+	if call.data, err = hex.DecodeString(query.Get("data")); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf("Invalid hex string: %v", query.Get("data"))))
+		return call, false
+	}
+
+	if query.Get("caller") != "" {
+		if call.caller, err = decodeHash(query.Get("caller")); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return call, false
+		}
+	}
+
+	call.fee = miner.MAX_CALL_FEE
+	for param, value := range map[string]*uint64{"amount": &call.amount, "fee": &call.fee} {
+		if query.Get(param) == "" {
+			continue
+		}
+		if *value, err = strconv.ParseUint(query.Get(param), 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf("Invalid %v: %v", param, query.Get(param))))
+			return call, false
+		}
+	}
+
+	if _, _, err := miner.GetAccount(call.contract); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return call, false
+	}
+
+	return call, true
+}
+
+//GET /call/<hash>?data=<hex>&caller=<hash>&amount=<amount>&fee=<fee> runs the contract of the account against the
+//current state without a tx. The contract's changes are discarded, the fee defaults to miner.MAX_CALL_FEE.
+func callContract(w http.ResponseWriter, r *http.Request) {
+	call, ok := parseCallQuery(w, r, "/call/")
+	if !ok {
+		return
+	}
+
+	result, err := miner.CallContract(call.contract, call.caller, call.amount, call.data, call.fee)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toCallJSON(result))
+}
+
+//GET /estimate/<hash>?data=<hex>&caller=<hash>&amount=<amount> returns the gas a tx with the data needs, i.e., the
+//fee it has to pay at least.
+func estimateGas(w http.ResponseWriter, r *http.Request) {
+	call, ok := parseCallQuery(w, r, "/estimate/")
+	if !ok {
+		return
+	}
+
+	estimate, err := miner.EstimateGas(call.contract, call.caller, call.amount, call.data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toEstimateJSON(estimate))
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func get(t *testing.T, path string, response interface{}) int {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	if response != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), response); err != nil {
+			t.Fatalf("Could not decode response of %v: %v", path, err)
+		}
+	}
+
+	return rec.Code
+}
+
+func writeTestChain(t *testing.T) (genesis, last *protocol.Block) {
+	genesis = protocol.NewBlock([32]byte{}, 0)
+	genesis.Hash = genesis.HashBlock()
+
+	last = protocol.NewBlock(genesis.Hash, 1)
+	last.Timestamp = 1
+	last.Hash = last.HashBlock()
+
+	for _, block := range []*protocol.Block{genesis, last} {
+		if err := storage.WriteClosedBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	storage.DeleteAllLastClosedBlock()
+	storage.WriteLastClosedBlock(last)
+
+	return genesis, last
+}
+
+func TestGetBlock(t *testing.T) {
+	genesis, last := writeTestChain(t)
+
+	var block protocol.Block
+	if code := get(t, "/block/last", &block); code != http.StatusOK {
+		t.Fatalf("Expected status '%v' but was '%v'", http.StatusOK, code)
+	}
+	if block.Hash != last.Hash {
+		t.Errorf("Expected last block '%x' but was '%x'", last.Hash, block.Hash)
+	}
+
+	if code := get(t, "/block/hash/"+hex.EncodeToString(genesis.Hash[:]), &block); code != http.StatusOK {
+		t.Fatalf("Expected status '%v' but was '%v'", http.StatusOK, code)
+	}
+	if block.Height != 0 {
+		t.Errorf("Expected block height '0' but was '%v'", block.Height)
+	}
+
+	if code := get(t, "/block/height/0", &block); code != http.StatusOK {
+		t.Fatalf("Expected status '%v' but was '%v'", http.StatusOK, code)
+	}
+	if block.Hash != genesis.Hash {
+		t.Errorf("Expected genesis block '%x' but was '%x'", genesis.Hash, block.Hash)
+	}
+
+	if code := get(t, "/block/height/5", nil); code != http.StatusNotFound {
+		t.Errorf("Expected status '%v' but was '%v'", http.StatusNotFound, code)
+	}
+	if code := get(t, "/block/hash/1234", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status '%v' but was '%v'", http.StatusBadRequest, code)
+	}
+}
+
+func TestGetTx(t *testing.T) {
+	tx := &protocol.FundsTx{Amount: 100, Fee: 1, TxCnt: 3}
+	storage.WriteClosedTx(tx)
+	txHash := tx.Hash()
+
+	response := txJSON{Tx: new(protocol.FundsTx)}
+	if code := get(t, "/tx/"+hex.EncodeToString(txHash[:]), &response); code != http.StatusOK {
+		t.Fatalf("Expected status '%v' but was '%v'", http.StatusOK, code)
+	}
+	if response.Type != "funds" {
+		t.Errorf("Expected tx type 'funds' but was '%v'", response.Type)
+	}
+	if response.Hash != hex.EncodeToString(txHash[:]) {
+		t.Errorf("Expected tx hash '%x' but was '%v'", txHash, response.Hash)
+	}
+	if !reflect.DeepEqual(response.Tx, tx) {
+		t.Errorf("Expected tx '%v' but was '%v'", tx, response.Tx)
+	}
+
+	if code := get(t, "/tx/"+hex.EncodeToString(make([]byte, 32)), nil); code != http.StatusNotFound {
+		t.Errorf("Expected status '%v' but was '%v'", http.StatusNotFound, code)
+	}
+}
+
+func TestGetAccount(t *testing.T) {
+	acc := new(protocol.Account)
+	acc.Address[0] = 1
+	acc.Balance = 1000
+	accHash := acc.Hash()
+	storage.State[accHash] = acc
+	defer delete(storage.State, accHash)
+
+	var response accountJSON
+	if code := get(t, "/account/"+hex.EncodeToString(accHash[:]), &response); code != http.StatusOK {
+		t.Fatalf("Expected status '%v' but was '%v'", http.StatusOK, code)
+	}
+	if response.Account.Balance != 1000 {
+		t.Errorf("Expected balance '1000' but was '%v'", response.Account.Balance)
+	}
+
+	if code := get(t, "/account/"+hex.EncodeToString(acc.Address[:]), &response); code != http.StatusOK {
+		t.Fatalf("Expected status '%v' but was '%v'", http.StatusOK, code)
+	}
+	if response.Hash != hex.EncodeToString(accHash[:]) {
+		t.Errorf("Expected account hash '%x' but was '%v'", accHash, response.Hash)
+	}
+
+	if code := get(t, "/account/"+hex.EncodeToString(make([]byte, 32)), nil); code != http.StatusNotFound {
+		t.Errorf("Expected status '%v' but was '%v'", http.StatusNotFound, code)
+	}
+}
+
+func TestGetAccountHistory(t *testing.T) {
+	acc := new(protocol.Account)
+	acc.Address[0] = 2
+	accHash := acc.Hash()
+	storage.State[accHash] = acc
+	defer delete(storage.State, accHash)
+
+	upgrade := &protocol.ContractHistoryEntry{Account: accHash, Type: protocol.CONTRACT_UPGRADE, BlockHeight: 3, TxHash: [32]byte{7}}
+	selfDestruct := &protocol.ContractHistoryEntry{Account: accHash, Type: protocol.CONTRACT_SELFDESTRUCT, BlockHeight: 5, TxHash: [32]byte{8}, Beneficiary: [32]byte{9}, Amount: 10}
+	storage.WriteContractHistory(selfDestruct)
+	storage.WriteContractHistory(upgrade)
+	defer storage.DeleteContractHistory(upgrade.Account, upgrade.BlockHeight, upgrade.TxHash)
+	defer storage.DeleteContractHistory(selfDestruct.Account, selfDestruct.BlockHeight, selfDestruct.TxHash)
+
+	var response []contractHistoryJSON
+	if code := get(t, "/account/"+hex.EncodeToString(accHash[:])+"/history", &response); code != http.StatusOK {
+		t.Fatalf("Expected status '%v' but was '%v'", http.StatusOK, code)
+	}
+	if len(response) != 2 || response[0].Type != "upgrade" || response[1].Type != "selfdestruct" {
+		t.Fatalf("Expected the upgrade and the self-destruct in the order of the blocks but was '%v'", response)
+	}
+	if response[1].Beneficiary != hex.EncodeToString(selfDestruct.Beneficiary[:]) || response[1].Amount != 10 {
+		t.Errorf("Expected the beneficiary and the amount of the self-destruct but was '%v'", response[1])
+	}
+
+	if code := get(t, "/account/"+hex.EncodeToString(make([]byte, 32))+"/history", nil); code != http.StatusNotFound {
+		t.Errorf("Expected status '%v' but was '%v'", http.StatusNotFound, code)
+	}
+}
+
+func TestCallContract(t *testing.T) {
+	acc := new(protocol.Account)
+	acc.Address[0] = 2
+	acc.Contract = []byte{
+		35,    // CALLDATA
+		29, 0, // SLOAD
+		4,     // ADD
+		27, 0, // SSTORE
+		29, 0, // SLOAD
+		50, // HALT
+	}
+	acc.ContractVariables = []protocol.ByteArray{{0, 2}}
+	accHash := acc.Hash()
+	storage.State[accHash] = acc
+	defer delete(storage.State, accHash)
+
+	var response callJSON
+	if code := get(t, "/call/"+hex.EncodeToString(accHash[:])+"?data=01000f&fee=5000", &response); code != http.StatusOK {
+		t.Fatalf("Expected status '%v' but was '%v'", http.StatusOK, code)
+	}
+	if !response.Success || response.GasUsed == 0 {
+		t.Errorf("Expected a successful call which used gas but was %v", response)
+	}
+
+	//Writes are not visible to SLOAD within the same execution and discarded afterwards.
+	if response.Result != "0002" || !reflect.DeepEqual(acc.ContractVariables, []protocol.ByteArray{{0, 2}}) {
+		t.Errorf("Expected result '0002' and unchanged variables but were '%v' and %v", response.Result, acc.ContractVariables)
+	}
+
+	if code := get(t, "/call/"+hex.EncodeToString(accHash[:])+"?data=01000f&fee=5", &response); code != http.StatusOK || response.Success {
+		t.Errorf("Expected a failed call with status '%v' but was '%v': %v", http.StatusOK, code, response)
+	}
+
+	for path, status := range map[string]int{
+		"/call/" + hex.EncodeToString(make([]byte, 32)):       http.StatusNotFound,
+		"/call/" + hex.EncodeToString(accHash[:]) + "?data=0": http.StatusBadRequest,
+		"/call/" + hex.EncodeToString(accHash[:]) + "?fee=x":  http.StatusBadRequest,
+		"/call/01": http.StatusBadRequest,
+	} {
+		if code := get(t, path, nil); code != status {
+			t.Errorf("%v: expected status '%v' but was '%v'", path, status, code)
+		}
+	}
+}
+
+func TestEstimateGas(t *testing.T) {
+	acc := new(protocol.Account)
+	acc.Address[0] = 3
+	acc.Contract = []byte{
+		35,    // CALLDATA
+		29, 0, // SLOAD
+		4,     // ADD
+		27, 0, // SSTORE
+		50, // HALT
+	}
+	acc.ContractVariables = []protocol.ByteArray{{0, 2}}
+	accHash := acc.Hash()
+	storage.State[accHash] = acc
+	defer delete(storage.State, accHash)
+
+	var response estimateJSON
+	if code := get(t, "/estimate/"+hex.EncodeToString(accHash[:])+"?data=01000f", &response); code != http.StatusOK {
+		t.Fatalf("Expected status '%v' but was '%v'", http.StatusOK, code)
+	}
+
+	var total uint64
+	for _, gas := range response.OpCodes {
+		total += gas.Gas
+	}
+	if !response.Success || response.GasUsed != total || response.OpCodes["sstore"].Count != 1 {
+		t.Errorf("Expected a successful estimation with the gas per opcode but was %v", response)
+	}
+
+	if code := get(t, "/estimate/"+hex.EncodeToString(make([]byte, 32)), nil); code != http.StatusNotFound {
+		t.Errorf("Expected status '%v' but was '%v'", http.StatusNotFound, code)
+	}
+}
+
+func TestGetReceipt(t *testing.T) {
+	receipt := &protocol.Receipt{
+		TxHash:  [32]byte{5},
+		GasUsed: 42,
+		Events:  []protocol.Event{{Account: [32]byte{6}, Topics: []protocol.ByteArray{{0xab}}, Data: protocol.ByteArray{1, 2}}},
+	}
+	storage.WriteReceipt(receipt)
+	defer storage.DeleteReceipt(receipt.TxHash)
+
+	var response receiptJSON
+	if code := get(t, "/receipt/"+hex.EncodeToString(receipt.TxHash[:]), &response); code != http.StatusOK {
+		t.Fatalf("Expected status '%v' but was '%v'", http.StatusOK, code)
+	}
+	if !reflect.DeepEqual(response, toReceiptJSON(receipt)) {
+		t.Errorf("Expected receipt '%v' but was '%v'", toReceiptJSON(receipt), response)
+	}
+	if len(response.Events) != 1 || response.Events[0].Topics[0] != "ab" || response.Events[0].Data != "0102" {
+		t.Errorf("Expected the event's bytes in hex but was '%v'", response.Events)
+	}
+
+	if code := get(t, "/receipt/"+hex.EncodeToString(make([]byte, 32)), nil); code != http.StatusNotFound {
+		t.Errorf("Expected status '%v' but was '%v'", http.StatusNotFound, code)
+	}
+}
+
+func TestGetReceiptsByTopic(t *testing.T) {
+	receipt := &protocol.Receipt{TxHash: [32]byte{7}, Events: []protocol.Event{{Topics: []protocol.ByteArray{{0xcd}}}}}
+	storage.WriteReceipt(receipt)
+	defer storage.DeleteReceipt(receipt.TxHash)
+
+	var response []receiptJSON
+	if code := get(t, "/receipts?topic=cd", &response); code != http.StatusOK {
+		t.Fatalf("Expected status '%v' but was '%v'", http.StatusOK, code)
+	}
+	if len(response) != 1 || response[0].TxHash != hex.EncodeToString(receipt.TxHash[:]) {
+		t.Errorf("Expected only the receipt with the topic but was '%v'", response)
+	}
+
+	if code := get(t, "/receipts?topic=ef", &response); code != http.StatusOK || len(response) != 0 {
+		t.Errorf("Expected no receipts but the status was '%v' and the response '%v'", code, response)
+	}
+	if code := get(t, "/receipts?topic=xyz", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status '%v' but was '%v'", http.StatusBadRequest, code)
+	}
+}
+
+func TestGetParameters_NotInitialized(t *testing.T) {
+	if code := get(t, "/parameters", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status '%v' but was '%v'", http.StatusServiceUnavailable, code)
+	}
+}
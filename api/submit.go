@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/miner"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	MAX_TX_BODY_SIZE = 1 << 20
+)
+
+type submitResponse struct {
+	Hash string `json:"hash"`
+}
+
+//POST /tx/<type> with type one of funds, acc, config or stake. The body is the encoded tx, i.e. the same payload
+//a client would put into a *TX_BRDCST packet.
+func submitTx(w http.ResponseWriter, r *http.Request) {
+	txType := strings.TrimPrefix(r.URL.Path, "/tx/")
+
+	encodedTx, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MAX_TX_BODY_SIZE))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf("Could not read request body: %v", err)))
+		return
+	}
+
+	tx, err := decodeTx(txType, encodedTx)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	txHash, err := miner.SubmitTx(tx)
+	if err != nil {
+		if rejection, ok := err.(*miner.TxRejection); ok {
+			status := http.StatusUnprocessableEntity
+			if rejection.Reason == miner.REJECT_NOT_READY {
+				status = http.StatusServiceUnavailable
+			}
+			writeJSON(w, status, errorResponse{rejection.Message, rejection.Reason})
+		} else {
+			writeError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, submitResponse{hex.EncodeToString(txHash[:])})
+}
+
+func decodeTx(txType string, encodedTx []byte) (tx protocol.Transaction, err error) {
+	if len(encodedTx) == 0 {
+		return nil, errors.New("Empty request body.")
+	}
+
+	switch txType {
+	case "funds":
+		var fundsTx *protocol.FundsTx
+		if fundsTx = fundsTx.Decode(encodedTx); fundsTx != nil {
+			tx = fundsTx
+		}
+	case "acc":
+		var accTx *protocol.AccTx
+		if accTx = accTx.Decode(encodedTx); accTx != nil {
+			tx = accTx
+		}
+	case "config":
+		var configTx *protocol.ConfigTx
+		if configTx = configTx.Decode(encodedTx); configTx != nil {
+			tx = configTx
+		}
+	case "stake":
+		var stakeTx *protocol.StakeTx
+		if stakeTx = stakeTx.Decode(encodedTx); stakeTx != nil {
+			tx = stakeTx
+		}
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown tx type: %v", txType))
+	}
+
+	if tx == nil {
+		return nil, errors.New(fmt.Sprintf("Could not decode %v tx.", txType))
+	}
+
+	return tx, nil
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func post(path string, body []byte) (int, errorResponse) {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	var response errorResponse
+	json.Unmarshal(rec.Body.Bytes(), &response)
+
+	return rec.Code, response
+}
+
+func TestSubmitTx_Malformed(t *testing.T) {
+	if code, _ := post("/tx/unknown", []byte{1}); code != http.StatusBadRequest {
+		t.Errorf("Expected status '%v' but was '%v'", http.StatusBadRequest, code)
+	}
+
+	if code, _ := post("/tx/funds", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status '%v' but was '%v'", http.StatusBadRequest, code)
+	}
+
+	//Config txs have a fixed size encoding.
+	if code, _ := post("/tx/config", []byte{1, 2, 3}); code != http.StatusBadRequest {
+		t.Errorf("Expected status '%v' but was '%v'", http.StatusBadRequest, code)
+	}
+}
+
+func TestSubmitTx_NotReady(t *testing.T) {
+	tx := &protocol.FundsTx{Amount: 100, Fee: 1}
+
+	code, response := post("/tx/funds", tx.Encode())
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status '%v' but was '%v'", http.StatusServiceUnavailable, code)
+	}
+	if response.Reason != "not_ready" {
+		t.Errorf("Expected reason 'not_ready' but was '%v'", response.Reason)
+	}
+}
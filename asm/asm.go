@@ -0,0 +1,242 @@
+package asm
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/bazo-blockchain/bazo-miner/vm"
+)
+
+//The assembly language has one instruction per line, the opcode names and argument types are the ones of vm.OpCodes:
+//
+//	// Comments start with two slashes
+//	start:                 // A label marks the address of the next instruction
+//	    push 0x007d        // BYTES: hex (as is), a decimal integer (signed, as the VM expects it) or a "string"
+//	    sload 0            // BYTE: decimal or hex
+//	    jmp start          // LABEL: a label or an address
+//	    callext 0x<64 hex digits> 0x01 0x02 0x03 0x04 1 // ADDR: 32 bytes in hex
+//
+//Every argument type of an opcode takes one argument, e.g., callext takes the address, the four bytes of the function
+//hash and the number of arguments.
+
+const (
+	COMMENT     = "//"
+	LABEL_END   = ":"
+	MAX_BYTES   = 256
+	ADDR_LENGTH = 32
+	LABEL_SIZE  = 2
+)
+
+type instruction struct {
+	line   int
+	opCode vm.OpCode
+	code   byte
+	args   []string
+}
+
+//Returns the opcode with the given name.
+func lookup(name string) (code byte, opCode vm.OpCode, err error) {
+	for i, opCode := range vm.OpCodes {
+		if opCode.Name == name {
+			return byte(i), opCode, nil
+		}
+	}
+	return 0, vm.OpCode{}, errors.New(fmt.Sprintf("Unknown opcode %v", name))
+}
+
+//Translates assembly source into bytecode for the VM.
+func Assemble(source string) ([]byte, error) {
+	labels := make(map[string]int)
+	var instructions []instruction
+
+	//First pass: parse the instructions and compute the addresses of the labels.
+	address := 0
+	for i, line := range strings.Split(source, "\n") {
+		tokens, err := tokenize(line)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Line %v: %v", i+1, err))
+		}
+
+		for len(tokens) > 0 && strings.HasSuffix(tokens[0], LABEL_END) {
+			label := strings.TrimSuffix(tokens[0], LABEL_END)
+			if !isLabel(label) {
+				return nil, errors.New(fmt.Sprintf("Line %v: Invalid label %v", i+1, label))
+			}
+			if _, exists := labels[label]; exists {
+				return nil, errors.New(fmt.Sprintf("Line %v: Label %v is defined twice", i+1, label))
+			}
+			labels[label] = address
+			tokens = tokens[1:]
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+
+		code, opCode, err := lookup(strings.ToLower(tokens[0]))
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Line %v: %v", i+1, err))
+		}
+		if len(tokens)-1 != len(opCode.ArgTypes) {
+			return nil, errors.New(fmt.Sprintf("Line %v: %v expects %v arguments but got %v", i+1, opCode.Name, len(opCode.ArgTypes), len(tokens)-1))
+		}
+
+		instr := instruction{i + 1, opCode, code, tokens[1:]}
+		size, err := instr.size()
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Line %v: %v", i+1, err))
+		}
+
+		instructions = append(instructions, instr)
+		address += size
+	}
+
+	//Second pass: encode the instructions with the label addresses known.
+	var code []byte
+	for _, instr := range instructions {
+		encoded, err := instr.encode(labels)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Line %v: %v", instr.line, err))
+		}
+		code = append(code, encoded...)
+	}
+
+	return code, nil
+}
+
+//Returns the number of bytes of the encoded instruction.
+func (instr instruction) size() (int, error) {
+	size := 1
+	for i, argType := range instr.opCode.ArgTypes {
+		switch argType {
+		case vm.BYTES:
+			bytes, err := parseBytes(instr.args[i])
+			if err != nil {
+				return 0, err
+			}
+			size += 1 + len(bytes)
+		case vm.BYTE:
+			size += 1
+		case vm.LABEL:
+			size += LABEL_SIZE
+		case vm.ADDR:
+			size += ADDR_LENGTH
+		}
+	}
+	return size, nil
+}
+
+func (instr instruction) encode(labels map[string]int) ([]byte, error) {
+	code := []byte{instr.code}
+	for i, argType := range instr.opCode.ArgTypes {
+		arg := instr.args[i]
+		switch argType {
+		case vm.BYTES:
+			bytes, err := parseBytes(arg)
+			if err != nil {
+				return nil, err
+			}
+			code = append(code, byte(len(bytes)-1))
+			code = append(code, bytes...)
+		case vm.BYTE:
+			value, err := strconv.ParseUint(arg, 0, 8)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid byte %v", arg))
+			}
+			code = append(code, byte(value))
+		case vm.LABEL:
+			address, exists := labels[arg]
+			if !exists {
+				value, err := strconv.ParseUint(arg, 0, 16)
+				if err != nil {
+					return nil, errors.New(fmt.Sprintf("Unknown label %v", arg))
+				}
+				address = int(value)
+			}
+			if address > 0xffff {
+				return nil, errors.New(fmt.Sprintf("Label %v at %v is out of the address range", arg, address))
+			}
+			code = append(code, byte(address>>8), byte(address))
+		case vm.ADDR:
+			address, err := hex.DecodeString(strings.TrimPrefix(arg, "0x"))
+			if err != nil || len(address) != ADDR_LENGTH {
+				return nil, errors.New(fmt.Sprintf("Invalid address %v, expected %v bytes in hex", arg, ADDR_LENGTH))
+			}
+			code = append(code, address...)
+		}
+	}
+	return code, nil
+}
+
+//Parses the argument of a BYTES argument type, which must be between 1 and MAX_BYTES bytes long.
+func parseBytes(arg string) (bytes []byte, err error) {
+	switch {
+	case strings.HasPrefix(arg, "\""):
+		str, err := strconv.Unquote(arg)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid string %v", arg))
+		}
+		bytes = []byte(str)
+	case strings.HasPrefix(arg, "0x"):
+		if bytes, err = hex.DecodeString(arg[2:]); err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid hex %v", arg))
+		}
+	default:
+		var value big.Int
+		if _, ok := value.SetString(arg, 10); !ok {
+			return nil, errors.New(fmt.Sprintf("Invalid integer %v", arg))
+		}
+		bytes = vm.SignedByteArrayConversion(value)
+	}
+
+	if len(bytes) == 0 || len(bytes) > MAX_BYTES {
+		return nil, errors.New(fmt.Sprintf("%v must have between 1 and %v bytes", arg, MAX_BYTES))
+	}
+	return bytes, nil
+}
+
+//Splits a line into its tokens, strings in double quotes are single tokens. Comments are removed.
+func tokenize(line string) (tokens []string, err error) {
+	for {
+		line = strings.TrimLeft(line, " \t\r,")
+		if line == "" || strings.HasPrefix(line, COMMENT) {
+			return tokens, nil
+		}
+
+		end := 0
+		if line[0] == '"' {
+			for end = 1; end < len(line) && line[end] != '"'; end++ {
+				if line[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(line) {
+				return nil, errors.New("Unterminated string")
+			}
+			end++
+		} else {
+			end = strings.IndexAny(line, " \t\r,")
+			if end == -1 {
+				end = len(line)
+			}
+		}
+
+		tokens = append(tokens, line[:end])
+		line = line[end:]
+	}
+}
+
+func isLabel(label string) bool {
+	if label == "" {
+		return false
+	}
+	for i, c := range label {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+package asm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/vm"
+)
+
+func TestAssemble(t *testing.T) {
+	source := `
+		// Adds the argument to the first contract variable
+		start:
+			calldata
+			PUSH 125              // Decimal integers are signed
+			push -1
+			push 0x0001ff
+			push "Hi //#"
+			sload 0x00
+			jmpif end
+			callext 0x0101010101010101010101010101010101010101010101010101010101010101 1 2 3 4 0
+			jmp start
+		end: halt
+	`
+
+	expected := []byte{
+		vm.CALLDATA,
+		vm.PUSH, 1, 0, 125,
+		vm.PUSH, 1, 1, 1,
+		vm.PUSH, 2, 0, 1, 255,
+		vm.PUSH, 5, 'H', 'i', ' ', '/', '/', '#',
+		vm.SLOAD, 0,
+		vm.JMPIF, 0, 68,
+		vm.CALLEXT, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 2, 3, 4, 0,
+		vm.JMP, 0, 0,
+		vm.HALT,
+	}
+
+	code, err := Assemble(source)
+	if err != nil {
+		t.Fatalf("Could not assemble: %v", err)
+	}
+
+	if !bytes.Equal(expected, code) {
+		t.Errorf("Expected bytecode to be '%v' but was '%v'", expected, code)
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	invalid := map[string]string{
+		"unknown opcode":      "jump 0",
+		"missing argument":    "sload",
+		"too many arguments":  "halt 1",
+		"unknown label":       "jmp nowhere",
+		"duplicate label":     "a: nop\na: nop",
+		"invalid label":       "1a: nop",
+		"byte too large":      "sload 256",
+		"empty bytes":         "push 0x",
+		"too many bytes":      "push 0x" + strings.Repeat("00", 257),
+		"invalid hex":         "push 0x0",
+		"unterminated string": `push "abc`,
+		"short address":       "callext 0x01 1 2 3 4 0",
+		"label out of range":  strings.Repeat("push 0x"+strings.Repeat("00", 256)+"\n", 255) + "end: jmp end",
+	}
+
+	for name, source := range invalid {
+		if code, err := Assemble(source); err == nil {
+			t.Errorf("%v: '%v' has been assembled to %v", name, source, code)
+		}
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	code := []byte{
+		vm.PUSH, 1, 0, 125,
+		vm.JMPIF, 0, 9,
+		vm.NOP, 0,
+		vm.CALL, 0, 2, 0, // Not the start of an instruction
+		vm.HALT,
+	}
+
+	listing, err := Disassemble(code)
+	if err != nil {
+		t.Fatalf("Could not disassemble: %v", err)
+	}
+
+	for _, expected := range []string{"push 0x007d", "jmpif L0009", "L0009:", "call 2 0", "halt"} {
+		if !strings.Contains(listing, expected) {
+			t.Errorf("Expected '%v' in listing:\n%v", expected, listing)
+		}
+	}
+}
+
+func TestDisassembleErrors(t *testing.T) {
+	invalid := map[string][]byte{
+		"invalid opcode":    {byte(len(vm.OpCodes))},
+		"truncated bytes":   {vm.PUSH, 2, 0},
+		"missing length":    {vm.PUSH},
+		"truncated label":   {vm.JMP, 0},
+		"truncated address": {vm.CALLEXT, 1, 2},
+	}
+
+	for name, code := range invalid {
+		if listing, err := Disassemble(code); err == nil {
+			t.Errorf("%v: %v has been disassembled to:\n%v", name, code, listing)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	//One instruction of every opcode, the jumps go to the first and the last instruction.
+	var code []byte
+	for i, opCode := range vm.OpCodes {
+		code = append(code, byte(i))
+		for _, argType := range opCode.ArgTypes {
+			switch argType {
+			case vm.BYTES:
+				code = append(code, 2, 0, 1, byte(i))
+			case vm.BYTE:
+				code = append(code, byte(i))
+			case vm.LABEL:
+				code = append(code, 0, 0)
+			case vm.ADDR:
+				code = append(code, bytes.Repeat([]byte{byte(i)}, 32)...)
+			}
+		}
+	}
+
+	codes := [][]byte{
+		code,
+		{vm.JMP, 0, 3, vm.HALT},
+		{vm.JMP, 0, 1, vm.HALT},
+		{},
+	}
+
+	for _, code := range codes {
+		listing, err := Disassemble(code)
+		if err != nil {
+			t.Fatalf("Could not disassemble %v: %v", code, err)
+		}
+
+		assembled, err := Assemble(listing)
+		if err != nil {
+			t.Fatalf("Could not assemble listing: %v\n%v", err, listing)
+		}
+
+		if !bytes.Equal(code, assembled) {
+			t.Errorf("Round trip changed the bytecode from '%v' to '%v', listing:\n%v", code, assembled, listing)
+		}
+	}
+}
+
+func TestOpCodeNames(t *testing.T) {
+	names := make(map[string]bool)
+	for i, opCode := range vm.OpCodes {
+		if names[opCode.Name] {
+			t.Errorf("Opcode %v has the name %v of another opcode", i, opCode.Name)
+		}
+		names[opCode.Name] = true
+	}
+}
@@ -0,0 +1,114 @@
+package asm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bazo-blockchain/bazo-miner/vm"
+)
+
+type decodedInstruction struct {
+	address int
+	opCode  vm.OpCode
+	args    [][]byte
+}
+
+//Translates bytecode into an assembly listing which Assemble() translates back into the same bytecode. Jump targets
+//get labels, the address of every instruction is added as comment.
+func Disassemble(code []byte) (string, error) {
+	instructions, err := decode(code)
+	if err != nil {
+		return "", err
+	}
+
+	starts := make(map[int]bool)
+	for _, instr := range instructions {
+		starts[instr.address] = true
+	}
+
+	//Only addresses at which an instruction starts can be labelled.
+	labels := make(map[int]string)
+	for _, instr := range instructions {
+		for i, argType := range instr.opCode.ArgTypes {
+			if argType == vm.LABEL {
+				if target := labelAddress(instr.args[i]); starts[target] {
+					labels[target] = fmt.Sprintf("L%04d", target)
+				}
+			}
+		}
+	}
+
+	var listing strings.Builder
+	for _, instr := range instructions {
+		if label, exists := labels[instr.address]; exists {
+			fmt.Fprintf(&listing, "%v%v\n", label, LABEL_END)
+		}
+
+		args := []string{instr.opCode.Name}
+		for i, argType := range instr.opCode.ArgTypes {
+			arg := instr.args[i]
+			switch argType {
+			case vm.BYTES, vm.ADDR:
+				args = append(args, fmt.Sprintf("0x%x", arg))
+			case vm.BYTE:
+				args = append(args, fmt.Sprintf("%v", arg[0]))
+			case vm.LABEL:
+				if label, exists := labels[labelAddress(arg)]; exists {
+					args = append(args, label)
+				} else {
+					args = append(args, fmt.Sprintf("%v", labelAddress(arg)))
+				}
+			}
+		}
+
+		fmt.Fprintf(&listing, "\t%-40s %v %04d\n", strings.Join(args, " "), COMMENT, instr.address)
+	}
+
+	return listing.String(), nil
+}
+
+//Splits the bytecode into instructions and their arguments.
+func decode(code []byte) (instructions []decodedInstruction, err error) {
+	for pc := 0; pc < len(code); {
+		address := pc
+		if int(code[pc]) >= len(vm.OpCodes) {
+			return nil, errors.New(fmt.Sprintf("Invalid opcode %v at %04d", code[pc], address))
+		}
+		opCode := vm.OpCodes[code[pc]]
+		pc++
+
+		var args [][]byte
+		for _, argType := range opCode.ArgTypes {
+			size := 0
+			switch argType {
+			case vm.BYTES:
+				if pc >= len(code) {
+					return nil, errors.New(fmt.Sprintf("%v at %04d is truncated", opCode.Name, address))
+				}
+				size = int(code[pc]) + 1
+				pc++
+			case vm.BYTE:
+				size = 1
+			case vm.LABEL:
+				size = LABEL_SIZE
+			case vm.ADDR:
+				size = ADDR_LENGTH
+			}
+
+			if pc+size > len(code) {
+				return nil, errors.New(fmt.Sprintf("%v at %04d is truncated", opCode.Name, address))
+			}
+			args = append(args, code[pc:pc+size])
+			pc += size
+		}
+
+		instructions = append(instructions, decodedInstruction{address, opCode, args})
+	}
+
+	return instructions, nil
+}
+
+func labelAddress(arg []byte) int {
+	return int(arg[0])<<8 | int(arg[1])
+}
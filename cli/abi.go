@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/abi"
+	"github.com/urfave/cli"
+	"math/big"
+)
+
+func GetABICommand() cli.Command {
+	return cli.Command {
+		Name:	"abi",
+		Usage:	"encode contract calls and generate function dispatchers",
+		Subcommands: []cli.Command {
+			{
+				Name:		"encode",
+				Usage:		"print the transaction data of a function call in hex",
+				ArgsUsage:	"[--] [arguments...]",
+				Action:	func(c *cli.Context) error {
+					function, err := parseFunctionArg(c)
+					if err != nil {
+						return err
+					}
+
+					if c.NArg() != len(function.Inputs) {
+						return errors.New(fmt.Sprintf("%v takes %v arguments, got %v", function.Name, len(function.Inputs), c.NArg()))
+					}
+
+					var args []interface{}
+					for i, t := range function.Inputs {
+						arg, err := parseABIValue(t, c.Args().Get(i))
+						if err != nil {
+							return err
+						}
+						args = append(args, arg)
+					}
+
+					data, err := function.EncodeCall(args...)
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("%x\n", data)
+					return nil
+				},
+				Flags:	[]cli.Flag {
+					functionFlag,
+				},
+			},
+			{
+				Name:	"decode",
+				Usage:	"print the arguments of a function call",
+				Action:	func(c *cli.Context) error {
+					function, err := parseFunctionArg(c)
+					if err != nil {
+						return err
+					}
+
+					data, err := decodeHexArg("data", c.String("data"))
+					if err != nil {
+						return err
+					}
+
+					args, err := function.DecodeCall(data)
+					if err != nil {
+						return err
+					}
+
+					for i, arg := range args {
+						fmt.Printf("%v: %v\n", function.Inputs[i], formatABIValue(arg))
+					}
+					return nil
+				},
+				Flags:	[]cli.Flag {
+					functionFlag,
+					cli.StringFlag {
+						Name: 	"data",
+						Usage: 	"the transaction data in `HEX`",
+					},
+				},
+			},
+			{
+				Name:	"dispatcher",
+				Usage:	"print the assembly of a dispatcher which jumps to the label of the called function",
+				Action:	func(c *cli.Context) error {
+					var functions []*abi.Function
+					for _, signature := range c.StringSlice("function") {
+						function, err := abi.ParseFunction(signature)
+						if err != nil {
+							return err
+						}
+						functions = append(functions, function)
+					}
+
+					if len(functions) == 0 {
+						return errors.New("argument missing: function")
+					}
+
+					dispatcher, err := abi.Dispatcher(functions)
+					if err != nil {
+						return err
+					}
+
+					fmt.Print(dispatcher)
+					return nil
+				},
+				Flags:	[]cli.Flag {
+					cli.StringSliceFlag {
+						Name: 	"function, f",
+						Usage: 	"the function's `SIGNATURE`, repeat for every function",
+					},
+				},
+			},
+		},
+	}
+}
+
+var functionFlag = cli.StringFlag {
+	Name: 	"function, f",
+	Usage: 	"the function's `SIGNATURE`, e.g., \"transfer(address,int)\"",
+}
+
+func parseFunctionArg(c *cli.Context) (*abi.Function, error) {
+	if !c.IsSet("function") {
+		return nil, errors.New("argument missing: function")
+	}
+	return abi.ParseFunction(c.String("function"))
+}
+
+//Parses an argument given on the command line: ints in decimal, bytes and addresses in hex, bools as true or false.
+func parseABIValue(t abi.Type, s string) (interface{}, error) {
+	switch t {
+	case abi.INT:
+		value, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("invalid int: %v", s))
+		}
+		return value, nil
+	case abi.BYTES:
+		return decodeHexArg("bytes", s)
+	case abi.ADDRESS:
+		value, err := decodeHexArg("address", s)
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	case abi.BOOL:
+		switch s {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, errors.New(fmt.Sprintf("invalid bool: %v", s))
+	}
+	return nil, errors.New(fmt.Sprintf("unknown type %v", t))
+}
+
+func formatABIValue(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return fmt.Sprintf("%x", v)
+	case [32]byte:
+		return fmt.Sprintf("%x", v)
+	}
+	return fmt.Sprintf("%v", value)
+}
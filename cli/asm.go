@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/asm"
+	"github.com/urfave/cli"
+	"io/ioutil"
+	"strings"
+)
+
+func GetAssembleCommand() cli.Command {
+	return cli.Command {
+		Name:	"asm",
+		Usage:	"translate contract assembly into bytecode",
+		Action:	func(c *cli.Context) error {
+			if !c.IsSet("file") {
+				return errors.New("argument missing: file")
+			}
+
+			source, err := ioutil.ReadFile(c.String("file"))
+			if err != nil {
+				return err
+			}
+
+			code, err := asm.Assemble(string(source))
+			if err != nil {
+				return errors.New(fmt.Sprintf("could not assemble %v: %v", c.String("file"), err))
+			}
+
+			if c.IsSet("out") {
+				return ioutil.WriteFile(c.String("out"), code, 0644)
+			}
+
+			fmt.Printf("%x\n", code)
+			return nil
+		},
+		Flags:	[]cli.Flag {
+			cli.StringFlag {
+				Name: 	"file, f",
+				Usage: 	"read the assembly from `FILE`",
+			},
+			cli.StringFlag {
+				Name: 	"out, o",
+				Usage: 	"write the bytecode to `FILE` instead of printing it in hex",
+			},
+		},
+	}
+}
+
+func GetDisassembleCommand() cli.Command {
+	return cli.Command {
+		Name:	"disasm",
+		Usage:	"translate contract bytecode into assembly",
+		Action:	func(c *cli.Context) error {
+			code, err := readBytecode(c)
+			if err != nil {
+				return err
+			}
+
+			listing, err := asm.Disassemble(code)
+			if err != nil {
+				return errors.New(fmt.Sprintf("could not disassemble: %v", err))
+			}
+
+			fmt.Print(listing)
+			return nil
+		},
+		Flags:	bytecodeFlags,
+	}
+}
+
+var bytecodeFlags = []cli.Flag {
+	cli.StringFlag {
+		Name: 	"file, f",
+		Usage: 	"read the bytecode from `FILE`",
+	},
+	cli.StringFlag {
+		Name: 	"code",
+		Usage: 	"the bytecode in `HEX`",
+	},
+}
+
+//Reads the bytecode given either as file or in hex.
+func readBytecode(c *cli.Context) ([]byte, error) {
+	if c.IsSet("file") {
+		return ioutil.ReadFile(c.String("file"))
+	}
+
+	if c.IsSet("code") {
+		code, err := hex.DecodeString(strings.TrimPrefix(c.String("code"), "0x"))
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("invalid bytecode: %v", err))
+		}
+		return code, nil
+	}
+
+	return nil, errors.New("argument missing: file or code")
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/miner"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"github.com/urfave/cli"
+	"os"
+)
+
+func GetExportCommand() cli.Command {
+	return cli.Command {
+		Name:	"export",
+		Usage:	"write the closed chain with all its transactions to a file, the miner must not be running",
+		Action:	func(c *cli.Context) error {
+			if !c.IsSet("file") {
+				return errors.New("argument missing: file")
+			}
+
+			if err := openDatabase(c); err != nil {
+				return err
+			}
+			defer storage.TearDown()
+
+			file, err := os.Create(c.String("file"))
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			blocks, err := miner.Export(file)
+			if err != nil {
+				return errors.New(fmt.Sprintf("could not export chain: %v", err))
+			}
+
+			fmt.Printf("Exported %v blocks to %v\n", blocks, c.String("file"))
+			return nil
+		},
+		Flags:	[]cli.Flag {
+			databaseFlag,
+			cli.StringFlag {
+				Name: 	"file, f",
+				Usage: 	"write the chain to `FILE`",
+			},
+		},
+	}
+}
+
+func GetImportCommand() cli.Command {
+	return cli.Command {
+		Name:	"import",
+		Usage:	"validate an exported chain and apply it to an empty database, without network",
+		Action:	func(c *cli.Context) error {
+			for _, arg := range []string{"file", "rootwallet", "rootcommitment"} {
+				if !c.IsSet(arg) {
+					return errors.New(fmt.Sprintf("argument missing: %v", arg))
+				}
+			}
+
+			rootPrivKey, err := crypto.ExtractECDSAKeyFromFile(c.String("rootwallet"))
+			if err != nil {
+				return err
+			}
+
+			rootCommPrivKey, err := crypto.ExtractRSAKeyFromFile(c.String("rootcommitment"))
+			if err != nil {
+				return err
+			}
+
+			file, err := os.Open(c.String("file"))
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			storage.Init(c.String("database"), "")
+			defer storage.TearDown()
+
+			blocks, err := miner.Import(file, &rootPrivKey.PublicKey, rootCommPrivKey)
+			if err != nil {
+				return errors.New(fmt.Sprintf("could not import chain after %v blocks: %v", blocks, err))
+			}
+
+			fmt.Printf("Imported %v blocks into %v\n", blocks, c.String("database"))
+			return nil
+		},
+		Flags:	[]cli.Flag {
+			cli.StringFlag {
+				Name: 	"file, f",
+				Usage: 	"read the chain from `FILE`",
+			},
+			cli.StringFlag {
+				Name: 	"database, d",
+				Usage: 	"import into the empty database of the disk-based key/value store at `FILE`",
+				Value:	"store.db",
+			},
+			cli.StringFlag {
+				Name: 	"rootwallet",
+				Usage: 	"load root's public key from `FILE`, the chain must have been started with it",
+			},
+			cli.StringFlag {
+				Name: 	"rootcommitment",
+				Usage: 	"load root's RSA public-private key from `FILE`",
+			},
+		},
+	}
+}
@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"github.com/urfave/cli"
+)
+
+var databaseFlag = cli.StringFlag {
+	Name: 	"database, d",
+	Usage: 	"inspect the database of the disk-based key/value store from `FILE`",
+	Value:	"store.db",
+}
+
+func GetInspectCommand() cli.Command {
+	return cli.Command {
+		Name:	"inspect",
+		Usage:	"inspect the database of a miner which is not running",
+		Subcommands: []cli.Command {
+			{
+				Name:	"buckets",
+				Usage:	"list all buckets and their number of entries",
+				Action:	func(c *cli.Context) error {
+					if err := openDatabase(c); err != nil {
+						return err
+					}
+					defer storage.TearDown()
+
+					for _, bucket := range storage.ReadBucketStats() {
+						fmt.Printf("%-25s %v\n", bucket.Name, bucket.Entries)
+					}
+
+					return nil
+				},
+				Flags:	[]cli.Flag {
+					databaseFlag,
+				},
+			},
+			{
+				Name:	"block",
+				Usage:	"print a closed block by hash or height, the last closed block if neither is set",
+				Action:	func(c *cli.Context) error {
+					if err := openDatabase(c); err != nil {
+						return err
+					}
+					defer storage.TearDown()
+
+					var block *protocol.Block
+					if c.IsSet("hash") {
+						hash, err := parseHash(c.String("hash"))
+						if err != nil {
+							return err
+						}
+						if block = storage.ReadClosedBlock(hash); block == nil {
+							block = storage.ReadClosedBlockWithoutTx(hash)
+						}
+					} else if c.IsSet("height") {
+						block = storage.ReadClosedBlockByHeight(uint32(c.Uint("height")))
+					} else {
+						block = storage.ReadLastClosedBlock()
+					}
+
+					if block == nil {
+						return errors.New("block not found")
+					}
+
+					fmt.Printf("%v", block)
+					return nil
+				},
+				Flags:	[]cli.Flag {
+					databaseFlag,
+					cli.StringFlag {
+						Name: 	"hash",
+						Usage: 	"the block's `HASH` (with or without tx) in hex",
+					},
+					cli.UintFlag {
+						Name: 	"height",
+						Usage: 	"the block's `HEIGHT`",
+					},
+				},
+			},
+			{
+				Name:	"tx",
+				Usage:	"print a closed transaction by hash",
+				Action:	func(c *cli.Context) error {
+					if err := openDatabase(c); err != nil {
+						return err
+					}
+					defer storage.TearDown()
+
+					hash, err := parseHash(c.String("hash"))
+					if err != nil {
+						return err
+					}
+
+					tx := storage.ReadClosedTx(hash)
+					if tx == nil {
+						return errors.New("transaction not found")
+					}
+
+					fmt.Printf("%T\n%v\n", tx, tx)
+					return nil
+				},
+				Flags:	[]cli.Flag {
+					databaseFlag,
+					cli.StringFlag {
+						Name: 	"hash",
+						Usage: 	"the transaction's `HASH` in hex",
+					},
+				},
+			},
+			{
+				Name:	"chain",
+				Usage:	"walk the chain from the last closed block back to genesis",
+				Action:	func(c *cli.Context) error {
+					if err := openDatabase(c); err != nil {
+						return err
+					}
+					defer storage.TearDown()
+
+					return walkChain()
+				},
+				Flags:	[]cli.Flag {
+					databaseFlag,
+				},
+			},
+		},
+	}
+}
+
+func openDatabase(c *cli.Context) error {
+	if err := storage.InitReadOnly(c.String("database")); err != nil {
+		return errors.New(fmt.Sprintf("could not open database %v: %v", c.String("database"), err))
+	}
+
+	return nil
+}
+
+func parseHash(s string) (hash [32]byte, err error) {
+	decoded, err := hex.DecodeString(s)
+	if err != nil || len(decoded) != 32 {
+		return hash, errors.New(fmt.Sprintf("invalid hash: %v", s))
+	}
+	copy(hash[:], decoded)
+
+	return hash, nil
+}
+
+//Prints one summary line per block. Blocks whose transactions are aggregated are only found by their hash without tx.
+func walkChain() error {
+	block := storage.ReadLastClosedBlock()
+	if block == nil {
+		return errors.New("no last closed block found")
+	}
+
+	for {
+		fmt.Printf("%6d  hash %x  hashWithoutTx %x  time %v  beneficiary %x  acc/funds/config/stake/agg %v/%v/%v/%v/%v  aggregated %t\n",
+			block.Height,
+			block.Hash[0:8],
+			block.HashWithoutTx[0:8],
+			block.Timestamp,
+			block.Beneficiary[0:8],
+			block.NrAccTx,
+			block.NrFundsTx,
+			block.NrConfigTx,
+			block.NrStakeTx,
+			block.NrAggTx,
+			block.Aggregated)
+
+		if block.Height == 0 {
+			return nil
+		}
+
+		prevBlock := storage.ReadClosedBlock(block.PrevHash)
+		if prevBlock == nil {
+			prevBlock = storage.ReadClosedBlockWithoutTx(block.PrevHashWithoutTx)
+		}
+		if prevBlock == nil {
+			return errors.New(fmt.Sprintf("ancestor of block %x (prevHash %x, prevHashWithoutTx %x) not found",
+				block.Hash[0:8], block.PrevHash[0:8], block.PrevHashWithoutTx[0:8]))
+		}
+		block = prevBlock
+	}
+}
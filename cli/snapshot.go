@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/miner"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"github.com/urfave/cli"
+	"path/filepath"
+)
+
+var snapshotFileFlag = cli.StringFlag {
+	Name: 	"file, f",
+	Usage: 	"load the snapshot from `FILE`",
+}
+
+func GetSnapshotCommand() cli.Command {
+	return cli.Command {
+		Name:	"snapshot",
+		Usage:	"list, verify and restore state snapshots",
+		Subcommands: []cli.Command {
+			{
+				Name:	"list",
+				Usage:	"list the snapshots in a directory",
+				Action:	func(c *cli.Context) error {
+					filenames, err := miner.ListSnapshots(c.String("dir"))
+					if err != nil {
+						return err
+					}
+
+					for _, filename := range filenames {
+						snapshot, err := miner.ReadSnapshot(filename)
+						if err != nil {
+							fmt.Printf("%-40s invalid: %v\n", filepath.Base(filename), err)
+							continue
+						}
+						fmt.Printf("%-40s height: %v, hash: %x, accounts: %v\n", filepath.Base(filename), snapshot.Block.Height, snapshot.Block.Hash, len(snapshot.Accounts))
+					}
+
+					return nil
+				},
+				Flags:	[]cli.Flag {
+					cli.StringFlag {
+						Name: 	"dir",
+						Usage: 	"list the snapshots in `DIR`",
+						Value: 	"snapshots",
+					},
+				},
+			},
+			{
+				Name:	"verify",
+				Usage:	"check the integrity of a snapshot",
+				Action:	func(c *cli.Context) error {
+					snapshot, err := readSnapshot(c)
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("Snapshot is valid: height %v, hash %x, %v accounts, %v root keys\n", snapshot.Block.Height, snapshot.Block.Hash, len(snapshot.Accounts), len(snapshot.RootKeys))
+					return nil
+				},
+				Flags:	[]cli.Flag {
+					snapshotFileFlag,
+				},
+			},
+			{
+				Name:	"restore",
+				Usage:	"restore a snapshot into a database, the miner started on it continues at the snapshot's block",
+				Action:	func(c *cli.Context) error {
+					snapshot, err := readSnapshot(c)
+					if err != nil {
+						return err
+					}
+
+					storage.Init(c.String("database"), "")
+					defer storage.TearDown()
+
+					if storage.ReadLastClosedBlock() != nil && !c.Bool("force") {
+						return errors.New("database is not empty, use --force to overwrite it")
+					}
+
+					if err := miner.RestoreSnapshot(snapshot); err != nil {
+						return errors.New(fmt.Sprintf("could not restore snapshot: %v", err))
+					}
+
+					fmt.Printf("Restored state of block %x at height %v into %v\n", snapshot.Block.Hash, snapshot.Block.Height, c.String("database"))
+					return nil
+				},
+				Flags:	[]cli.Flag {
+					snapshotFileFlag,
+					cli.StringFlag {
+						Name: 	"database, d",
+						Usage: 	"restore into the database of the disk-based key/value store at `FILE`",
+						Value:	"store.db",
+					},
+					cli.BoolFlag {
+						Name: 	"force",
+						Usage: 	"overwrite a non-empty database",
+					},
+				},
+			},
+		},
+	}
+}
+
+func readSnapshot(c *cli.Context) (*miner.Snapshot, error) {
+	if !c.IsSet("file") {
+		return nil, errors.New("argument missing: file")
+	}
+
+	snapshot, err := miner.ReadSnapshot(c.String("file"))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid snapshot %v: %v", c.String("file"), err))
+	}
+
+	return snapshot, nil
+}
@@ -3,6 +3,7 @@ package cli
 import (
 	"crypto/ecdsa"
 	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/api"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 	"github.com/bazo-blockchain/bazo-miner/miner"
 	"github.com/bazo-blockchain/bazo-miner/p2p"
@@ -21,6 +22,9 @@ type startArgs struct {
 	commitmentFile			string
 	rootKeyFile				string
 	rootCommitmentFile		string
+	apiAddress				string
+	snapshotDir				string
+	snapshotInterval		uint
 }
 
 func GetStartCommand(logger *log.Logger) cli.Command {
@@ -37,6 +41,9 @@ func GetStartCommand(logger *log.Logger) cli.Command {
 				commitmentFile:			c.String("commitment"),
 				rootKeyFile:			c.String("rootwallet"),
 				rootCommitmentFile: 	c.String("rootcommitment"),
+				apiAddress:				c.String("api"),
+				snapshotDir:			c.String("snapshots"),
+				snapshotInterval:		c.Uint("snapshot-interval"),
 			}
 
 			if !c.IsSet("bootstrap") {
@@ -96,6 +103,19 @@ func GetStartCommand(logger *log.Logger) cli.Command {
 				Usage: 	"load root's RSA public-private key from `FILE`",
 				Value: 	"commitment.txt",
 			},
+			cli.StringFlag {
+				Name: 	"api",
+				Usage: 	"serve the HTTP/JSON API at `IP:PORT` (disabled if not set)",
+			},
+			cli.StringFlag {
+				Name: 	"snapshots",
+				Usage: 	"write state snapshots to `DIR`",
+				Value: 	"snapshots",
+			},
+			cli.UintFlag {
+				Name: 	"snapshot-interval",
+				Usage: 	"write a state snapshot every `N` blocks (disabled if 0)",
+			},
 			cli.BoolFlag {
 				Name: 	"confirm",
 				Usage: 	"user must press enter before starting the miner",
@@ -108,6 +128,12 @@ func Start(args *startArgs, logger *log.Logger) error {
 	storage.Init(args.dbname, args.bootstrapNodeAddress)
 	p2p.Init(args.myNodeAddress)
 
+	if len(args.apiAddress) > 0 {
+		api.Init(args.apiAddress)
+	}
+
+	miner.InitSnapshots(args.snapshotDir, uint32(args.snapshotInterval))
+
 	validatorPubKey, err := crypto.ExtractECDSAPublicKeyFromFile(args.walletFile)
 	if err != nil {
 		logger.Printf("%v\n", err)
@@ -188,7 +214,10 @@ func (args startArgs) String() string {
 			"- Multisig File:\t\t %v\n" +
 			"- Commitment File:\t\t %v\n" +
 			"- Root Wallet File:\t\t %v\n" +
-			"- Root Commitment File:\t\t %v\n",
+			"- Root Commitment File:\t\t %v\n" +
+			"- API Address:\t\t\t %v\n" +
+			"- Snapshot Directory:\t\t %v\n" +
+			"- Snapshot Interval:\t\t %v\n",
 		args.dbname,
 		args.myNodeAddress,
 		args.bootstrapNodeAddress,
@@ -196,5 +225,8 @@ func (args startArgs) String() string {
 		args.multisigFile,
 		args.commitmentFile,
 		args.rootKeyFile,
-		args.rootCommitmentFile)
+		args.rootCommitmentFile,
+		args.apiAddress,
+		args.snapshotDir,
+		args.snapshotInterval)
 }
\ No newline at end of file
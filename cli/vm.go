@@ -0,0 +1,282 @@
+package cli
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/asm"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/vm"
+	"github.com/urfave/cli"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+//Input of a local contract execution, all byte fields are in hex.
+type runInput struct {
+	Code      string   `json:"code"`
+	Variables []string `json:"variables"`
+	Caller    string   `json:"caller"`
+	Amount    uint64   `json:"amount"`
+	Balance   uint64   `json:"balance"`
+	Data      string   `json:"data"`
+	Fee       uint64   `json:"fee"`
+	Height    uint32   `json:"height"`
+	Timestamp int64    `json:"timestamp"`
+	PrevHash  string   `json:"prevHash"`
+}
+
+func GetVMCommand() cli.Command {
+	return cli.Command {
+		Name:	"vm",
+		Usage:	"execute contracts locally",
+		Subcommands: []cli.Command {
+			{
+				Name:	"run",
+				Usage:	"run a contract without mining a transaction, nothing is written to the chain",
+				Action:	func(c *cli.Context) error {
+					input, err := readRunInput(c)
+					if err != nil {
+						return err
+					}
+
+					context, err := input.context()
+					if err != nil {
+						return err
+					}
+
+					virtualMachine := vm.NewVM(context)
+					if c.Bool("debug") {
+						debugger := vm.NewDebugger(os.Stdin, os.Stdout)
+						for _, pc := range c.IntSlice("break") {
+							debugger.AddBreakpoint(pc)
+						}
+						if !c.IsSet("break") {
+							debugger.Step()
+						}
+						virtualMachine.SetTracer(debugger)
+					} else if c.Bool("trace") {
+						virtualMachine.SetTracer(vm.NewJSONTracer(os.Stdout))
+					}
+					success := virtualMachine.Exec(false)
+
+					printRunResult(context, &virtualMachine, success)
+					return nil
+				},
+				Flags:	[]cli.Flag {
+					cli.StringFlag {
+						Name: 	"input, i",
+						Usage: 	"read the execution input from the JSON `FILE`, flags take precedence",
+					},
+					cli.StringFlag {
+						Name: 	"code",
+						Usage: 	"the contract's bytecode in `HEX`",
+					},
+					cli.StringFlag {
+						Name: 	"source",
+						Usage: 	"assemble the contract from the assembly in `FILE`",
+					},
+					cli.StringSliceFlag {
+						Name: 	"variable",
+						Usage: 	"a contract variable in `HEX`, repeat for every variable",
+					},
+					cli.StringFlag {
+						Name: 	"caller",
+						Usage: 	"the caller's account `HASH` in hex",
+					},
+					cli.Uint64Flag {
+						Name: 	"amount",
+						Usage: 	"the `AMOUNT` transferred to the contract",
+					},
+					cli.Uint64Flag {
+						Name: 	"balance",
+						Usage: 	"the contract account's `BALANCE`",
+					},
+					cli.StringFlag {
+						Name: 	"data",
+						Usage: 	"the transaction data (calldata) in `HEX`",
+					},
+					cli.Uint64Flag {
+						Name: 	"fee",
+						Usage: 	"the `FEE` available as gas",
+						Value:	100000,
+					},
+					cli.UintFlag {
+						Name: 	"height",
+						Usage: 	"the `HEIGHT` of the block the transaction is in",
+					},
+					cli.Int64Flag {
+						Name: 	"timestamp",
+						Usage: 	"the `TIMESTAMP` of the block the transaction is in",
+					},
+					cli.StringFlag {
+						Name: 	"prevhash",
+						Usage: 	"the previous block's `HASH` in hex",
+					},
+					cli.BoolFlag {
+						Name: 	"trace",
+						Usage: 	"print the state before and after every instruction as JSON lines",
+					},
+					cli.BoolFlag {
+						Name: 	"debug",
+						Usage: 	"step through the contract interactively, starting at the first instruction or breakpoint",
+					},
+					cli.IntSliceFlag {
+						Name: 	"break",
+						Usage: 	"stop before the instruction at `PC` when debugging, repeat for every breakpoint",
+					},
+				},
+			},
+		},
+	}
+}
+
+func readRunInput(c *cli.Context) (input runInput, err error) {
+	input.Fee = c.Uint64("fee")
+
+	if c.IsSet("input") {
+		encoded, err := ioutil.ReadFile(c.String("input"))
+		if err != nil {
+			return input, err
+		}
+		if err := json.Unmarshal(encoded, &input); err != nil {
+			return input, errors.New(fmt.Sprintf("invalid input file: %v", err))
+		}
+	}
+
+	if c.IsSet("source") {
+		source, err := ioutil.ReadFile(c.String("source"))
+		if err != nil {
+			return input, err
+		}
+		code, err := asm.Assemble(string(source))
+		if err != nil {
+			return input, errors.New(fmt.Sprintf("could not assemble %v: %v", c.String("source"), err))
+		}
+		input.Code = hex.EncodeToString(code)
+	}
+
+	for _, flag := range []struct {
+		name  string
+		value *string
+	}{{"code", &input.Code}, {"caller", &input.Caller}, {"data", &input.Data}, {"prevhash", &input.PrevHash}} {
+		if c.IsSet(flag.name) {
+			*flag.value = c.String(flag.name)
+		}
+	}
+	if c.IsSet("variable") {
+		input.Variables = c.StringSlice("variable")
+	}
+	if c.IsSet("fee") {
+		input.Fee = c.Uint64("fee")
+	}
+	if c.IsSet("amount") {
+		input.Amount = c.Uint64("amount")
+	}
+	if c.IsSet("balance") {
+		input.Balance = c.Uint64("balance")
+	}
+	if c.IsSet("height") {
+		input.Height = uint32(c.Uint("height"))
+	}
+	if c.IsSet("timestamp") {
+		input.Timestamp = c.Int64("timestamp")
+	}
+
+	if input.Code == "" {
+		return input, errors.New("argument missing: code, source or input")
+	}
+
+	return input, nil
+}
+
+//Builds the context of the contract account called by a transaction of the caller in the given block.
+func (input runInput) context() (*protocol.Context, error) {
+	code, err := decodeHexArg("code", input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	var variables []protocol.ByteArray
+	for _, variable := range input.Variables {
+		value, err := decodeHexArg("variable", variable)
+		if err != nil {
+			return nil, err
+		}
+		variables = append(variables, value)
+	}
+
+	data, err := decodeHexArg("data", input.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var caller [32]byte
+	if input.Caller != "" {
+		if caller, err = parseHash(input.Caller); err != nil {
+			return nil, err
+		}
+	}
+
+	var prevHash [32]byte
+	if input.PrevHash != "" {
+		if prevHash, err = parseHash(input.PrevHash); err != nil {
+			return nil, err
+		}
+	}
+
+	account := protocol.Account{Balance: input.Balance, Contract: code, ContractVariables: variables}
+	tx := protocol.FundsTx{From: caller, Amount: input.Amount, Fee: input.Fee, Data: data}
+	context := protocol.NewContext(account, tx)
+	context.SetBlock(input.Height, input.Timestamp, prevHash)
+	return context, nil
+}
+
+func decodeHexArg(name string, value string) ([]byte, error) {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid %v: %v", name, err))
+	}
+	return decoded, nil
+}
+
+func printRunResult(context *protocol.Context, virtualMachine *vm.VM, success bool) {
+	fmt.Printf("Success:  %v\n", success)
+	if !success {
+		fmt.Printf("Error:    %v\n", virtualMachine.GetErrorMsg())
+	}
+	fmt.Printf("Gas used: %v of %v\n", context.Fee-virtualMachine.GetRemainingFee(), context.Fee)
+
+	fmt.Println("Stack (top first):")
+	stack := virtualMachine.GetStack()
+	for i := len(stack) - 1; i >= 0; i-- {
+		fmt.Printf("  %x\n", stack[i])
+	}
+
+	fmt.Println("Changed contract variables:")
+	for _, change := range context.GetChanges() {
+		index, value := change.GetChange()
+		fmt.Printf("  %v: %x -> %x\n", index, context.ContractVariables[index], value)
+	}
+
+	fmt.Println("Transfers:")
+	for _, transfer := range context.GetTransfers() {
+		fmt.Printf("  %v to %x\n", transfer.Amount, transfer.To)
+	}
+
+	fmt.Println("Self-destructs:")
+	for _, selfDestruct := range context.GetSelfDestructs() {
+		fmt.Printf("  %x, remaining balance to %x\n", selfDestruct.Account, selfDestruct.Beneficiary)
+	}
+
+	fmt.Println("Emitted events:")
+	for _, event := range context.GetEvents() {
+		fmt.Printf("  topics: %x, data: %x\n", event.Topics, event.Data)
+	}
+
+	if (len(context.GetChanges()) > 0 || len(context.GetEvents()) > 0 || len(context.GetTransfers()) > 0 || len(context.GetSelfDestructs()) > 0) && !success {
+		fmt.Println("The changes, transfers, self-destructs and events would be discarded, because the execution failed.")
+	}
+}
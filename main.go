@@ -20,6 +20,14 @@ func main() {
 		cli.GetStartCommand(logger),
 		cli.GetGenerateWalletCommand(),
 		cli.GetGenerateCommitmentCommand(),
+		cli.GetInspectCommand(),
+		cli.GetSnapshotCommand(),
+		cli.GetExportCommand(),
+		cli.GetImportCommand(),
+		cli.GetAssembleCommand(),
+		cli.GetDisassembleCommand(),
+		cli.GetVMCommand(),
+		cli.GetABICommand(),
 	}
 
 	err := app.Run(os.Args)
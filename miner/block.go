@@ -80,13 +80,6 @@ func finalizeBlock(block *protocol.Block) error {
 		return err
 	}
 
-
-	//Block hash with MerkleTree and therefore, including all transactions
-	partialHash := block.HashBlock()
-
-	//Block hash without MerkleTree and therefore, without any transactions
-	partialHashWithoutMerkleRoot := block.HashBlockWithoutMerkleRoot()
-
 	prevProofs := GetLatestProofs(activeParameters.num_included_prev_proofs, block)
 	nonce, err := proofOfStake(getDifficulty(), block.PrevHash, prevProofs, block.Height, validatorAcc.Balance, commitmentProof)
 	if err != nil {
@@ -105,10 +98,6 @@ func finalizeBlock(block *protocol.Block) error {
 	block.Nonce = nonceBuf
 	block.Timestamp = nonce
 
-	//Put pieces together to get the final hash.
-	block.Hash = sha3.Sum256(append(nonceBuf[:], partialHash[:]...))
-	block.HashWithoutTx = sha3.Sum256(append(nonceBuf[:], partialHashWithoutMerkleRoot[:]...))
-
 	//This doesn't need to be hashed, because we already have the merkle tree taking care of consistency.
 	block.NrAccTx = uint16(len(block.AccTxData))
 	block.NrFundsTx = uint16(len(block.FundsTxData))
@@ -117,6 +106,26 @@ func finalizeBlock(block *protocol.Block) error {
 	block.NrAggTx = uint16(len(block.AggTxData))
 
 	copy(block.CommitmentProof[0:crypto.COMM_PROOF_LENGTH], commitmentProof[:])
+
+	//The state root can only be computed once the block is complete, because the block is validated against the state.
+	if block.StateRoot, err = computeStateRoot(block); err != nil {
+		//The block is abandoned, the failed tx is removed from the mempool such that the next block doesn't include it.
+		if callErr, ok := err.(*contractCallError); ok {
+			storage.DeleteOpenTx(callErr.tx)
+			storage.WriteINVALIDOpenTx(callErr.tx)
+		}
+		return err
+	}
+
+	//Block hash with MerkleTree and therefore, including all transactions
+	partialHash := block.HashBlock()
+
+	//Block hash without MerkleTree and therefore, without any transactions
+	partialHashWithoutMerkleRoot := block.HashBlockWithoutMerkleRoot()
+
+	//Put pieces together to get the final hash.
+	block.Hash = sha3.Sum256(append(nonceBuf[:], partialHash[:]...))
+	block.HashWithoutTx = sha3.Sum256(append(nonceBuf[:], partialHashWithoutMerkleRoot[:]...))
 	logger.Printf("-- End Finalization")
 	return nil
 }
@@ -184,7 +193,12 @@ func addTx(b *protocol.Block, tx protocol.Transaction) error {
 }
 
 func addAccTx(b *protocol.Block, tx *protocol.AccTx) error {
+	if tx.Header == protocol.ACCTX_UPGRADE {
+		return addAccUpgradeTx(b, tx)
+	}
+
 	accHash := sha3.Sum256(tx.PubKey[:])
+
 	//According to the accTx specification, we only accept new accounts except if the removal bit is
 	//set in the header (2nd bit).
 	if tx.Header&0x02 != 0x02 {
@@ -199,6 +213,36 @@ func addAccTx(b *protocol.Block, tx *protocol.AccTx) error {
 	return nil
 }
 
+//The accTxs are applied before the fundsTxs of a block, so an upgrade can't be added after a fundsTx has touched the
+//account. The upgraded account replaces the account in the local state copy, the contract calls of the following
+//fundsTxs run the new contract.
+func addAccUpgradeTx(b *protocol.Block, tx *protocol.AccTx) error {
+	if b.Height < tx.LockHeight {
+		return errors.New(fmt.Sprintf("Upgrade is locked until height %v.", tx.LockHeight))
+	}
+
+	accHash := protocol.SerializeHashContent(tx.PubKey)
+	acc := storage.State[accHash]
+	if acc == nil {
+		return errors.New("Account does not exist.")
+	}
+
+	if _, exists := b.StateCopy[accHash]; exists {
+		return errors.New("Account has already been changed in this block.")
+	}
+
+	upgraded := storage.CopyAccount(acc)
+	upgraded.Contract = tx.Contract
+	if len(tx.ContractVariables) > 0 {
+		upgraded.ContractVariables = append([]protocol.ByteArray(nil), tx.ContractVariables...)
+	}
+	b.StateCopy[accHash] = upgraded
+
+	b.AccTxData = append(b.AccTxData, tx.Hash())
+	logger.Printf("Added tx (%x) to the AccTxData slice: %v", tx.Hash(), *tx)
+	return nil
+}
+
 func addFundsTx(b *protocol.Block, tx *protocol.FundsTx) error {
 
 	addFundsTxMutex.Lock()
@@ -209,9 +253,7 @@ func addFundsTx(b *protocol.Block, tx *protocol.FundsTx) error {
 		if acc := storage.State[tx.From]; acc != nil {
 			hash := protocol.SerializeHashContent(acc.Address)
 			if hash == tx.From {
-				newAcc := protocol.Account{}
-				newAcc = *acc
-				b.StateCopy[tx.From] = &newAcc
+				b.StateCopy[tx.From] = storage.CopyAccount(acc)
 			}
 		} else {
 			storage.WriteINVALIDOpenTx(tx)
@@ -225,9 +267,8 @@ func addFundsTx(b *protocol.Block, tx *protocol.FundsTx) error {
 		if acc := storage.State[tx.To]; acc != nil {
 			hash := protocol.SerializeHashContent(acc.Address)
 			if hash == tx.To {
-				newAcc := protocol.Account{}
-				newAcc = *acc
-				b.StateCopy[tx.To] = &newAcc
+				//The contract variables are copied as well, the contract call below must not change the state.
+				b.StateCopy[tx.To] = storage.CopyAccount(acc)
 			}
 		} else {
 			storage.WriteINVALIDOpenTx(tx)
@@ -277,8 +318,25 @@ func addFundsTx(b *protocol.Block, tx *protocol.FundsTx) error {
 	}
 
 	//Check if transaction has data and the receiver account has a smart contract
+	var transfers []protocol.Transfer
+	var selfDestructs []protocol.SelfDestruct
 	if tx.Data != nil && b.StateCopy[tx.To].Contract != nil {
 		context := protocol.NewContext(*b.StateCopy[tx.To], *tx)
+		//Called contracts are read from and changed in the state copy as well.
+		accounts := func(address [32]byte) (*protocol.Account, error) {
+			if _, exists := b.StateCopy[address]; !exists {
+				acc, err := storage.GetAccount(address)
+				if err != nil {
+					return nil, err
+				}
+				b.StateCopy[address] = storage.CopyAccount(acc)
+			}
+			return b.StateCopy[address], nil
+		}
+		context.SetAccountReader(accounts)
+		//The timestamp is set when the block is finalized, the current time is the best guess until then. Calls which
+		//fail with the final timestamp are dropped in finalizeBlock().
+		context.SetBlock(b.Height, time.Now().Unix(), b.PrevHash)
 		virtualMachine := vm.NewVM(context)
 
 		// Check if vm execution run without error
@@ -288,8 +346,22 @@ func addFundsTx(b *protocol.Block, tx *protocol.FundsTx) error {
 			return errors.New(virtualMachine.GetErrorMsg())
 		}
 
+		if err := checkTransfers(tx, context.GetTransfers(), accounts); err != nil {
+			storage.WriteINVALIDOpenTx(tx)
+			addFundsTxMutex.Unlock()
+			return err
+		}
+
+		if err := checkSelfDestructs(tx, context.GetTransfers(), context.GetSelfDestructs(), accounts); err != nil {
+			storage.WriteINVALIDOpenTx(tx)
+			addFundsTxMutex.Unlock()
+			return err
+		}
+
 		//Update changes vm has made to the contract variables
 		context.PersistChanges()
+		transfers = context.GetTransfers()
+		selfDestructs = context.GetSelfDestructs()
 	}
 
 	//Update state copy.
@@ -300,6 +372,22 @@ func addFundsTx(b *protocol.Block, tx *protocol.FundsTx) error {
 	accReceiver := b.StateCopy[tx.To]
 	accReceiver.Balance += tx.Amount
 
+	//Transfers of the contract, the accounts have been copied when the transfers were checked.
+	for _, transfer := range transfers {
+		b.StateCopy[transfer.From].Balance -= transfer.Amount
+		b.StateCopy[transfer.To].Balance += transfer.Amount
+	}
+
+	//Self-destructed contracts send their remaining balance to the beneficiary, the accounts are in the state copy as
+	//well.
+	for _, selfDestruct := range selfDestructs {
+		acc := b.StateCopy[selfDestruct.Account]
+		b.StateCopy[selfDestruct.Beneficiary].Balance += acc.Balance
+		acc.Balance = 0
+		acc.Contract = nil
+		acc.ContractVariables = nil
+	}
+
 	//Add teh transaction to the storage where all Funds-transactions are stored before they where aggregated.
 	storage.WriteFundsTxBeforeAggregation(tx)
 
@@ -1205,7 +1293,7 @@ func validate(b *protocol.Block, initialSetup bool) error {
 			}
 
 			blockDataMap[block.Hash] = blockData{accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, aggregatedFundsTxSlice,block}
-			if err := validateState(blockDataMap[block.Hash], initialSetup); err != nil {
+			if err := validateStateRoot(blockDataMap[block.Hash], initialSetup); err != nil {
 				return err
 			}
 
@@ -1238,7 +1326,7 @@ func validate(b *protocol.Block, initialSetup bool) error {
 			}
 
 			blockDataMap[block.Hash] = blockData{accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, aggregatedFundsTxSlice,block}
-			if err := validateState(blockDataMap[block.Hash], initialSetup); err != nil {
+			if err := validateStateRoot(blockDataMap[block.Hash], initialSetup); err != nil {
 				return err
 			}
 
@@ -1399,67 +1487,86 @@ func preValidate(block *protocol.Block, initialSetup bool) (accTxSlice []*protoc
 }
 
 //Dynamic state check.
-func validateState(data blockData, initialSetup bool) error {
+func validateState(state *blockState, data blockData, initialSetup bool, journal *storage.Journal) (err error) {
 	//The sequence of validation matters. If we start with accs, then fund/stake transactions can be done in the same block
 	//even though the accounts did not exist before the block validation.
 
-	if err := accStateChange(data.accTxSlice); err != nil {
+	//The rollbacks below don't touch contract variables, receipts, transfers, upgrades and self-destructs, they are
+	//restored from the journal.
+	contractWrites, receipts, transfers := len(journal.Contract), len(journal.Receipts), len(journal.Transfers)
+	upgrades, selfDestructs := len(journal.Upgrades), len(journal.SelfDestructs)
+	defer func() {
+		if err != nil {
+			selfDestructJournalRollback(state, journal.SelfDestructs[selfDestructs:])
+			journal.SelfDestructs = journal.SelfDestructs[:selfDestructs]
+			contractJournalRollback(state, journal.Contract[contractWrites:])
+			journal.Contract = journal.Contract[:contractWrites]
+			receiptJournalRollback(journal.Receipts[receipts:])
+			journal.Receipts = journal.Receipts[:receipts]
+			transferJournalRollback(state, journal.Transfers[transfers:])
+			journal.Transfers = journal.Transfers[:transfers]
+			upgradeJournalRollback(state, journal.Upgrades[upgrades:])
+			journal.Upgrades = journal.Upgrades[:upgrades]
+		}
+	}()
+
+	if err := accStateChange(state, data.accTxSlice, data.block, journal); err != nil {
 		return err
 	}
 
-	if err := fundsStateChange(data.fundsTxSlice, initialSetup); err != nil {
-		accStateChangeRollback(data.accTxSlice)
+	if err := fundsStateChange(state, data.fundsTxSlice, initialSetup, data.block, journal); err != nil {
+		accStateChangeRollback(state, data.accTxSlice)
 		return err
 	}
 
-	if err := aggTxStateChange(data.aggregatedFundsTxSlice, initialSetup); err != nil {
-		fundsStateChangeRollback(data.fundsTxSlice)
-		accStateChangeRollback(data.accTxSlice)
+	if err := aggTxStateChange(state, data.aggregatedFundsTxSlice, initialSetup, data.block, journal); err != nil {
+		fundsStateChangeRollback(state, data.fundsTxSlice)
+		accStateChangeRollback(state, data.accTxSlice)
 		return err
 	}
 
-	if err := stakeStateChange(data.stakeTxSlice, data.block.Height, initialSetup); err != nil {
-		fundsStateChangeRollback(data.fundsTxSlice)
-		accStateChangeRollback(data.accTxSlice)
-		aggregatedStateRollback(data.aggTxSlice, data.block.HashWithoutTx, data.block.Beneficiary)
+	if err := stakeStateChange(state, data.stakeTxSlice, data.block.Height, initialSetup); err != nil {
+		fundsStateChangeRollback(state, data.fundsTxSlice)
+		accStateChangeRollback(state, data.accTxSlice)
+		aggregatedStateRollback(state, data.aggTxSlice, data.block.HashWithoutTx, data.block.Beneficiary)
 		return err
 	}
 
-	if err := collectTxFees(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.aggTxSlice, data.block.Beneficiary, initialSetup); err != nil {
-		stakeStateChangeRollback(data.stakeTxSlice)
-		fundsStateChangeRollback(data.fundsTxSlice)
-		aggregatedStateRollback(data.aggTxSlice, data.block.HashWithoutTx, data.block.Beneficiary)
-		accStateChangeRollback(data.accTxSlice)
+	if err := collectTxFees(state, data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.aggTxSlice, data.block.Beneficiary, initialSetup); err != nil {
+		stakeStateChangeRollback(state, data.stakeTxSlice)
+		fundsStateChangeRollback(state, data.fundsTxSlice)
+		aggregatedStateRollback(state, data.aggTxSlice, data.block.HashWithoutTx, data.block.Beneficiary)
+		accStateChangeRollback(state, data.accTxSlice)
 		return err
 	}
 
-	if err := collectBlockReward(activeParameters.Block_reward, data.block.Beneficiary, initialSetup); err != nil {
-		collectTxFeesRollback(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
-		stakeStateChangeRollback(data.stakeTxSlice)
-		fundsStateChangeRollback(data.fundsTxSlice)
-		aggregatedStateRollback(data.aggTxSlice, data.block.HashWithoutTx, data.block.Beneficiary)
-		accStateChangeRollback(data.accTxSlice)
+	if err := collectBlockReward(state, activeParameters.Block_reward, data.block.Beneficiary, initialSetup); err != nil {
+		collectTxFeesRollback(state, data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
+		stakeStateChangeRollback(state, data.stakeTxSlice)
+		fundsStateChangeRollback(state, data.fundsTxSlice)
+		aggregatedStateRollback(state, data.aggTxSlice, data.block.HashWithoutTx, data.block.Beneficiary)
+		accStateChangeRollback(state, data.accTxSlice)
 		return err
 	}
 
-	if err := collectSlashReward(activeParameters.Slash_reward, data.block); err != nil {
-		collectBlockRewardRollback(activeParameters.Block_reward, data.block.Beneficiary)
-		collectTxFeesRollback(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
-		stakeStateChangeRollback(data.stakeTxSlice)
-		fundsStateChangeRollback(data.fundsTxSlice)
-		aggregatedStateRollback(data.aggTxSlice, data.block.HashWithoutTx, data.block.Beneficiary)
-		accStateChangeRollback(data.accTxSlice)
+	if err := collectSlashReward(state, activeParameters.Slash_reward, data.block); err != nil {
+		collectBlockRewardRollback(state, activeParameters.Block_reward, data.block.Beneficiary)
+		collectTxFeesRollback(state, data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
+		stakeStateChangeRollback(state, data.stakeTxSlice)
+		fundsStateChangeRollback(state, data.fundsTxSlice)
+		aggregatedStateRollback(state, data.aggTxSlice, data.block.HashWithoutTx, data.block.Beneficiary)
+		accStateChangeRollback(state, data.accTxSlice)
 		return err
 	}
 
-	if err := updateStakingHeight(data.block); err != nil {
-		collectSlashRewardRollback(activeParameters.Slash_reward, data.block)
-		collectBlockRewardRollback(activeParameters.Block_reward, data.block.Beneficiary)
-		collectTxFeesRollback(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
-		stakeStateChangeRollback(data.stakeTxSlice)
-		fundsStateChangeRollback(data.fundsTxSlice)
-		aggregatedStateRollback(data.aggTxSlice, data.block.HashWithoutTx, data.block.Beneficiary)
-		accStateChangeRollback(data.accTxSlice)
+	if err := updateStakingHeight(state, data.block); err != nil {
+		collectSlashRewardRollback(state, activeParameters.Slash_reward, data.block)
+		collectBlockRewardRollback(state, activeParameters.Block_reward, data.block.Beneficiary)
+		collectTxFeesRollback(state, data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
+		stakeStateChangeRollback(state, data.stakeTxSlice)
+		fundsStateChangeRollback(state, data.fundsTxSlice)
+		aggregatedStateRollback(state, data.aggTxSlice, data.block.HashWithoutTx, data.block.Beneficiary)
+		accStateChangeRollback(state, data.accTxSlice)
 		return err
 	}
 
@@ -1587,9 +1694,15 @@ func postValidate(data blockData, initialSetup bool) {
 			}
 		}
 
-		// Write last block to db and delete last block's ancestor.
-		storage.DeleteAllLastClosedBlock()
-		storage.WriteLastClosedBlock(data.block)
+		//Write last block to db and delete last block's ancestor. The state is persisted along with it, except during
+		//the initial setup where it is persisted once after all blocks are replayed.
+		if initialSetup {
+			storage.DeleteAllLastClosedBlock()
+			storage.WriteLastClosedBlock(data.block)
+		} else {
+			persistState(data.block)
+			writeSnapshotIfDue(data.block)
+		}
 	}
 }
 
@@ -147,6 +147,7 @@ func initRootKey(rootKey *ecdsa.PublicKey) error {
 	rootAcc := protocol.NewAccount(address, [32]byte{}, activeParameters.Staking_minimum, true, commPubKey, nil, nil)
 	storage.State[addressHash] = &rootAcc
 	storage.RootKeys[addressHash] = &rootAcc
+	resetStateChanges()
 
 	return nil
 }
@@ -53,6 +53,19 @@ func NewDefaultParameters() Parameters {
 	return newParameters
 }
 
+//Returns a copy of the currently active system parameters, nil as long as the miner is not initialized. A config tx
+//replaces the parameters while its block is validated, so they are read under the validation lock.
+func GetActiveParameters() *Parameters {
+	blockValidation.Lock()
+	defer blockValidation.Unlock()
+
+	if activeParameters == nil {
+		return nil
+	}
+	params := *activeParameters
+	return &params
+}
+
 //Captures first and last timestamp of the intended blocks of the range.
 type timerange struct {
 	first int64
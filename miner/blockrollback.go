@@ -91,13 +91,24 @@ func preValidateRollback(b *protocol.Block) (accTxSlice []*protocol.AccTx, funds
 }
 
 func validateStateRollback(data blockData) {
-	collectSlashRewardRollback(activeParameters.Slash_reward, data.block)
-	collectBlockRewardRollback(activeParameters.Block_reward, data.block.Beneficiary)
-	collectTxFeesRollback(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
-	stakeStateChangeRollback(data.stakeTxSlice)
-	fundsStateChangeRollback(data.fundsTxSlice)
-	aggregatedStateRollback(data.aggTxSlice, data.block.HashWithoutTx,  data.block.Beneficiary)
-	accStateChangeRollback(data.accTxSlice)
+	state := globalState()
+	defer state.recordChanges()
+	collectSlashRewardRollback(state, activeParameters.Slash_reward, data.block)
+	collectBlockRewardRollback(state, activeParameters.Block_reward, data.block.Beneficiary)
+	collectTxFeesRollback(state, data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
+	stakeStateChangeRollback(state, data.stakeTxSlice)
+	journal := storage.ReadJournal(data.block.Hash)
+	stakingJournalRollback(state, journal)
+	fundsStateChangeRollback(state, data.fundsTxSlice)
+	aggregatedStateRollback(state, data.aggTxSlice, data.block.HashWithoutTx,  data.block.Beneficiary)
+	if journal != nil {
+		selfDestructJournalRollback(state, journal.SelfDestructs)
+		contractJournalRollback(state, journal.Contract)
+		receiptJournalRollback(journal.Receipts)
+		transferJournalRollback(state, journal.Transfers)
+		upgradeJournalRollback(state, journal.Upgrades)
+	}
+	accStateChangeRollback(state, data.accTxSlice)
 }
 
 func postValidateRollback(data blockData) {
@@ -151,16 +162,24 @@ func postValidateRollback(data blockData) {
 	//For transactions we switch from closed to open. However, we do not write back blocks
 	//to open storage, because in case of rollback the chain they belonged to is likely to starve.
 	storage.DeleteClosedBlock(data.block.Hash)
+	storage.DeleteJournal(data.block.Hash)
 	storage.WriteToReceivedStash(data.block) //Write it to received stash, it will be deleted after X new blocks.
 
-	//Save the previous block as the last closed block.
-	storage.DeleteAllLastClosedBlock()
+	//Save the previous block as the last closed block, together with the rolled back state.
 	prevBlock := storage.ReadClosedBlock(data.block.PrevHash)
 	if prevBlock == nil {
-		prevBlock = storage.ReadClosedBlock(data.block.PrevHashWithoutTx)
+		prevBlock = storage.ReadClosedBlockWithoutTx(data.block.PrevHashWithoutTx)
 	}
 	if prevBlock == nil {
+		storage.DeleteAllLastClosedBlock()
+		storage.DeleteState()
+		resetStateChanges()
 		return
 	}
-	storage.WriteLastClosedBlock(prevBlock)
+
+	//The rolled back state must be the state the previous block commits to.
+	if root := stateRoot(); prevBlock.Height > 0 && root != prevBlock.StateRoot {
+		logger.Printf("CRITICAL: State root after rollback does not match block (%x): %x vs. %x\n", prevBlock.Hash[0:8], root[0:8], prevBlock.StateRoot[0:8])
+	}
+	persistState(prevBlock)
 }
@@ -0,0 +1,110 @@
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/vm"
+	"time"
+)
+
+const (
+	//Upper bound of the fee a read-only call may use, the fee is not paid.
+	MAX_CALL_FEE = 10000000
+
+	//The gas of an estimation is unbounded, the number of instructions is not. The fee is below math.MaxInt64, such
+	//that the VM's gas checks do not overflow.
+	ESTIMATE_FEE       = 1 << 62
+	ESTIMATE_MAX_STEPS = 10000000
+)
+
+//Outcome of a contract execution which is not part of a block.
+type CallResult struct {
+	Success bool
+	Error   string
+	Result  protocol.ByteArray //Top of the stack when the contract halted
+	GasUsed uint64
+}
+
+//Runs the contract of the account against the current state as if the caller sent a tx with the data, e.g., to query
+//a view of the contract. Nothing is written, the changes to the contract variables are discarded.
+func CallContract(contract [32]byte, caller [32]byte, amount uint64, data []byte, fee uint64) (*CallResult, error) {
+	if fee > MAX_CALL_FEE {
+		return nil, errors.New(fmt.Sprintf("Fee of a call must not exceed %v.", MAX_CALL_FEE))
+	}
+
+	tx := &protocol.FundsTx{From: caller, To: contract, Amount: amount, Fee: fee, Data: data}
+	virtualMachine, err := newDryRunVM(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CallResult{Success: virtualMachine.Exec(false)}
+	result.GasUsed = fee - virtualMachine.GetRemainingFee()
+	if !result.Success {
+		result.Error = virtualMachine.GetErrorMsg()
+	} else if stack := virtualMachine.GetStack(); len(stack) > 0 {
+		result.Result = stack[len(stack)-1]
+	}
+
+	return result, nil
+}
+
+//Gas a contract execution needs, broken down per opcode.
+type GasEstimate struct {
+	Success bool
+	Error   string
+	GasUsed uint64
+	OpCodes map[string]vm.OpCodeGas
+}
+
+//Runs the contract of the account against the current state like CallContract, but with an unbounded budget. The gas
+//used is the fee a tx with the same sender, amount and data needs for the execution (at least).
+func EstimateGas(contract [32]byte, caller [32]byte, amount uint64, data []byte) (*GasEstimate, error) {
+	tx := &protocol.FundsTx{From: caller, To: contract, Amount: amount, Fee: ESTIMATE_FEE, Data: data}
+	virtualMachine, err := newDryRunVM(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	profiler := vm.NewGasProfiler(ESTIMATE_MAX_STEPS)
+	virtualMachine.SetTracer(profiler)
+
+	estimate := &GasEstimate{Success: virtualMachine.Exec(false), OpCodes: profiler.OpCodes}
+	estimate.GasUsed = ESTIMATE_FEE - virtualMachine.GetRemainingFee()
+	if !estimate.Success {
+		estimate.Error = virtualMachine.GetErrorMsg()
+	}
+
+	return estimate, nil
+}
+
+//Returns a VM for the contract of the tx's receiver which works on copies of the accounts in the state, such that
+//the execution does not change the state.
+//Each account is copied under the validation lock when the contract reads it. Holding the lock for the whole execution
+//would stall the validation of blocks, a contract which reads several accounts may thus see them before and after a
+//block which is validated concurrently.
+func newDryRunVM(tx *protocol.FundsTx) (*vm.VM, error) {
+	acc, _, err := GetAccount(tx.To)
+	if err != nil {
+		return nil, err
+	}
+	if len(acc.Contract) == 0 {
+		return nil, errors.New(fmt.Sprintf("Account (%x) has no contract.", tx.To[0:8]))
+	}
+
+	context := protocol.NewContext(*acc, *tx)
+	context.SetAccountReader(func(address [32]byte) (*protocol.Account, error) {
+		acc, _, err := GetAccount(address)
+		return acc, err
+	})
+	//The contract is executed as if the tx was in the next block.
+	blockValidation.Lock()
+	if lastBlock != nil {
+		context.SetBlock(lastBlock.Height+1, time.Now().Unix(), lastBlock.Hash)
+	}
+	blockValidation.Unlock()
+
+	virtualMachine := vm.NewVM(context)
+	return &virtualMachine, nil
+}
@@ -0,0 +1,93 @@
+package miner
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"reflect"
+	"testing"
+)
+
+func TestCallContract(t *testing.T) {
+	cleanAndPrepare()
+
+	contractHash := addCounterContract()
+	contract, _ := storage.GetAccount(contractHash)
+
+	result, err := CallContract(contractHash, [32]byte{}, 0, []byte{1, 0, 15}, 100000)
+	if err != nil {
+		t.Fatalf("Could not call contract: %v\n", err)
+	}
+	if !result.Success || result.GasUsed == 0 {
+		t.Errorf("Expected a successful call which used gas but was %v\n", result)
+	}
+
+	//The counter's write is discarded.
+	if expected := []protocol.ByteArray{{0, 2}}; !reflect.DeepEqual(contract.ContractVariables, expected) {
+		t.Errorf("Call changed the contract variables to %v\n", contract.ContractVariables)
+	}
+
+	result, err = CallContract(contractHash, [32]byte{}, 0, []byte{1, 0, 15}, 5)
+	if err != nil || result.Success || result.Error == "" {
+		t.Errorf("Expected the call to run out of gas but was %v: %v\n", result, err)
+	}
+
+	if _, err := CallContract(protocol.SerializeHashContent(accA.Address), [32]byte{}, 0, nil, 100000); err == nil {
+		t.Errorf("Account without contract has been called\n")
+	}
+	if _, err := CallContract(contractHash, [32]byte{}, 0, nil, MAX_CALL_FEE+1); err == nil {
+		t.Errorf("Call with a fee above the maximum has been executed\n")
+	}
+}
+
+func TestCallContract_Result(t *testing.T) {
+	cleanAndPrepare()
+
+	contractHash := addContract([]byte{
+		35,    // CALLDATA
+		29, 0, // SLOAD
+		4,  // ADD
+		50, // HALT
+	}, []protocol.ByteArray{{0, 2}})
+
+	result, err := CallContract(contractHash, [32]byte{}, 0, []byte{1, 0, 40}, 100000)
+	if err != nil || !result.Success {
+		t.Fatalf("Could not call contract: %v, %v\n", err, result)
+	}
+	if expected := (protocol.ByteArray{0, 42}); !reflect.DeepEqual(result.Result, expected) {
+		t.Errorf("Expected result %v but was %v\n", expected, result.Result)
+	}
+}
+
+func TestEstimateGas(t *testing.T) {
+	cleanAndPrepare()
+
+	contractHash := addCounterContract()
+	data := []byte{1, 0, 15}
+
+	estimate, err := EstimateGas(contractHash, [32]byte{}, 0, data)
+	if err != nil || !estimate.Success {
+		t.Fatalf("Could not estimate gas: %v, %v\n", err, estimate)
+	}
+	if sstore := estimate.OpCodes["sstore"]; sstore.Count != 1 || sstore.Gas < 1000 {
+		t.Errorf("Expected sstore to be accounted for but was %v\n", estimate.OpCodes)
+	}
+
+	//The estimate is exact, one less fails.
+	if result, _ := CallContract(contractHash, [32]byte{}, 0, data, estimate.GasUsed); !result.Success {
+		t.Errorf("Call with the estimated fee %v failed: %v\n", estimate.GasUsed, result.Error)
+	}
+	if result, _ := CallContract(contractHash, [32]byte{}, 0, data, estimate.GasUsed-1); result.Success {
+		t.Errorf("Call with less than the estimated fee %v succeeded\n", estimate.GasUsed)
+	}
+
+	//Endless loops are aborted.
+	loopHash := addContract([]byte{
+		0, 0, 1, // PUSH
+		3,        // POP
+		19, 0, 0, // JMP
+		50, // HALT
+	}, nil)
+	if estimate, err := EstimateGas(loopHash, [32]byte{}, 0, nil); err != nil || estimate.Success {
+		t.Errorf("Expected the estimation of an endless loop to fail but was %v: %v\n", estimate, err)
+	}
+}
@@ -0,0 +1,321 @@
+package miner
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/p2p"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"io"
+)
+
+//An export file starts with EXPORT_MAGIC and EXPORT_VERSION, followed by records. Every record consists of its type,
+//the length of its payload (uint32, big-endian) and the payload:
+//  - EXPORT_RECORD_TX: a transaction in its canonical encoding
+//  - EXPORT_RECORD_BLOCK: a block in its canonical encoding
+//  - EXPORT_RECORD_END: the number of blocks in the file (uint32), detects truncated files
+//Blocks are written from the genesis block upwards, every transaction is written once before the first block which
+//needs it. Transactions aggregated in an AggTx precede the AggTx. A file can therefore be imported while it is read.
+const (
+	EXPORT_MAGIC   = "BAZO"
+	EXPORT_VERSION = 1
+
+	EXPORT_RECORD_TX    = 0x01
+	EXPORT_RECORD_BLOCK = 0x02
+	EXPORT_RECORD_END   = 0x03
+
+	EXPORT_MAX_RECORD_SIZE = 64 * 1024 * 1024
+)
+
+type exportWriter struct {
+	writer *bufio.Writer
+}
+
+func newExportWriter(w io.Writer) (*exportWriter, error) {
+	ew := &exportWriter{bufio.NewWriter(w)}
+	if _, err := ew.writer.WriteString(EXPORT_MAGIC); err != nil {
+		return nil, err
+	}
+	if err := ew.writer.WriteByte(EXPORT_VERSION); err != nil {
+		return nil, err
+	}
+
+	return ew, nil
+}
+
+func (ew *exportWriter) write(recordType byte, payload []byte) error {
+	header := make([]byte, 5)
+	header[0] = recordType
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+
+	if _, err := ew.writer.Write(header); err != nil {
+		return err
+	}
+	_, err := ew.writer.Write(payload)
+	return err
+}
+
+func (ew *exportWriter) end(blocks int) error {
+	count := make([]byte, 4)
+	binary.BigEndian.PutUint32(count, uint32(blocks))
+	if err := ew.write(EXPORT_RECORD_END, count); err != nil {
+		return err
+	}
+
+	return ew.writer.Flush()
+}
+
+type exportReader struct {
+	reader *bufio.Reader
+}
+
+func newExportReader(r io.Reader) (*exportReader, error) {
+	er := &exportReader{bufio.NewReader(r)}
+
+	header := make([]byte, len(EXPORT_MAGIC)+1)
+	if _, err := io.ReadFull(er.reader, header); err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not read the header: %v", err))
+	}
+	if string(header[:len(EXPORT_MAGIC)]) != EXPORT_MAGIC {
+		return nil, errors.New("Not an exported chain.")
+	}
+	if header[len(EXPORT_MAGIC)] != EXPORT_VERSION {
+		return nil, errors.New(fmt.Sprintf("Unsupported export version %v.", header[len(EXPORT_MAGIC)]))
+	}
+
+	return er, nil
+}
+
+func (er *exportReader) next() (recordType byte, payload []byte, err error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(er.reader, header); err != nil {
+		return 0, nil, errors.New(fmt.Sprintf("File is truncated: %v", err))
+	}
+
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > EXPORT_MAX_RECORD_SIZE {
+		return 0, nil, errors.New(fmt.Sprintf("Record of %v bytes is too large.", length))
+	}
+
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(er.reader, payload); err != nil {
+		return 0, nil, errors.New(fmt.Sprintf("File is truncated: %v", err))
+	}
+
+	return header[0], payload, nil
+}
+
+//Writes the closed chain, from the genesis block to the last closed block, along with all its transactions to w.
+//Returns the number of exported blocks.
+func Export(w io.Writer) (blocks int, err error) {
+	chain, err := readClosedChain()
+	if err != nil {
+		return 0, err
+	}
+
+	ew, err := newExportWriter(w)
+	if err != nil {
+		return 0, err
+	}
+
+	written := make(map[[32]byte]bool)
+	for _, block := range chain {
+		txs, err := blockTransactions(block, storage.ReadClosedTx)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, tx := range txs {
+			if written[tx.Hash()] {
+				continue
+			}
+			if err := ew.write(EXPORT_RECORD_TX, tx.Encode()); err != nil {
+				return 0, err
+			}
+			written[tx.Hash()] = true
+		}
+
+		if err := ew.write(EXPORT_RECORD_BLOCK, block.Encode()); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := ew.end(len(chain)); err != nil {
+		return 0, err
+	}
+
+	return len(chain), nil
+}
+
+//Returns the closed blocks from the genesis block to the last closed block. Blocks which have been emptied are in the
+//bucket of blocks without transactions.
+func readClosedChain() (chain []*protocol.Block, err error) {
+	block := storage.ReadLastClosedBlock()
+	if block == nil {
+		return nil, errors.New("There is no closed block.")
+	}
+
+	for {
+		chain = append(chain, block)
+		if block.Height == 0 {
+			break
+		}
+
+		prevBlock := storage.ReadClosedBlock(block.PrevHash)
+		if prevBlock == nil {
+			prevBlock = storage.ReadClosedBlockWithoutTx(block.PrevHashWithoutTx)
+		}
+		if prevBlock == nil {
+			return nil, errors.New(fmt.Sprintf("Predecessor of block (%x) at height %v not found.", block.Hash[0:8], block.Height))
+		}
+		block = prevBlock
+	}
+
+	return InvertBlockArray(chain), nil
+}
+
+//Returns all transactions the validation of the block needs, including those aggregated in its AggTxs (recursively).
+//Aggregated transactions precede the AggTx which aggregates them.
+func blockTransactions(block *protocol.Block, readTx func(hash [32]byte) protocol.Transaction) (txs []protocol.Transaction, err error) {
+	var read func(hashes [][32]byte) error
+	read = func(hashes [][32]byte) error {
+		for _, hash := range hashes {
+			tx := readTx(hash)
+			if tx == nil {
+				return errors.New(fmt.Sprintf("Transaction (%x) of block (%x) not found.", hash[0:8], block.Hash[0:8]))
+			}
+
+			if aggTx, ok := tx.(*protocol.AggTx); ok {
+				if err := read(aggTx.AggregatedTxSlice); err != nil {
+					return err
+				}
+			}
+			txs = append(txs, tx)
+		}
+		return nil
+	}
+
+	for _, hashes := range [][][32]byte{block.AccTxData, block.FundsTxData, block.ConfigTxData, block.StakeTxData, block.AggTxData} {
+		if err := read(hashes); err != nil {
+			return nil, err
+		}
+	}
+
+	return txs, nil
+}
+
+//Validates and applies an exported chain to an empty database, without network. The root wallet and commitment must
+//be the ones the chain was started with. Returns the number of imported blocks.
+func Import(r io.Reader, rootWallet *ecdsa.PublicKey, rootCommitment *rsa.PrivateKey) (blocks int, err error) {
+	if storage.ReadLastClosedBlock() != nil {
+		return 0, errors.New("Database is not empty.")
+	}
+
+	er, err := newExportReader(r)
+	if err != nil {
+		return 0, err
+	}
+
+	if logger == nil {
+		logger = storage.InitLogger()
+	}
+
+	//Same setup as in Init.
+	rootCommPrivKey = rootCommitment
+	parameterSlice = append(parameterSlice, NewDefaultParameters())
+	activeParameters = &parameterSlice[0]
+	initRootKey(rootWallet)
+	currentTargetTime = new(timerange)
+	target = append(target, 13)
+
+	//Nobody consumes the verified transactions without network.
+	done := make(chan bool)
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-p2p.VerifiedTxsOut:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var prevBlock *protocol.Block
+	for {
+		recordType, payload, err := er.next()
+		if err != nil {
+			return blocks, err
+		}
+
+		switch recordType {
+		case EXPORT_RECORD_TX:
+			tx := protocol.DecodeTx(payload)
+			if tx == nil {
+				return blocks, errors.New(fmt.Sprintf("Transaction after block %v could not be decoded.", blocks))
+			}
+			storage.WriteOpenTx(tx)
+		case EXPORT_RECORD_BLOCK:
+			var block *protocol.Block
+			if block = block.Decode(payload); block == nil {
+				return blocks, errors.New(fmt.Sprintf("Block %v could not be decoded.", blocks))
+			}
+
+			if err := importBlock(block, prevBlock); err != nil {
+				return blocks, errors.New(fmt.Sprintf("Block (%x) at height %v could not be imported: %v", block.Hash[0:8], block.Height, err))
+			}
+			logger.Printf("Block imported: %d --> %x", block.Height, block.Hash[0:8])
+
+			prevBlock = block
+			blocks++
+		case EXPORT_RECORD_END:
+			if len(payload) != 4 || int(binary.BigEndian.Uint32(payload)) != blocks {
+				return blocks, errors.New(fmt.Sprintf("File ends after %v blocks, but the end record does not match.", blocks))
+			}
+			if prevBlock == nil {
+				return 0, errors.New("File does not contain any block.")
+			}
+
+			persistState(lastBlock)
+			return blocks, nil
+		default:
+			return blocks, errors.New(fmt.Sprintf("Unknown record type %v.", recordType))
+		}
+	}
+}
+
+//The genesis block is stored as during the initial setup, every other block is validated like a block of a miner
+//that catches up with the network.
+func importBlock(block, prevBlock *protocol.Block) error {
+	if prevBlock == nil {
+		if block.Height != 0 {
+			return errors.New("Chain does not start with the genesis block.")
+		}
+
+		storage.WriteLastClosedBlock(block)
+		storage.WriteClosedBlock(block)
+		postValidate(blockData{nil, nil, nil, nil, nil, nil, block}, true)
+		return nil
+	}
+
+	if block.PrevHash != prevBlock.Hash && block.PrevHashWithoutTx != prevBlock.HashWithoutTx {
+		return errors.New(fmt.Sprintf("Block does not follow block (%x).", prevBlock.Hash[0:8]))
+	}
+
+	//All transactions need to be in the file, otherwise they would be requested from the network.
+	_, err := blockTransactions(block, func(hash [32]byte) protocol.Transaction {
+		if tx := storage.ReadOpenTx(hash); tx != nil {
+			return tx
+		}
+		return storage.ReadClosedTx(hash)
+	})
+	if err != nil {
+		return err
+	}
+
+	return validate(block, true)
+}
@@ -0,0 +1,93 @@
+package miner
+
+import (
+	"bytes"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	fundsTx, _ := protocol.ConstrFundsTx(0x01, 10, 1, 0, accAHash, accBHash, PrivKeyAccA, nil, nil)
+	aggregatedTx, _ := protocol.ConstrFundsTx(0x01, 20, 1, 1, accAHash, accBHash, PrivKeyAccA, nil, nil)
+	aggTx, _ := protocol.ConstrAggTx(20, 1, [][32]byte{accAHash}, [][32]byte{accBHash}, [][32]byte{aggregatedTx.Hash()})
+	for _, tx := range []protocol.Transaction{fundsTx, aggregatedTx, aggTx} {
+		storage.WriteClosedTx(tx)
+	}
+
+	b := newBlock(genesisBlock.Hash, genesisBlock.HashWithoutTx, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	b.FundsTxData = [][32]byte{fundsTx.Hash()}
+	b.AggTxData = [][32]byte{aggTx.Hash()}
+	b.NrFundsTx, b.NrAggTx = 1, 1
+	b.Hash = b.HashBlock()
+	storage.WriteClosedBlock(b)
+	storage.DeleteAllLastClosedBlock()
+	storage.WriteLastClosedBlock(b)
+
+	var exported bytes.Buffer
+	if blocks, err := Export(&exported); err != nil || blocks != 2 {
+		t.Fatalf("Expected 2 exported blocks but were %v: %v\n", blocks, err)
+	}
+
+	er, err := newExportReader(bytes.NewReader(exported.Bytes()))
+	if err != nil {
+		t.Fatalf("Could not read exported chain: %v\n", err)
+	}
+
+	//The aggregated tx precedes its AggTx, the txs precede their block.
+	expected := []interface{}{genesisBlock.Hash, fundsTx.Hash(), aggregatedTx.Hash(), aggTx.Hash(), b.Hash}
+	for i, hash := range expected {
+		recordType, payload, err := er.next()
+		if err != nil {
+			t.Fatalf("Could not read record %v: %v\n", i, err)
+		}
+
+		var recordHash [32]byte
+		switch recordType {
+		case EXPORT_RECORD_TX:
+			recordHash = protocol.DecodeTx(payload).Hash()
+		case EXPORT_RECORD_BLOCK:
+			var block *protocol.Block
+			recordHash = block.Decode(payload).Hash
+		}
+		if recordHash != hash {
+			t.Errorf("Expected record %v to be %x but was %x\n", i, hash, recordHash)
+		}
+	}
+	if recordType, _, err := er.next(); err != nil || recordType != EXPORT_RECORD_END {
+		t.Errorf("Expected end record after the last block: %v\n", err)
+	}
+
+	//A database with a chain is not overwritten.
+	if _, err := Import(bytes.NewReader(exported.Bytes()), &PrivKeyRoot.PublicKey, CommPrivKeyRoot); err == nil {
+		t.Errorf("Chain was imported into a non-empty database\n")
+	}
+}
+
+func TestImport(t *testing.T) {
+	cleanAndPrepare()
+
+	var exported bytes.Buffer
+	ew, _ := newExportWriter(&exported)
+	ew.write(EXPORT_RECORD_BLOCK, genesisBlock.Encode())
+	ew.end(1)
+
+	storage.DeleteAll()
+	truncated := exported.Bytes()[:exported.Len()-1]
+	if _, err := Import(bytes.NewReader(truncated), &PrivKeyRoot.PublicKey, CommPrivKeyRoot); err == nil {
+		t.Errorf("Truncated chain was imported\n")
+	}
+
+	storage.DeleteAll()
+	if blocks, err := Import(bytes.NewReader(exported.Bytes()), &PrivKeyRoot.PublicKey, CommPrivKeyRoot); err != nil || blocks != 1 {
+		t.Fatalf("Expected 1 imported block but were %v: %v\n", blocks, err)
+	}
+	if lastClosedBlock := storage.ReadLastClosedBlock(); lastClosedBlock == nil || lastClosedBlock.Hash != genesisBlock.Hash {
+		t.Errorf("Expected the genesis block as last closed block but was %v\n", lastClosedBlock)
+	}
+}
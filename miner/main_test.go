@@ -221,6 +221,7 @@ func cleanAndPrepare() {
 
 	addTestingAccounts()
 	addRootAccounts()
+	resetStateChanges()
 
 	genesisCommitmentProof, _ := crypto.SignMessageWithRSAKey(CommPrivKeyRoot, "0")
 	genesisBlock = newBlock([32]byte{}, genesisCommitmentProof, 0)
@@ -248,6 +249,7 @@ func TestMain(m *testing.M) {
 	cleanAndPrepare()
 	addTestingAccounts()
 	addRootAccounts()
+	resetStateChanges()
 	//We don't want logging msgs when testing, we have designated messages
 	logger = log.New(nil, "", 0)
 	logger.SetOutput(ioutil.Discard)
@@ -21,6 +21,11 @@ func processBlock(payload []byte) {
 
 	var block *protocol.Block
 	block = block.Decode(payload)
+	if block == nil {
+		logger.Println("Received block could not be decoded.")
+		return
+	}
+
 	//Block already confirmed and validated
 	if storage.ReadClosedBlock(block.Hash) != nil {
 		logger.Printf("Received block (%x) has already been validated.\n", block.Hash[0:8])
@@ -79,3 +84,24 @@ func broadcastVerifiedFundsTxsToOtherMiners(txs []*protocol.FundsTx) {
 		p2p.VerifiedTxsBrdcstOut <- toBrdcst
 	}
 }
+
+//Gossip a single tx the same way the p2p package does with received tx broadcasts.
+func broadcastTx(tx protocol.Transaction) {
+	var brdcstType uint8
+	switch tx.(type) {
+	case *protocol.FundsTx:
+		brdcstType = p2p.FUNDSTX_BRDCST
+	case *protocol.AccTx:
+		brdcstType = p2p.ACCTX_BRDCST
+	case *protocol.ConfigTx:
+		brdcstType = p2p.CONFIGTX_BRDCST
+	case *protocol.StakeTx:
+		brdcstType = p2p.STAKETX_BRDCST
+	case *protocol.AggTx:
+		brdcstType = p2p.AGGTX_BRDCST
+	default:
+		return
+	}
+
+	p2p.VerifiedTxsBrdcstOut <- p2p.BuildPacket(brdcstType, tx.Encode())
+}
@@ -0,0 +1,19 @@
+package miner
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Returns a copy of the account in the current state and whether it is a root account. The state is read under the
+//validation lock, such that no block is applied or rolled back meanwhile.
+func GetAccount(hash [32]byte) (acc *protocol.Account, isRoot bool, err error) {
+	blockValidation.Lock()
+	defer blockValidation.Unlock()
+
+	if acc, err = storage.GetAccount(hash); err != nil {
+		return nil, false, err
+	}
+
+	return storage.CopyAccount(acc), storage.IsRootKey(hash), nil
+}
@@ -0,0 +1,38 @@
+package miner
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+func TestGetAccount(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	acc, isRoot, err := GetAccount(accAHash)
+	if err != nil || isRoot {
+		t.Fatalf("Expected non-root account A but got %v, %v, %v\n", acc, isRoot, err)
+	}
+
+	//The caller gets a copy, the state is only changed by blocks.
+	acc.Balance++
+	if acc.Balance == storage.State[accAHash].Balance {
+		t.Errorf("Change of the returned account is visible in the state\n")
+	}
+
+	if _, _, err := GetAccount([32]byte{}); err == nil {
+		t.Errorf("Expected an error for an account which is not in the state\n")
+	}
+}
+
+func TestGetActiveParameters(t *testing.T) {
+	cleanAndPrepare()
+
+	params := GetActiveParameters()
+	params.Fee_minimum++
+	if params.Fee_minimum == activeParameters.Fee_minimum {
+		t.Errorf("Change of the returned parameters is visible in the active parameters\n")
+	}
+}
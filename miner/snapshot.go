@@ -0,0 +1,294 @@
+package miner
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"golang.org/x/crypto/sha3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	SNAPSHOT_VERSION        = 1
+	SNAPSHOT_FILE_EXTENSION = ".snapshot"
+	SNAPSHOT_HEADER_SIZE    = 1 + 32 //Version and checksum
+)
+
+var (
+	snapshotDir      string
+	snapshotInterval uint32
+)
+
+//The system parameters and the difficulty history are derived from the chain like the account state. They are
+//persisted along with the state and are part of every snapshot.
+type chainMeta struct {
+	Parameters        []Parameters
+	Target            []uint8
+	TargetTimes       [][2]int64
+	CurrentTargetTime [2]int64
+	GlobalBlockCount  int64
+	LocalBlockCount   int64
+}
+
+//A snapshot holds everything needed to continue validating blocks after Block.
+type Snapshot struct {
+	Block    *protocol.Block
+	Accounts []*protocol.Account
+	RootKeys [][32]byte
+	meta     []byte
+}
+
+//Wire format of a snapshot file's payload, blocks and accounts use their protocol encoding.
+type snapshotContent struct {
+	Block    []byte
+	Accounts [][]byte
+	RootKeys [][32]byte
+	Meta     []byte
+}
+
+//Snapshots are written to dir every interval blocks, an interval of 0 disables them.
+func InitSnapshots(dir string, interval uint32) {
+	snapshotDir = dir
+	snapshotInterval = interval
+}
+
+func encodeChainMeta() []byte {
+	meta := chainMeta{
+		Parameters:       parameterSlice,
+		Target:           target,
+		GlobalBlockCount: globalBlockCount,
+		LocalBlockCount:  localBlockCount,
+	}
+	for _, t := range targetTimes {
+		meta.TargetTimes = append(meta.TargetTimes, [2]int64{t.first, t.last})
+	}
+	if currentTargetTime != nil {
+		meta.CurrentTargetTime = [2]int64{currentTargetTime.first, currentTargetTime.last}
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(meta)
+	return buffer.Bytes()
+}
+
+func decodeChainMeta(encoded []byte) (meta *chainMeta, err error) {
+	meta = new(chainMeta)
+	if err := gob.NewDecoder(bytes.NewBuffer(encoded)).Decode(meta); err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not decode chain metadata: %v", err))
+	}
+	if len(meta.Parameters) == 0 || len(meta.Target) == 0 {
+		return nil, errors.New("Chain metadata without parameters or target.")
+	}
+
+	return meta, nil
+}
+
+func (meta *chainMeta) restore() {
+	parameterSlice = meta.Parameters
+	//Unexported fields are not encoded.
+	for i := range parameterSlice {
+		parameterSlice[i].num_included_prev_proofs = NUM_INCL_PREV_PROOFS
+	}
+	activeParameters = &parameterSlice[len(parameterSlice)-1]
+
+	target = meta.Target
+	targetTimes = nil
+	for _, t := range meta.TargetTimes {
+		targetTimes = append(targetTimes, timerange{t[0], t[1]})
+	}
+	currentTargetTime = &timerange{meta.CurrentTargetTime[0], meta.CurrentTargetTime[1]}
+
+	globalBlockCount = meta.GlobalBlockCount
+	localBlockCount = meta.LocalBlockCount
+}
+
+//The state is persisted incrementally, changedAccounts lists the accounts which changed since the state of
+//persistedBlock was persisted. It is nil if the whole state has to be written, e.g., because the state was replaced.
+var (
+	persistedBlock  [32]byte
+	changedAccounts map[[32]byte]bool
+)
+
+//Persists the state of the last closed block together with the chain metadata. Only the changed accounts are written,
+//unless the persisted state does not correspond to persistedBlock (e.g., the database was reset). The state tree of the
+//block is published as well, account proofs are served from it.
+func persistState(block *protocol.Block) {
+	storage.WriteClosedStateTree(block, globalStateTree())
+
+	meta := encodeChainMeta()
+
+	var accHashes [][32]byte
+	for hash := range changedAccounts {
+		accHashes = append(accHashes, hash)
+	}
+
+	var err error
+	if changedAccounts == nil || storage.WriteLastClosedBlockWithStateChanges(block, meta, persistedBlock, accHashes) != nil {
+		err = storage.WriteLastClosedBlockWithState(block, meta)
+	}
+	if err != nil {
+		logger.Printf("Could not persist the state of block (%x): %v\n", block.Hash[0:8], err)
+		resetStateChanges()
+		return
+	}
+
+	persistedBlock = block.Hash
+	changedAccounts = make(map[[32]byte]bool)
+}
+
+//Called after a block has been validated, the state must correspond to the block.
+func writeSnapshotIfDue(block *protocol.Block) {
+	if snapshotInterval == 0 || block.Height%snapshotInterval != 0 {
+		return
+	}
+
+	snapshot := &Snapshot{Block: block, meta: encodeChainMeta()}
+	for hash, acc := range storage.State {
+		snapshot.Accounts = append(snapshot.Accounts, acc)
+		if storage.IsRootKey(hash) {
+			snapshot.RootKeys = append(snapshot.RootKeys, hash)
+		}
+	}
+
+	filename, err := WriteSnapshot(snapshotDir, snapshot)
+	if err != nil {
+		logger.Printf("Could not write snapshot of block (%x): %v\n", block.Hash[0:8], err)
+		return
+	}
+
+	logger.Printf("Snapshot of block (%x) at height %v written to %v\n", block.Hash[0:8], block.Height, filename)
+}
+
+//Snapshot file names start with the zero padded height, such that they are sorted by height.
+func WriteSnapshot(dir string, snapshot *Snapshot) (filename string, err error) {
+	content := snapshotContent{
+		Block:    snapshot.Block.Encode(),
+		RootKeys: snapshot.RootKeys,
+		Meta:     snapshot.meta,
+	}
+	for _, acc := range snapshot.Accounts {
+		content.Accounts = append(content.Accounts, acc.Encode())
+	}
+
+	payload := new(bytes.Buffer)
+	if err := gob.NewEncoder(payload).Encode(content); err != nil {
+		return "", err
+	}
+
+	checksum := sha3.Sum256(payload.Bytes())
+	encoded := append([]byte{SNAPSHOT_VERSION}, checksum[:]...)
+	encoded = append(encoded, payload.Bytes()...)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	//Write to a temporary file first, a crash must not leave a truncated snapshot behind.
+	filename = filepath.Join(dir, fmt.Sprintf("%010d-%x%v", snapshot.Block.Height, snapshot.Block.Hash[0:8], SNAPSHOT_FILE_EXTENSION))
+	if err := ioutil.WriteFile(filename+".tmp", encoded, 0600); err != nil {
+		return "", err
+	}
+
+	return filename, os.Rename(filename+".tmp", filename)
+}
+
+//Reads and verifies a snapshot file.
+func ReadSnapshot(filename string) (snapshot *Snapshot, err error) {
+	encoded, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(encoded) < SNAPSHOT_HEADER_SIZE {
+		return nil, errors.New("Snapshot is truncated.")
+	}
+	if encoded[0] != SNAPSHOT_VERSION {
+		return nil, errors.New(fmt.Sprintf("Unsupported snapshot version %v.", encoded[0]))
+	}
+
+	payload := encoded[SNAPSHOT_HEADER_SIZE:]
+	if checksum := sha3.Sum256(payload); !bytes.Equal(checksum[:], encoded[1:SNAPSHOT_HEADER_SIZE]) {
+		return nil, errors.New("Snapshot checksum does not match.")
+	}
+
+	var content snapshotContent
+	if err := gob.NewDecoder(bytes.NewBuffer(payload)).Decode(&content); err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not decode snapshot: %v", err))
+	}
+
+	var block *protocol.Block
+	if block = block.Decode(content.Block); block == nil {
+		return nil, errors.New("Snapshot does not contain a block.")
+	}
+
+	if _, err := decodeChainMeta(content.Meta); err != nil {
+		return nil, err
+	}
+
+	snapshot = &Snapshot{Block: block, RootKeys: content.RootKeys, meta: content.Meta}
+
+	accounts := make(map[[32]byte]*protocol.Account)
+	for _, encodedAcc := range content.Accounts {
+		var acc *protocol.Account
+		if acc = acc.Decode(encodedAcc); acc == nil {
+			return nil, errors.New("Snapshot contains an account which can't be decoded.")
+		}
+		if accounts[acc.Hash()] != nil {
+			return nil, errors.New(fmt.Sprintf("Account (%x) is contained twice.", acc.Hash()))
+		}
+		accounts[acc.Hash()] = acc
+		snapshot.Accounts = append(snapshot.Accounts, acc)
+	}
+
+	for _, rootKey := range snapshot.RootKeys {
+		if accounts[rootKey] == nil {
+			return nil, errors.New(fmt.Sprintf("Root key (%x) has no account.", rootKey[0:8]))
+		}
+	}
+
+	if root := protocol.BuildStateTree(accounts).Root(); block.Height > 0 && root != block.StateRoot {
+		return nil, errors.New(fmt.Sprintf("Accounts do not match the state root of the block: %x vs. %x", root[0:8], block.StateRoot[0:8]))
+	}
+
+	return snapshot, nil
+}
+
+//Returns the snapshot files in dir, ordered by height.
+func ListSnapshots(dir string) (filenames []string, err error) {
+	filenames, err = filepath.Glob(filepath.Join(dir, "*"+SNAPSHOT_FILE_EXTENSION))
+	sort.Strings(filenames)
+	return filenames, err
+}
+
+//Replaces the content of the (already initialized) storage with the snapshot. A miner started on this storage
+//continues at the snapshot's block and syncs the remaining blocks from the network.
+func RestoreSnapshot(snapshot *Snapshot) error {
+	storage.DeleteAll()
+
+	state := make(map[[32]byte]*protocol.Account)
+	for _, acc := range snapshot.Accounts {
+		state[acc.Hash()] = acc
+	}
+	rootKeys := make(map[[32]byte]*protocol.Account)
+	for _, rootKey := range snapshot.RootKeys {
+		rootKeys[rootKey] = state[rootKey]
+	}
+	storage.State, storage.RootKeys = state, rootKeys
+
+	if snapshot.Block.Aggregated {
+		if err := storage.WriteClosedBlockWithoutTx(snapshot.Block); err != nil {
+			return err
+		}
+	} else if err := storage.WriteClosedBlock(snapshot.Block); err != nil {
+		return err
+	}
+
+	resetStateChanges()
+	return storage.WriteLastClosedBlockWithState(snapshot.Block, snapshot.meta)
+}
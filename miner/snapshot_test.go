@@ -0,0 +1,190 @@
+package miner
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"golang.org/x/crypto/sha3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	cleanAndPrepare()
+
+	dir, _ := ioutil.TempDir("", "snapshots")
+	defer os.RemoveAll(dir)
+	InitSnapshots(dir, 2)
+	defer InitSnapshots("", 0)
+
+	b := newBlock(genesisBlock.Hash, [32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	b.Hash = b.HashBlock()
+	b2 := newBlock(b.Hash, [32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 2)
+	b2.Hash = b2.HashBlock()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	storage.State[accAHash].Balance = 4242
+	resetStateChanges()
+	b2.StateRoot = stateRoot()
+
+	//Only every second block is written.
+	writeSnapshotIfDue(b)
+	writeSnapshotIfDue(b2)
+
+	filenames, _ := ListSnapshots(dir)
+	if len(filenames) != 1 {
+		t.Fatalf("Expected 1 snapshot but found %v\n", len(filenames))
+	}
+
+	snapshot, err := ReadSnapshot(filenames[0])
+	if err != nil {
+		t.Fatalf("Could not read snapshot: %v\n", err)
+	}
+	if snapshot.Block.Hash != b2.Hash {
+		t.Errorf("Expected snapshot of block %x but was %x\n", b2.Hash[0:8], snapshot.Block.Hash[0:8])
+	}
+	if len(snapshot.Accounts) != len(storage.State) || len(snapshot.RootKeys) != len(storage.RootKeys) {
+		t.Errorf("Expected %v accounts and %v root keys but were %v and %v\n", len(storage.State), len(storage.RootKeys), len(snapshot.Accounts), len(snapshot.RootKeys))
+	}
+
+	if err := RestoreSnapshot(snapshot); err != nil {
+		t.Fatalf("Could not restore snapshot: %v\n", err)
+	}
+	storage.State[accAHash].Balance = 0
+	if replayFrom := fastForwardState([]*protocol.Block{storage.ReadLastClosedBlock()}); replayFrom != 1 {
+		t.Errorf("Expected restored state of the snapshot block\n")
+	}
+	if storage.State[accAHash].Balance != 4242 {
+		t.Errorf("Expected restored balance 4242 but was %v\n", storage.State[accAHash].Balance)
+	}
+
+	//A corrupted snapshot is rejected.
+	encoded, _ := ioutil.ReadFile(filenames[0])
+	encoded[len(encoded)-1]++
+	ioutil.WriteFile(filenames[0], encoded, 0600)
+	if _, err := ReadSnapshot(filenames[0]); err == nil {
+		t.Errorf("Corrupted snapshot was not detected\n")
+	}
+
+	//Accounts which don't match the state root of the block are rejected.
+	snapshot.Accounts[0].Balance++
+	snapshot.Block.Height = 4
+	filename, _ := WriteSnapshot(dir, snapshot)
+	if _, err := ReadSnapshot(filename); err == nil {
+		t.Errorf("Snapshot with a wrong state root was not detected\n")
+	}
+}
+
+func TestReadSnapshot_UndecodableAccount(t *testing.T) {
+	cleanAndPrepare()
+
+	dir, _ := ioutil.TempDir("", "snapshots")
+	defer os.RemoveAll(dir)
+
+	//The checksum is valid, the account is truncated.
+	content := snapshotContent{
+		Block:    genesisBlock.Encode(),
+		Meta:     encodeChainMeta(),
+		Accounts: [][]byte{accA.Encode()[:10]},
+	}
+	payload := new(bytes.Buffer)
+	gob.NewEncoder(payload).Encode(content)
+	checksum := sha3.Sum256(payload.Bytes())
+	encoded := append(append([]byte{SNAPSHOT_VERSION}, checksum[:]...), payload.Bytes()...)
+
+	filename := filepath.Join(dir, "truncated"+SNAPSHOT_FILE_EXTENSION)
+	ioutil.WriteFile(filename, encoded, 0600)
+	if _, err := ReadSnapshot(filename); err == nil {
+		t.Errorf("Snapshot with an undecodable account was not rejected\n")
+	}
+}
+
+func TestFastForwardState(t *testing.T) {
+	cleanAndPrepare()
+
+	b := newBlock(genesisBlock.Hash, [32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	b.Hash = b.HashBlock()
+	storage.WriteClosedBlock(b)
+
+	b2 := newBlock(b.Hash, [32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 2)
+	b2.Hash = b2.HashBlock()
+	storage.WriteClosedBlock(b2)
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	storage.State[accAHash].Balance = 4242
+	params := NewDefaultParameters()
+	params.Fee_minimum = 5
+	parameterSlice = append(parameterSlice, params)
+	activeParameters = &parameterSlice[len(parameterSlice)-1]
+	globalBlockCount = 7
+	resetStateChanges()
+	b.StateRoot = stateRoot()
+	persistState(b)
+
+	//Simulate a restart, the state and system parameters are back to the defaults.
+	storage.State[accAHash].Balance = 0
+	parameterSlice = []Parameters{NewDefaultParameters()}
+	activeParameters = &parameterSlice[0]
+	globalBlockCount = 0
+
+	if replayFrom := fastForwardState([]*protocol.Block{genesisBlock, b, b2}); replayFrom != 2 {
+		t.Errorf("Expected replay to start at index 2 but was %v\n", replayFrom)
+	}
+	if storage.State[accAHash].Balance != 4242 {
+		t.Errorf("Expected persisted balance 4242 but was %v\n", storage.State[accAHash].Balance)
+	}
+	if activeParameters.Fee_minimum != 5 || len(parameterSlice) != 2 {
+		t.Errorf("Expected persisted parameters (fee minimum 5) but fee minimum was %v\n", activeParameters.Fee_minimum)
+	}
+	if activeParameters.num_included_prev_proofs != NUM_INCL_PREV_PROOFS {
+		t.Errorf("Expected %v included previous proofs but was %v\n", NUM_INCL_PREV_PROOFS, activeParameters.num_included_prev_proofs)
+	}
+	if globalBlockCount != 7 {
+		t.Errorf("Expected persisted global block count 7 but was %v\n", globalBlockCount)
+	}
+	if lastBlock != b {
+		t.Errorf("Expected last block %x but was %x\n", b.Hash[0:8], lastBlock.Hash[0:8])
+	}
+
+	//State of a block which is not on the chain is ignored.
+	if replayFrom := fastForwardState([]*protocol.Block{genesisBlock}); replayFrom != 0 {
+		t.Errorf("Expected full replay but replay started at index %v\n", replayFrom)
+	}
+}
+
+func TestPersistState_Changes(t *testing.T) {
+	cleanAndPrepare()
+
+	b := newBlock(genesisBlock.Hash, [32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	b.Hash = b.HashBlock()
+	b2 := newBlock(b.Hash, [32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 2)
+	b2.Hash = b2.HashBlock()
+
+	//Nothing has been persisted yet, the whole state is written.
+	resetStateChanges()
+	persistState(b)
+	if changedAccounts == nil || persistedBlock != b.Hash {
+		t.Fatalf("Expected the state changes to be tracked after the state of block (%x) was persisted\n", b.Hash[0:8])
+	}
+
+	//Only the accounts a block changed through the global state are written.
+	accAHash, accBHash := protocol.SerializeHashContent(accA.Address), protocol.SerializeHashContent(accB.Address)
+	balanceB := storage.State[accBHash].Balance
+	state := globalState()
+	acc, _ := state.getAccount(accAHash)
+	acc.Balance = 4242
+	state.recordChanges()
+	storage.State[accBHash].Balance = balanceB + 1
+	persistState(b2)
+
+	if _, err := storage.LoadState(); err != nil {
+		t.Fatalf("Could not load the persisted state: %v\n", err)
+	}
+	if storage.State[accAHash].Balance != 4242 || storage.State[accBHash].Balance != balanceB {
+		t.Errorf("Expected only the change of account A to be persisted but balances were %v and %v\n", storage.State[accAHash].Balance, storage.State[accBHash].Balance)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/bazo-blockchain/bazo-miner/p2p"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
+	"github.com/bazo-blockchain/bazo-miner/vm"
 	"sort"
 	"strconv"
 	"time"
@@ -184,8 +185,11 @@ func initState() (initialBlock *protocol.Block, err error) {
 		allClosedBlocks = InvertBlockArray(allClosedBlocks)
 	}
 
+	//Blocks which are covered by the persisted state don't need to be validated again.
+	replayFrom := fastForwardState(allClosedBlocks)
+
 	//Validate all closed blocks and update state
-	for _, blockToValidate := range allClosedBlocks {
+	for _, blockToValidate := range allClosedBlocks[replayFrom:] {
 		//Prepare datastructure to fill tx payloads
 		blockDataMap := make(map[[32]byte]blockData)
 
@@ -199,7 +203,7 @@ func initState() (initialBlock *protocol.Block, err error) {
 
 			blockDataMap[blockToValidate.Hash] = blockData{accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, aggregatedFundsTxSlice, blockToValidate}
 
-			err = validateState(blockDataMap[blockToValidate.Hash], true)
+			err = validateStateRoot(blockDataMap[blockToValidate.Hash], true)
 			if err != nil {
 				return nil, errors.New(fmt.Sprintf("Block (%x) could not be statevalidated: %v\n", blockToValidate.Hash[0:8], err))
 			}
@@ -221,54 +225,159 @@ func initState() (initialBlock *protocol.Block, err error) {
 	}
 
 
-	logger.Printf("\n\n%v block(s) validated. Chain good to go.\n------------------------------------------------------------------------\n\n", len(allClosedBlocks))
+	persistState(lastBlock)
+
+	logger.Printf("\n\n%v block(s) validated. Chain good to go.\n------------------------------------------------------------------------\n\n", len(allClosedBlocks)-replayFrom)
 	logger.Printf("Last Block: \n%v\n------------------------------------------------------------------------\n\n", lastBlock)
 	logger.Printf("Current STATE: \n%v\n------------------------------------------------------------------------\n\n", getState())
 
 	return initialBlock, nil
 }
 
-func accStateChange(txSlice []*protocol.AccTx) error {
+//If the persisted state corresponds to one of the given blocks (in ascending order), the state and the chain
+//metadata (system parameters, difficulty history) are loaded. Returns the index of the first block which has to be
+//validated.
+func fastForwardState(blocks []*protocol.Block) int {
+	stateBlockHash, err := storage.ReadStateBlockHash()
+	if err != nil {
+		return 0
+	}
+
+	index := -1
+	for i, block := range blocks {
+		if block.Hash == stateBlockHash {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		logger.Printf("Persisted state of block (%x) is not on the chain, replaying all blocks.\n", stateBlockHash[0:8])
+		return 0
+	}
+
+	meta, err := decodeChainMeta(storage.ReadStateMeta())
+	if err != nil {
+		logger.Printf("Could not load persisted chain metadata, replaying all blocks: %v\n", err)
+		return 0
+	}
+
+	state, rootKeys := storage.CopyState()
+	if _, err := storage.LoadState(); err != nil {
+		logger.Printf("Could not load persisted state, replaying all blocks: %v\n", err)
+		return 0
+	}
+	resetStateChanges()
+
+	//The genesis block does not commit to a state.
+	if root := stateRoot(); blocks[index].Height > 0 && root != blocks[index].StateRoot {
+		logger.Printf("Persisted state does not match the state root of block (%x), replaying all blocks.\n", stateBlockHash[0:8])
+		storage.State, storage.RootKeys = state, rootKeys
+		resetStateChanges()
+		return 0
+	}
+
+	meta.restore()
+	lastBlock = blocks[index]
+	persistedBlock = stateBlockHash
+	changedAccounts = make(map[[32]byte]bool)
+
+	logger.Printf("Loaded persisted state of block (%x) at height %v.\n", stateBlockHash[0:8], blocks[index].Height)
+
+	return index + 1
+}
+
+//Upgrades replace the contract of an existing account, the previous contract and variables are recorded in the journal.
+func accStateChange(state *blockState, txSlice []*protocol.AccTx, block *protocol.Block, journal *storage.Journal) error {
 	for _, tx := range txSlice {
-		if tx.Header != 2 {
+		if tx.Header == protocol.ACCTX_UPGRADE {
+			if err := upgradeContract(state, tx, block, journal); err != nil {
+				return err
+			}
+		} else if tx.Header != 2 {
 			newAcc := protocol.NewAccount(tx.PubKey, tx.Issuer, 0, false, [crypto.COMM_KEY_LENGTH]byte{}, tx.Contract, tx.ContractVariables)
 			newAccHash := newAcc.Hash()
 
-			acc, _ := storage.GetAccount(newAccHash)
+			acc, _ := state.getAccount(newAccHash)
 			if acc != nil {
 				//Shouldn't happen, because this should have been prevented when adding an accTx to the block
 				return errors.New("Address already exists in the state.")
 			}
 
 			//If acc does not exist, write to state
-			storage.State[newAccHash] = &newAcc
+			state.setAccount(newAccHash, &newAcc)
 
 			if tx.Header == 1 {
 				//First bit set, given account will be a new root account
 				//It might be cleaner to move this to the storage package (e.g., storage.Delete(...))
 				//leave it here for now (not fully convinced yet)
-				storage.RootKeys[newAccHash] = &newAcc
+				state.setRootKey(newAccHash, &newAcc)
 			}
 		} else if tx.Header == 2 {
 			accHash := protocol.SerializeHashContent(tx.PubKey)
-			_, err := storage.GetAccount(accHash)
+			_, err := state.getAccount(accHash)
 			if err != nil {
 				return err
 			}
 
 			//Second bit set, delete account from root account
-			delete(storage.RootKeys, accHash)
+			state.deleteRootKey(accHash)
 		}
 	}
 
 	return nil
 }
 
+//The signature of the issuer was checked with verify(), the issuer must still be the account's issuer.
+func upgradeContract(state *blockState, tx *protocol.AccTx, block *protocol.Block, journal *storage.Journal) error {
+	if block.Height < tx.LockHeight {
+		return errors.New(fmt.Sprintf("Upgrade (%x) is locked until height %v.", tx.Hash(), tx.LockHeight))
+	}
+
+	accHash := protocol.SerializeHashContent(tx.PubKey)
+	acc, err := state.getAccount(accHash)
+	if err != nil {
+		return err
+	}
+
+	if len(acc.Contract) == 0 {
+		return errors.New(fmt.Sprintf("Account (%x) has no contract to upgrade.", accHash[0:8]))
+	}
+
+	if tx.Issuer != acc.Issuer {
+		return errors.New(fmt.Sprintf("Upgrade (%x) is not signed by the issuer of account (%x).", tx.Hash(), accHash[0:8]))
+	}
+
+	journal.Upgrades = append(journal.Upgrades, storage.UpgradeJournalEntry{accHash, tx.Hash(), block.Height, acc.Contract, acc.ContractVariables})
+
+	entry := &protocol.ContractHistoryEntry{
+		Account:     accHash,
+		Type:        protocol.CONTRACT_UPGRADE,
+		BlockHeight: block.Height,
+		TxHash:      tx.Hash(),
+		OldCode:     protocol.CodeHash(acc.Contract),
+		NewCode:     protocol.CodeHash(tx.Contract),
+	}
+
+	//Without variables, the account keeps its variables. Contract calls write the variables in place, so neither the
+	//journal nor the tx must share the slice with the account.
+	variables := acc.ContractVariables
+	if len(tx.ContractVariables) > 0 {
+		variables = tx.ContractVariables
+	}
+	acc.Contract = tx.Contract
+	acc.ContractVariables = append([]protocol.ByteArray(nil), variables...)
+
+	if state.speculative {
+		return nil
+	}
+	return storage.WriteContractHistory(entry)
+}
+
 //this method does inititate the state change for aggregated Transactions.
-func aggTxStateChange(txSlice []*protocol.FundsTx, initialSetup bool) (err error) {
+func aggTxStateChange(state *blockState, txSlice []*protocol.FundsTx, initialSetup bool, block *protocol.Block, journal *storage.Journal) (err error) {
 	sort.Sort(ByTxCount(txSlice))
 
-	if err := fundsStateChange(txSlice, initialSetup); err != nil {
+	if err := fundsStateChange(state, txSlice, initialSetup, block, journal); err != nil {
 		return err
 	} else {
 		return nil
@@ -280,7 +389,9 @@ func (a ByTxCount) Len() int           { return len(a) }
 func (a ByTxCount) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a ByTxCount) Less(i, j int) bool { return a[i].TxCnt <= a[j].TxCnt }
 
-func fundsStateChange(txSlice []*protocol.FundsTx, initialSetup bool) (err error) {
+//Contract calls are executed as part of the state change in the given block, their writes to contract variables and
+//their transfers are recorded in the journal.
+func fundsStateChange(state *blockState, txSlice []*protocol.FundsTx, initialSetup bool, block *protocol.Block, journal *storage.Journal) (err error) {
 	for _, tx := range txSlice {
 
 		//If transaction is in closed tx, the state was adjusted already.
@@ -290,7 +401,7 @@ func fundsStateChange(txSlice []*protocol.FundsTx, initialSetup bool) (err error
 
 		var rootAcc *protocol.Account
 		//Check if we have to issue new coins (in case a root account signed the tx)
-		if rootAcc, err = storage.GetRootAccount(tx.From); err != nil {
+		if rootAcc, err = state.getRootAccount(tx.From); err != nil {
 			return err
 		}
 
@@ -305,8 +416,8 @@ func fundsStateChange(txSlice []*protocol.FundsTx, initialSetup bool) (err error
 		}
 
 		var accSender, accReceiver *protocol.Account
-		accSender, err = storage.GetAccount(tx.From)
-		accReceiver, err = storage.GetAccount(tx.To)
+		accSender, err = state.getAccount(tx.From)
+		accReceiver, err = state.getAccount(tx.To)
 
 		//Check transaction counter
 		if !initialSetup && tx.Aggregated == false && tx.TxCnt != accSender.TxCnt {
@@ -333,6 +444,12 @@ func fundsStateChange(txSlice []*protocol.FundsTx, initialSetup bool) (err error
 			err = errors.New("Transaction amount would lead to balance overflow at the receiver account.")
 		}
 
+		var transfers []protocol.Transfer
+		var selfDestructs []protocol.SelfDestruct
+		if err == nil && tx.Data != nil && accReceiver.Contract != nil {
+			transfers, selfDestructs, err = executeContract(state, tx, accReceiver, block, journal)
+		}
+
 		if err != nil {
 			if rootAcc != nil {
 				//Rollback root's credits if error occurs
@@ -347,10 +464,193 @@ func fundsStateChange(txSlice []*protocol.FundsTx, initialSetup bool) (err error
 		accSender.TxCnt += 1
 		accSender.Balance -= tx.Amount
 		accReceiver.Balance += tx.Amount
+
+		//The amount of the tx is credited first, the contract may pass it on.
+		for _, transfer := range transfers {
+			from, _ := state.getAccount(transfer.From)
+			to, _ := state.getAccount(transfer.To)
+			from.Balance -= transfer.Amount
+			to.Balance += transfer.Amount
+			journal.Transfers = append(journal.Transfers, storage.TransferJournalEntry{transfer.From, transfer.To, transfer.Amount})
+		}
+
+		//A self-destructed contract sends the balance it has after the transfers to the beneficiary.
+		for _, selfDestruct := range selfDestructs {
+			if err := destroyContract(state, tx, selfDestruct, block, journal); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
+//Removes the contract and its variables, they are kept in the journal for a rollback.
+func destroyContract(state *blockState, tx *protocol.FundsTx, selfDestruct protocol.SelfDestruct, block *protocol.Block, journal *storage.Journal) error {
+	acc, _ := state.getAccount(selfDestruct.Account)
+	beneficiary, _ := state.getAccount(selfDestruct.Beneficiary)
+
+	entry := &protocol.ContractHistoryEntry{
+		Account:     selfDestruct.Account,
+		Type:        protocol.CONTRACT_SELFDESTRUCT,
+		BlockHeight: block.Height,
+		TxHash:      tx.Hash(),
+		OldCode:     protocol.CodeHash(acc.Contract),
+		NewCode:     protocol.CodeHash(nil),
+		Beneficiary: selfDestruct.Beneficiary,
+		Amount:      acc.Balance,
+	}
+
+	journal.SelfDestructs = append(journal.SelfDestructs, storage.SelfDestructJournalEntry{selfDestruct.Account, selfDestruct.Beneficiary, acc.Balance, tx.Hash(), block.Height, acc.Contract, acc.ContractVariables})
+	beneficiary.Balance += acc.Balance
+	acc.Balance = 0
+	acc.Contract = nil
+	acc.ContractVariables = nil
+
+	if state.speculative {
+		return nil
+	}
+	return storage.WriteContractHistory(entry)
+}
+
+//A contract call which fails when the block is applied. The contract may read the block's timestamp, which is only
+//known once the block is finalized, so a call which succeeded in addFundsTx() can still fail here.
+type contractCallError struct {
+	tx  *protocol.FundsTx
+	err error
+}
+
+func (e *contractCallError) Error() string {
+	return e.err.Error()
+}
+
+//Runs the contract of the receiver like addFundsTx() does when the tx is added to a block. The transfers and
+//self-destructs of the contract are checked, but not applied.
+func executeContract(state *blockState, tx *protocol.FundsTx, accReceiver *protocol.Account, block *protocol.Block, journal *storage.Journal) ([]protocol.Transfer, []protocol.SelfDestruct, error) {
+	context := protocol.NewContext(*accReceiver, *tx)
+	context.SetAccountReader(state.getAccount)
+	context.SetBlock(block.Height, block.Timestamp, block.PrevHash)
+	virtualMachine := vm.NewVM(context)
+	if !virtualMachine.Exec(false) {
+		return nil, nil, &contractCallError{tx, errors.New(fmt.Sprintf("Contract call in tx %x failed: %v", tx.Hash(), virtualMachine.GetErrorMsg()))}
+	}
+
+	if err := checkTransfers(tx, context.GetTransfers(), state.getAccount); err != nil {
+		return nil, nil, &contractCallError{tx, err}
+	}
+
+	if err := checkSelfDestructs(tx, context.GetTransfers(), context.GetSelfDestructs(), state.getAccount); err != nil {
+		return nil, nil, &contractCallError{tx, err}
+	}
+
+	//The changes include those of the contracts called with CALLEXT.
+	for _, change := range context.GetChanges() {
+		index, value := change.GetChange()
+		acc := accReceiver
+		if change.GetAccount() != tx.To {
+			var err error
+			if acc, err = state.getAccount(change.GetAccount()); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		journal.Contract = append(journal.Contract, storage.ContractJournalEntry{change.GetAccount(), index, acc.ContractVariables[index], value})
+		acc.ContractVariables[index] = value
+	}
+
+	if state.speculative {
+		return context.GetTransfers(), context.GetSelfDestructs(), nil
+	}
+
+	receipt := &protocol.Receipt{
+		TxHash:  tx.Hash(),
+		GasUsed: tx.Fee - virtualMachine.GetRemainingFee(),
+		Events:  context.GetEvents(),
+	}
+	if err := storage.WriteReceipt(receipt); err != nil {
+		return nil, nil, err
+	}
+	journal.Receipts = append(journal.Receipts, receipt.TxHash)
+
+	return context.GetTransfers(), context.GetSelfDestructs(), nil
+}
+
+//The VM checks that a contract doesn't transfer more than it has, the receivers' balances must not overflow either.
+func checkTransfers(tx *protocol.FundsTx, transfers []protocol.Transfer, accounts func(address [32]byte) (*protocol.Account, error)) error {
+	credits := map[[32]byte]uint64{tx.To: tx.Amount}
+	for _, transfer := range transfers {
+		acc, err := accounts(transfer.To)
+		if err != nil {
+			return err
+		}
+
+		credits[transfer.To] += transfer.Amount
+		if acc.Balance+credits[transfer.To] > MAX_MONEY {
+			return errors.New(fmt.Sprintf("Transfer of contract (%x) would lead to balance overflow at account (%x).", transfer.From[0:8], transfer.To[0:8]))
+		}
+	}
+
+	return nil
+}
+
+//The balance of a self-destructed contract, including the amount of the tx and the transfers, must not overflow the
+//beneficiary's balance.
+func checkSelfDestructs(tx *protocol.FundsTx, transfers []protocol.Transfer, selfDestructs []protocol.SelfDestruct, accounts func(address [32]byte) (*protocol.Account, error)) error {
+	if len(selfDestructs) == 0 {
+		return nil
+	}
+
+	balances := make(map[[32]byte]uint64)
+	balance := func(address [32]byte) (uint64, error) {
+		if _, exists := balances[address]; !exists {
+			acc, err := accounts(address)
+			if err != nil {
+				return 0, err
+			}
+			balances[address] = acc.Balance
+		}
+		return balances[address], nil
+	}
+
+	credit := func(address [32]byte, amount uint64) error {
+		current, err := balance(address)
+		if err != nil {
+			return err
+		}
+		balances[address] = current + amount
+		return nil
+	}
+
+	if err := credit(tx.To, tx.Amount); err != nil {
+		return err
+	}
+	for _, transfer := range transfers {
+		if err := credit(transfer.To, transfer.Amount); err != nil {
+			return err
+		}
+		if _, err := balance(transfer.From); err != nil {
+			return err
+		}
+		balances[transfer.From] -= transfer.Amount
+	}
+
+	for _, selfDestruct := range selfDestructs {
+		amount, err := balance(selfDestruct.Account)
+		if err != nil {
+			return err
+		}
+		balances[selfDestruct.Account] = 0
+
+		if err := credit(selfDestruct.Beneficiary, amount); err != nil {
+			return err
+		}
+		if balances[selfDestruct.Beneficiary] > MAX_MONEY {
+			return errors.New(fmt.Sprintf("Self-destruct of contract (%x) would lead to balance overflow at account (%x).", selfDestruct.Account[0:8], selfDestruct.Beneficiary[0:8]))
+		}
+	}
+
+	return nil
+}
+
 //We accept config slices with unknown id, but don't act on the payload. This is in case we have not updated to a new
 //software with corresponding code to act on the configTx id/payload
 func configStateChange(configTxSlice []*protocol.ConfigTx, blockHash [32]byte) {
@@ -362,6 +662,13 @@ func configStateChange(configTxSlice []*protocol.ConfigTx, blockHash [32]byte) {
 		return
 	}
 
+	//A new staking minimum removes validators anywhere in the state.
+	for _, tx := range configTxSlice {
+		if tx.Id == protocol.STAKING_MINIMUM_ID {
+			resetStateChanges()
+		}
+	}
+
 	//Only add a new parameter struct if a relevant system parameter changed
 	if CheckAndChangeParameters(&newParameters, &configTxSlice) {
 		newParameters.BlockHash = blockHash
@@ -371,10 +678,10 @@ func configStateChange(configTxSlice []*protocol.ConfigTx, blockHash [32]byte) {
 	}
 }
 
-func stakeStateChange(txSlice []*protocol.StakeTx, height uint32, initialSetup bool) (err error) {
+func stakeStateChange(state *blockState, txSlice []*protocol.StakeTx, height uint32, initialSetup bool) (err error) {
 	for _, tx := range txSlice {
 		var accSender *protocol.Account
-		accSender, err = storage.GetAccount(tx.Account)
+		accSender, err = state.getAccount(tx.Account)
 
 		//Check staking state
 		if tx.IsStaking == accSender.IsStaking {
@@ -423,14 +730,14 @@ func getFundsTxFromAggTx(AggregatedTxSlice [][32]byte)(fundsTxSlice []*protocol.
 	return fundsTxSlice
 }
 
-func collectTxFees(accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsTx, configTxSlice []*protocol.ConfigTx, stakeTxSlice []*protocol.StakeTx, aggTxSlice []*protocol.AggTx, minerHash [32]byte, initialSetup bool) (err error) {
+func collectTxFees(state *blockState, accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsTx, configTxSlice []*protocol.ConfigTx, stakeTxSlice []*protocol.StakeTx, aggTxSlice []*protocol.AggTx, minerHash [32]byte, initialSetup bool) (err error) {
 	var tmpAccTx []*protocol.AccTx
 	var tmpFundsTx []*protocol.FundsTx
 	var tmpConfigTx []*protocol.ConfigTx
 	var tmpStakeTx []*protocol.StakeTx
 
 	//if initialSetup { //TODO DELETE THIS
-		minerAcc, err := storage.GetAccount(minerHash)
+		minerAcc, err := state.getAccount(minerHash)
 		if err != nil {
 			return err
 		}
@@ -449,7 +756,7 @@ func collectTxFees(accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsT
 
 			if err != nil {
 				//Rollback of all perviously transferred transaction fees to the protocol's account
-				collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerHash)
+				collectTxFeesRollback(state, tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerHash)
 				return err
 			}
 
@@ -465,11 +772,11 @@ func collectTxFees(accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsT
 				err = errors.New("Fee amount would lead to balance overflow at the miner account.")
 			}
 
-			senderAcc, err = storage.GetAccount(tx.From)
+			senderAcc, err = state.getAccount(tx.From)
 
 			if err != nil {
 				//Rollback of all perviously transferred transaction fees to the protocol's account
-				collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerHash)
+				collectTxFeesRollback(state, tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerHash)
 				return err
 			}
 
@@ -485,7 +792,7 @@ func collectTxFees(accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsT
 
 			if err != nil {
 				//Rollback of all perviously transferred transaction fees to the protocol's account
-				collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerHash)
+				collectTxFeesRollback(state, tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerHash)
 				return err
 			}
 
@@ -499,11 +806,11 @@ func collectTxFees(accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsT
 				err = errors.New("Fee amount would lead to balance overflow at the miner account.")
 			}
 
-			senderAcc, err = storage.GetAccount(tx.Account)
+			senderAcc, err = state.getAccount(tx.Account)
 
 			if err != nil {
 				//Rollback of all perviously transferred transaction fees to the protocol's account
-				collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerHash)
+				collectTxFeesRollback(state, tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerHash)
 				return err
 			}
 
@@ -514,10 +821,10 @@ func collectTxFees(accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsT
 	return nil
 }
 
-func collectBlockReward(reward uint64, minerHash [32]byte, initialSetup bool) (err error) {
+func collectBlockReward(state *blockState, reward uint64, minerHash [32]byte, initialSetup bool) (err error) {
 	//if initialSetup {
 		var miner *protocol.Account
-		miner, err = storage.GetAccount(minerHash)
+		miner, err = state.getAccount(minerHash)
 
 		if !initialSetup && miner.Balance+reward > MAX_MONEY {
 			err = errors.New("Block reward would lead to balance overflow at the miner account.")
@@ -531,12 +838,12 @@ func collectBlockReward(reward uint64, minerHash [32]byte, initialSetup bool) (e
 	return nil
 }
 
-func collectSlashReward(reward uint64, block *protocol.Block) (err error) {
+func collectSlashReward(state *blockState, reward uint64, block *protocol.Block) (err error) {
 	//Check if proof is provided. If proof was incorrect, prevalidation would already have failed.
 	if block.SlashedAddress != [32]byte{} || block.ConflictingBlockHash1 != [32]byte{} || block.ConflictingBlockHash2 != [32]byte{} || block.ConflictingBlockHashWithoutTx1 != [32]byte{} || block.ConflictingBlockHashWithoutTx2 != [32]byte{} {
 		var minerAcc, slashedAcc *protocol.Account
-		minerAcc, err = storage.GetAccount(block.Beneficiary)
-		slashedAcc, err = storage.GetAccount(block.SlashedAddress)
+		minerAcc, err = state.getAccount(block.Beneficiary)
+		slashedAcc, err = state.getAccount(block.SlashedAddress)
 
 		if minerAcc.Balance+reward > MAX_MONEY {
 			err = errors.New("Slash reward would lead to balance overflow at the miner account.")
@@ -558,8 +865,8 @@ func collectSlashReward(reward uint64, block *protocol.Block) (err error) {
 }
 
 //No rollback method exists
-func updateStakingHeight(block *protocol.Block) error {
-	acc, err := storage.GetAccount(block.Beneficiary)
+func updateStakingHeight(state *blockState, block *protocol.Block) error {
+	acc, err := state.getAccount(block.Beneficiary)
 	if err != nil {
 		return err
 	}
@@ -6,42 +6,42 @@ import (
 	"sort"
 )
 
-func accStateChangeRollback(txSlice []*protocol.AccTx) {
+func accStateChangeRollback(state *blockState, txSlice []*protocol.AccTx) {
 	for _, tx := range txSlice {
 		if tx.Header == 0 || tx.Header == 1 || tx.Header == 2 {
 			accHash := protocol.SerializeHashContent(tx.PubKey)
 
-			acc, err := storage.GetAccount(accHash)
+			acc, err := state.getAccount(accHash)
 			if err != nil {
 				logger.Fatal("CRITICAL: An account that should have been saved does not exist.")
 			}
 
-			delete(storage.State, accHash)
+			state.deleteAccount(accHash)
 
 			switch tx.Header {
 			case 1:
-				delete(storage.RootKeys, accHash)
+				state.deleteRootKey(accHash)
 			case 2:
-				storage.RootKeys[accHash] = acc
+				state.setRootKey(accHash, acc)
 			}
 		}
 	}
 }
 
-func fundsStateChangeRollback(txSlice []*protocol.FundsTx) {
+func fundsStateChangeRollback(state *blockState, txSlice []*protocol.FundsTx) {
 	//Rollback in reverse order than original state change
 	for cnt := len(txSlice) - 1; cnt >= 0; cnt-- {
 		tx := txSlice[cnt]
 
-		accSender, _ := storage.GetAccount(tx.From)
-		accReceiver, _ := storage.GetAccount(tx.To)
+		accSender, _ := state.getAccount(tx.From)
+		accReceiver, _ := state.getAccount(tx.To)
 
 		accSender.TxCnt -= 1
 		accSender.Balance += tx.Amount
 		accReceiver.Balance -= tx.Amount
 
 		//If new coins were issued, revert
-		if rootAcc, _ := storage.GetRootAccount(tx.From); rootAcc != nil {
+		if rootAcc, _ := state.getRootAccount(tx.From); rootAcc != nil {
 			rootAcc.Balance -= tx.Amount
 			rootAcc.Balance -= tx.Fee
 		}
@@ -111,7 +111,12 @@ func (a ByHash) Len() int           { return len(a) }
 func (a ByHash) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a ByHash) Less(i, j int) bool { return string(a[i][0:32]) < string(a[j][0:32]) }
 
-func aggregatedStateRollback(txSlice []*protocol.AggTx, blockHash [32]byte, minerHash [32]byte) {
+func aggregatedStateRollback(state *blockState, txSlice []*protocol.AggTx, blockHash [32]byte, minerHash [32]byte) {
+	//The txs of a speculative run are not closed and the historic blocks must not be touched, the copy is discarded.
+	if state.speculative {
+		return
+	}
+
 	//Rollback in reverse order than original state change
 	var fundsTxSlice []*protocol.FundsTx
 
@@ -138,8 +143,8 @@ func aggregatedStateRollback(txSlice []*protocol.AggTx, blockHash [32]byte, mine
 
 		//do normal rollback for fundsTx And Fees
 		sort.Sort(ByTxCount(fundsTxSlice))
-		fundsStateChangeRollback(fundsTxSlice)
-		collectTxFeesRollback(nil, fundsTxSlice, nil, nil, minerHash)
+		fundsStateChangeRollback(state, fundsTxSlice)
+		collectTxFeesRollback(state, nil, fundsTxSlice, nil, nil, minerHash)
 		fundsTxSlice = fundsTxSlice[:0]
 	}
 }
@@ -162,19 +167,19 @@ func configStateChangeRollback(txSlice []*protocol.ConfigTx, blockHash [32]byte)
 	logger.Printf("Config parameters rolled back. New configuration: %v", *activeParameters)
 }
 
-func stakeStateChangeRollback(txSlice []*protocol.StakeTx) {
+func stakeStateChangeRollback(state *blockState, txSlice []*protocol.StakeTx) {
 	//Rollback in reverse order than original state change
 	for cnt := len(txSlice) - 1; cnt >= 0; cnt-- {
 		tx := txSlice[cnt]
 
-		accSender, _ := storage.GetAccount(tx.Account)
+		accSender, _ := state.getAccount(tx.Account)
 		//Rolling back stakingBlockHeight not needed
 		accSender.IsStaking = !accSender.IsStaking
 	}
 }
 
-func collectTxFeesRollback(accTx []*protocol.AccTx, fundsTx []*protocol.FundsTx, configTx []*protocol.ConfigTx, stakeTx []*protocol.StakeTx, minerHash [32]byte) {
-	minerAcc, _ := storage.GetAccount(minerHash)
+func collectTxFeesRollback(state *blockState, accTx []*protocol.AccTx, fundsTx []*protocol.FundsTx, configTx []*protocol.ConfigTx, stakeTx []*protocol.StakeTx, minerHash [32]byte) {
+	minerAcc, _ := state.getAccount(minerHash)
 
 	//Subtract fees from sender (check if that is allowed has already been done in the block validation)
 	for _, tx := range accTx {
@@ -185,7 +190,7 @@ func collectTxFeesRollback(accTx []*protocol.AccTx, fundsTx []*protocol.FundsTx,
 	for _, tx := range fundsTx {
 		minerAcc.Balance -= tx.Fee
 
-		senderAcc, _ := storage.GetAccount(tx.From)
+		senderAcc, _ := state.getAccount(tx.From)
 		senderAcc.Balance += tx.Fee
 	}
 
@@ -197,20 +202,20 @@ func collectTxFeesRollback(accTx []*protocol.AccTx, fundsTx []*protocol.FundsTx,
 	for _, tx := range stakeTx {
 		minerAcc.Balance -= tx.Fee
 
-		senderAcc, _ := storage.GetAccount(tx.Account)
+		senderAcc, _ := state.getAccount(tx.Account)
 		senderAcc.Balance += tx.Fee
 	}
 }
 
-func collectBlockRewardRollback(reward uint64, minerHash [32]byte) {
-	minerAcc, _ := storage.GetAccount(minerHash)
+func collectBlockRewardRollback(state *blockState, reward uint64, minerHash [32]byte) {
+	minerAcc, _ := state.getAccount(minerHash)
 	minerAcc.Balance -= reward
 }
 
-func collectSlashRewardRollback(reward uint64, block *protocol.Block) {
+func collectSlashRewardRollback(state *blockState, reward uint64, block *protocol.Block) {
 	if block.SlashedAddress != [32]byte{} || block.ConflictingBlockHash1 != [32]byte{} || block.ConflictingBlockHash2 != [32]byte{} || block.ConflictingBlockHashWithoutTx1 != [32]byte{} || block.ConflictingBlockHashWithoutTx2 != [32]byte{} {
-		minerAcc, _ := storage.GetAccount(block.Beneficiary)
-		slashedAcc, _ := storage.GetAccount(block.SlashedAddress)
+		minerAcc, _ := state.getAccount(block.Beneficiary)
+		slashedAcc, _ := state.getAccount(block.SlashedAddress)
 
 		minerAcc.Balance -= reward
 		slashedAcc.Balance += activeParameters.Staking_minimum
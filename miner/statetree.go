@@ -0,0 +1,322 @@
+package miner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//The accounts and root keys a block is applied to. Blocks are validated on the global state, the state root of a block
+//which is being mined is computed on a speculative state, such that nobody sees the state of a block which may never be
+//validated. A speculative state only holds the accounts the block accesses, they are copied from the global state on
+//first access, removed accounts are nil.
+type blockState struct {
+	accounts    map[[32]byte]*protocol.Account
+	rootKeys    map[[32]byte]*protocol.Account
+	speculative bool              //Nothing is written to the database, e.g., receipts and the contract history
+	changed     map[[32]byte]bool //Accounts which have been accessed, created or removed, i.e., which may have changed
+}
+
+func globalState() *blockState {
+	return &blockState{accounts: storage.State, rootKeys: storage.RootKeys, changed: make(map[[32]byte]bool)}
+}
+
+func speculativeState() *blockState {
+	return &blockState{
+		accounts:    make(map[[32]byte]*protocol.Account),
+		rootKeys:    make(map[[32]byte]*protocol.Account),
+		speculative: true,
+		changed:     make(map[[32]byte]bool),
+	}
+}
+
+//Accounts are changed through the returned pointer, so every account which is handed out counts as changed.
+func (state *blockState) getAccount(hash [32]byte) (*protocol.Account, error) {
+	acc, accessed := state.accounts[hash]
+	if !accessed && state.speculative && storage.State[hash] != nil {
+		acc = storage.CopyAccount(storage.State[hash])
+		state.accounts[hash] = acc
+		if storage.IsRootKey(hash) {
+			state.rootKeys[hash] = acc
+		}
+	}
+
+	if acc != nil {
+		state.changed[hash] = true
+		return acc, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Acc (%x) not in the state.", hash[0:8]))
+}
+
+func (state *blockState) getRootAccount(hash [32]byte) (*protocol.Account, error) {
+	if state.isRootKey(hash) {
+		return state.getAccount(hash)
+	}
+	return nil, nil
+}
+
+func (state *blockState) isRootKey(hash [32]byte) bool {
+	if _, accessed := state.accounts[hash]; state.speculative && !accessed {
+		return storage.IsRootKey(hash)
+	}
+	return state.rootKeys[hash] != nil
+}
+
+func (state *blockState) setAccount(hash [32]byte, acc *protocol.Account) {
+	state.accounts[hash] = acc
+	state.changed[hash] = true
+}
+
+func (state *blockState) deleteAccount(hash [32]byte) {
+	if state.speculative {
+		state.accounts[hash] = nil
+	} else {
+		delete(state.accounts, hash)
+	}
+	state.changed[hash] = true
+}
+
+func (state *blockState) setRootKey(hash [32]byte, acc *protocol.Account) {
+	state.rootKeys[hash] = acc
+	state.changed[hash] = true
+}
+
+func (state *blockState) deleteRootKey(hash [32]byte) {
+	if state.speculative {
+		state.rootKeys[hash] = nil
+	} else {
+		delete(state.rootKeys, hash)
+	}
+	state.changed[hash] = true
+}
+
+func (state *blockState) changedKeys() (keys [][32]byte) {
+	for hash := range state.changed {
+		keys = append(keys, hash)
+	}
+	return keys
+}
+
+//Root of the state tree after the changes of the block state.
+func (state *blockState) stateRoot() [32]byte {
+	return globalStateTree().Update(state.accounts, state.changedKeys()).Root()
+}
+
+//Adds the accounts which changed in the global state to the state tree and to the changes which have not been
+//persisted yet.
+func (state *blockState) recordChanges() {
+	if stateTree != nil {
+		stateTree = stateTree.Update(storage.State, state.changedKeys())
+	}
+	for hash := range state.changed {
+		if changedAccounts != nil {
+			changedAccounts[hash] = true
+		}
+	}
+}
+
+//Called whenever the global state is changed without the changes being recorded, the state tree is built from all
+//accounts and the next persistState() writes the whole state.
+func resetStateChanges() {
+	stateTree = nil
+	changedAccounts = nil
+}
+
+//The tree over the global state, it is updated with the accounts the blocks change. Nil if it has to be built from all
+//accounts, e.g., because the state was replaced.
+var stateTree *protocol.StateTree
+
+func globalStateTree() *protocol.StateTree {
+	if stateTree == nil {
+		stateTree = protocol.BuildStateTree(storage.State)
+	}
+	return stateTree
+}
+
+//Root of the state tree over the current state.
+func stateRoot() [32]byte {
+	return globalStateTree().Root()
+}
+
+//A block's state root commits to the state after the block. For a block which is being mined, this state is
+//computed by applying the block to a copy of the current state.
+func computeStateRoot(block *protocol.Block) (root [32]byte, err error) {
+	blockValidation.Lock()
+	defer blockValidation.Unlock()
+
+	if lastBlock != nil && block.PrevHash != lastBlock.Hash {
+		return root, errors.New("Last block changed while mining, the state does not correspond to the block's predecessor.")
+	}
+
+	accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, aggregatedFundsTxSlice, err := preValidate(block, false)
+	if err != nil {
+		return root, err
+	}
+
+	//The journal is only needed if the validation fails, the speculative state is discarded anyway.
+	state := speculativeState()
+	if err := validateState(state, blockData{accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, aggregatedFundsTxSlice, block}, false, new(storage.Journal)); err != nil {
+		return root, err
+	}
+
+	return state.stateRoot(), nil
+}
+
+//Applies the state changes of a block and checks that they lead to the state the block commits to. The staking
+//values and contract variables the block overwrites are journaled, such that a rollback restores the exact previous
+//state. The journal also lists the receipts the block writes, they are removed on rollback, as well as the contracts the
+//block upgrades or destroys.
+func validateStateRoot(data blockData, initialSetup bool) error {
+	state := globalState()
+	defer state.recordChanges()
+	journal := newStakingJournal(data)
+
+	if err := validateState(state, data, initialSetup, journal); err != nil {
+		return err
+	}
+
+	if err := storage.WriteJournal(data.block.Hash, journal); err != nil {
+		selfDestructJournalRollback(state, journal.SelfDestructs)
+		contractJournalRollback(state, journal.Contract)
+		receiptJournalRollback(journal.Receipts)
+		transferJournalRollback(state, journal.Transfers)
+		upgradeJournalRollback(state, journal.Upgrades)
+		validateStateRollback(data)
+		return err
+	}
+
+	//Blocks from before the state root was introduced carry a zero root and do not commit to the state.
+	if data.block.StateRoot == [32]byte{} {
+		return nil
+	}
+
+	if root := state.stateRoot(); root != data.block.StateRoot {
+		validateStateRollback(data)
+		storage.DeleteJournal(data.block.Hash)
+		return errors.New(fmt.Sprintf("State root is incorrect: %x (block) vs. %x (state).", data.block.StateRoot[0:8], root[0:8]))
+	}
+
+	return nil
+}
+
+//Records the values which stakeStateChange() and updateStakingHeight() overwrite.
+func newStakingJournal(data blockData) *storage.Journal {
+	journal := new(storage.Journal)
+	journaled := make(map[[32]byte]bool)
+
+	accHashes := [][32]byte{data.block.Beneficiary}
+	for _, tx := range data.stakeTxSlice {
+		accHashes = append(accHashes, tx.Account)
+	}
+
+	for _, accHash := range accHashes {
+		//Accounts which are created by the block are removed on rollback anyway.
+		acc, err := storage.GetAccount(accHash)
+		if err != nil || journaled[accHash] {
+			continue
+		}
+
+		journal.Staking = append(journal.Staking, storage.StakingJournalEntry{accHash, acc.CommitmentKey, acc.StakingBlockHeight})
+		journaled[accHash] = true
+	}
+
+	return journal
+}
+
+func stakingJournalRollback(state *blockState, journal *storage.Journal) {
+	if journal == nil {
+		return
+	}
+
+	for _, entry := range journal.Staking {
+		if acc, err := state.getAccount(entry.Account); err == nil {
+			acc.CommitmentKey = entry.CommitmentKey
+			acc.StakingBlockHeight = entry.StakingBlockHeight
+		}
+	}
+}
+
+//Restores the contract variables in reverse order of the writes.
+func contractJournalRollback(state *blockState, entries []storage.ContractJournalEntry) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+
+		acc, err := state.getAccount(entry.Account)
+		if err != nil || entry.Index >= len(acc.ContractVariables) {
+			logger.Printf("CRITICAL: Contract variable %v of account (%x) can't be restored.\n", entry.Index, entry.Account[0:8])
+			continue
+		}
+		if !bytes.Equal(acc.ContractVariables[entry.Index], entry.NewValue) {
+			logger.Printf("CRITICAL: Contract variable %v of account (%x) was changed outside of the journal.\n", entry.Index, entry.Account[0:8])
+		}
+
+		acc.ContractVariables[entry.Index] = entry.OldValue
+	}
+}
+
+//Returns the coins contracts transferred, in reverse order of the transfers.
+func transferJournalRollback(state *blockState, entries []storage.TransferJournalEntry) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+
+		from, errFrom := state.getAccount(entry.From)
+		to, errTo := state.getAccount(entry.To)
+		if errFrom != nil || errTo != nil {
+			logger.Printf("CRITICAL: Transfer of %v from account (%x) to (%x) can't be reverted.\n", entry.Amount, entry.From[0:8], entry.To[0:8])
+			continue
+		}
+
+		from.Balance += entry.Amount
+		to.Balance -= entry.Amount
+	}
+}
+
+//Removes the receipts of the contract executions of a block.
+func receiptJournalRollback(txHashes [][32]byte) {
+	for _, txHash := range txHashes {
+		storage.DeleteReceipt(txHash)
+	}
+}
+
+//Restores the contracts and variables from before the upgrades, in reverse order of the upgrades.
+func upgradeJournalRollback(state *blockState, entries []storage.UpgradeJournalEntry) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+
+		acc, err := state.getAccount(entry.Account)
+		if err != nil {
+			logger.Printf("CRITICAL: Upgrade of account (%x) can't be reverted.\n", entry.Account[0:8])
+			continue
+		}
+
+		acc.Contract = entry.Contract
+		acc.ContractVariables = entry.ContractVariables
+		if !state.speculative {
+			storage.DeleteContractHistory(entry.Account, entry.BlockHeight, entry.TxHash)
+		}
+	}
+}
+
+//Restores the self-destructed contracts and takes their balance back from the beneficiaries, in reverse order.
+func selfDestructJournalRollback(state *blockState, entries []storage.SelfDestructJournalEntry) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+
+		acc, errAcc := state.getAccount(entry.Account)
+		beneficiary, errBeneficiary := state.getAccount(entry.Beneficiary)
+		if errAcc != nil || errBeneficiary != nil {
+			logger.Printf("CRITICAL: Self-destruct of account (%x) can't be reverted.\n", entry.Account[0:8])
+			continue
+		}
+
+		beneficiary.Balance -= entry.Amount
+		acc.Balance += entry.Amount
+		acc.Contract = entry.Contract
+		acc.ContractVariables = entry.ContractVariables
+		if !state.speculative {
+			storage.DeleteContractHistory(entry.Account, entry.BlockHeight, entry.TxHash)
+		}
+	}
+}
@@ -0,0 +1,653 @@
+package miner
+
+import (
+	"crypto/rand"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"github.com/bazo-blockchain/bazo-miner/vm"
+	"golang.org/x/crypto/sha3"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStateRootValidationAndRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	rootBefore := stateRoot()
+	validatorAcc, _ := storage.GetAccount(protocol.SerializeHashContent(validatorAccAddress))
+	stakingHeightBefore := validatorAcc.StakingBlockHeight
+
+	b := newBlock(genesisBlock.Hash, genesisBlock.HashWithoutTx, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	if err := finalizeBlock(b); err != nil {
+		t.Fatalf("Could not finalize block: %v\n", err)
+	}
+
+	//Computing the state root must not change the state.
+	if stateRoot() != rootBefore {
+		t.Errorf("State changed while finalizing the block\n")
+	}
+	if b.StateRoot == rootBefore {
+		t.Errorf("State root does not include the block reward\n")
+	}
+
+	if err := validate(b, false); err != nil {
+		t.Fatalf("Could not validate block: %v\n", err)
+	}
+	if stateRoot() != b.StateRoot {
+		t.Errorf("State after validation does not match the block's state root: %x vs. %x\n", stateRoot(), b.StateRoot)
+	}
+	if validatorAcc.StakingBlockHeight != b.Height {
+		t.Errorf("Staking block height was not updated: %v vs. %v\n", validatorAcc.StakingBlockHeight, b.Height)
+	}
+
+	if err := rollback(b); err != nil {
+		t.Fatalf("Could not roll back block: %v\n", err)
+	}
+	if validatorAcc.StakingBlockHeight != stakingHeightBefore {
+		t.Errorf("Staking block height was not rolled back: %v vs. %v\n", validatorAcc.StakingBlockHeight, stakingHeightBefore)
+	}
+	if stateRoot() != rootBefore {
+		t.Errorf("State root after rollback does not match the state root before the block\n")
+	}
+	if storage.ReadJournal(b.Hash) != nil {
+		t.Errorf("Journal of the rolled back block was not deleted\n")
+	}
+}
+
+func TestStateRootMismatch(t *testing.T) {
+	cleanAndPrepare()
+
+	rootBefore := stateRoot()
+
+	b := newBlock(genesisBlock.Hash, genesisBlock.HashWithoutTx, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	if err := finalizeBlock(b); err != nil {
+		t.Fatalf("Could not finalize block: %v\n", err)
+	}
+	b.StateRoot[0]++
+
+	if err := validate(b, false); err == nil {
+		t.Errorf("Block with an incorrect state root was validated\n")
+	}
+	if stateRoot() != rootBefore {
+		t.Errorf("State was not rolled back after the state root check failed\n")
+	}
+}
+
+func TestStateRootLegacyBlock(t *testing.T) {
+	cleanAndPrepare()
+
+	b := newBlock(genesisBlock.Hash, genesisBlock.HashWithoutTx, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	if err := finalizeBlock(b); err != nil {
+		t.Fatalf("Could not finalize block: %v\n", err)
+	}
+	stateRootAfter := b.StateRoot
+
+	//Blocks from before the state root was introduced carry a zero root.
+	b.StateRoot = [32]byte{}
+	partialHash := b.HashBlock()
+	partialHashWithoutMerkleRoot := b.HashBlockWithoutMerkleRoot()
+	b.Hash = sha3.Sum256(append(b.Nonce[:], partialHash[:]...))
+	b.HashWithoutTx = sha3.Sum256(append(b.Nonce[:], partialHashWithoutMerkleRoot[:]...))
+
+	if err := validate(b, false); err != nil {
+		t.Fatalf("Could not validate block without a state root: %v\n", err)
+	}
+	if stateRoot() != stateRootAfter {
+		t.Errorf("State after validation does not match the state root the block would have carried\n")
+	}
+}
+
+//The contract adds the call data to its variable.
+func addCounterContract() [32]byte {
+	contract := []byte{
+		35,    // CALLDATA
+		29, 0, // SLOAD
+		4,     // ADD
+		27, 0, // SSTORE
+		50, // HALT
+	}
+
+	return addContract(contract, []protocol.ByteArray{{0, 2}})
+}
+
+func addContract(contract []byte, variables []protocol.ByteArray) [32]byte {
+	var address [64]byte
+	rand.Read(address[:])
+	acc := protocol.NewAccount(address, [32]byte{}, 0, false, [crypto.COMM_KEY_LENGTH]byte{}, contract, variables)
+	hash := protocol.SerializeHashContent(address)
+	storage.State[hash] = &acc
+	resetStateChanges()
+
+	return hash
+}
+
+func newContractCallBlock(t *testing.T, prevBlock *protocol.Block, contractHash [32]byte, amount byte) *protocol.Block {
+	b := newBlock(prevBlock.Hash, prevBlock.HashWithoutTx, [crypto.COMM_PROOF_LENGTH]byte{}, prevBlock.Height+1)
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	tx, _ := protocol.ConstrFundsTx(0x01, 1, 100000, accA.TxCnt, accAHash, contractHash, PrivKeyAccA, PrivKeyMultiSig, []byte{1, 0, amount})
+	if err := addTx(b, tx); err != nil {
+		t.Fatalf("Could not add contract call: %v\n", err)
+	}
+	addFundsTxFinal(b, tx)
+	storage.WriteOpenTx(tx)
+
+	if err := finalizeBlock(b); err != nil {
+		t.Fatalf("Could not finalize block: %v\n", err)
+	}
+	if err := validate(b, false); err != nil {
+		t.Fatalf("Could not validate block: %v\n", err)
+	}
+
+	return b
+}
+
+func TestContractJournalRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	contractHash := addCounterContract()
+	contract, _ := storage.GetAccount(contractHash)
+	rootBefore := stateRoot()
+
+	b := newContractCallBlock(t, genesisBlock, contractHash, 15)
+	if expected := []protocol.ByteArray{{0, 17}}; !reflect.DeepEqual(contract.ContractVariables, expected) {
+		t.Errorf("Expected contract variables %v after the call but were %v\n", expected, contract.ContractVariables)
+	}
+	if journal := storage.ReadJournal(b.Hash); journal == nil || len(journal.Contract) != 1 {
+		t.Fatalf("Contract write was not journaled: %v\n", journal)
+	}
+
+	if err := rollback(b); err != nil {
+		t.Fatalf("Could not roll back block: %v\n", err)
+	}
+	if expected := []protocol.ByteArray{{0, 2}}; !reflect.DeepEqual(contract.ContractVariables, expected) {
+		t.Errorf("Expected contract variables %v after the rollback but were %v\n", expected, contract.ContractVariables)
+	}
+	if stateRoot() != rootBefore {
+		t.Errorf("State root after rollback does not match the state root before the block\n")
+	}
+}
+
+func TestContractCallExtRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	//The callee adds the argument to its variable, the caller counts the calls.
+	calleeHash := addContract([]byte{
+		35,    // CALLDATA
+		3,     // POP (function hash)
+		29, 0, // SLOAD
+		4,     // ADD
+		27, 0, // SSTORE
+		50, // HALT
+	}, []protocol.ByteArray{{0, 2}})
+	caller := append([]byte{
+		0, 1, 0, 1, // PUSH
+		29, 0, // SLOAD
+		4,     // ADD
+		27, 0, // SSTORE
+		35, // CALLDATA
+		23, // CALLEXT
+	}, calleeHash[:]...)
+	caller = append(caller, 0, 0, 0, 0, 1, 50)
+	callerHash := addContract(caller, []protocol.ByteArray{{0, 0}})
+
+	callee, _ := storage.GetAccount(calleeHash)
+	callerAcc, _ := storage.GetAccount(callerHash)
+	rootBefore := stateRoot()
+
+	b := newContractCallBlock(t, genesisBlock, callerHash, 15)
+	if expected := []protocol.ByteArray{{0, 17}}; !reflect.DeepEqual(callee.ContractVariables, expected) {
+		t.Errorf("Expected callee variables %v after the call but were %v\n", expected, callee.ContractVariables)
+	}
+	if expected := []protocol.ByteArray{{0, 1}}; !reflect.DeepEqual(callerAcc.ContractVariables, expected) {
+		t.Errorf("Expected caller variables %v after the call but were %v\n", expected, callerAcc.ContractVariables)
+	}
+	if journal := storage.ReadJournal(b.Hash); journal == nil || len(journal.Contract) != 2 || journal.Contract[1].Account != calleeHash {
+		t.Fatalf("Contract writes of caller and callee were not journaled: %v\n", journal)
+	}
+
+	if err := rollback(b); err != nil {
+		t.Fatalf("Could not roll back block: %v\n", err)
+	}
+	if expected := []protocol.ByteArray{{0, 2}}; !reflect.DeepEqual(callee.ContractVariables, expected) {
+		t.Errorf("Expected callee variables %v after the rollback but were %v\n", expected, callee.ContractVariables)
+	}
+	if expected := []protocol.ByteArray{{0, 0}}; !reflect.DeepEqual(callerAcc.ContractVariables, expected) {
+		t.Errorf("Expected caller variables %v after the rollback but were %v\n", expected, callerAcc.ContractVariables)
+	}
+	if stateRoot() != rootBefore {
+		t.Errorf("State root after rollback does not match the state root before the block\n")
+	}
+}
+
+func TestContractReceiptRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	//Emits the call data with the topic 't'.
+	contractHash := addContract([]byte{
+		0, 0, 't', // PUSH
+		35, // CALLDATA
+		52, // LOG1
+		50, // HALT
+	}, nil)
+
+	b := newContractCallBlock(t, genesisBlock, contractHash, 15)
+	txHash := b.FundsTxData[0]
+
+	receipt := storage.ReadReceipt(txHash)
+	if receipt == nil || receipt.GasUsed == 0 {
+		t.Fatalf("Expected a receipt of the successful contract call but got %v\n", receipt)
+	}
+	expected := []protocol.Event{{Account: contractHash, Topics: []protocol.ByteArray{{'t'}}, Data: protocol.ByteArray{0, 15}}}
+	if !reflect.DeepEqual(receipt.Events, expected) {
+		t.Errorf("Expected events %v but were %v\n", expected, receipt.Events)
+	}
+	if receipts := storage.ReadReceiptsByTopic([]byte{'t'}); len(receipts) != 1 || receipts[0].TxHash != txHash {
+		t.Errorf("Receipt was not found by its topic: %v\n", receipts)
+	}
+	if journal := storage.ReadJournal(b.Hash); journal == nil || !reflect.DeepEqual(journal.Receipts, [][32]byte{txHash}) {
+		t.Fatalf("Receipt was not journaled: %v\n", journal)
+	}
+
+	if err := rollback(b); err != nil {
+		t.Fatalf("Could not roll back block: %v\n", err)
+	}
+	if receipt := storage.ReadReceipt(txHash); receipt != nil {
+		t.Errorf("Receipt was not removed on rollback: %v\n", receipt)
+	}
+}
+
+func TestContractBlockContext(t *testing.T) {
+	cleanAndPrepare()
+
+	//Stores the height and the previous block hash, the call data is ignored.
+	contractHash := addContract([]byte{
+		55,    // HEIGHT
+		27, 0, // SSTORE
+		57,    // PREVHASH
+		27, 1, // SSTORE
+		50, // HALT
+	}, []protocol.ByteArray{{}, {}})
+	contract, _ := storage.GetAccount(contractHash)
+
+	b := newContractCallBlock(t, genesisBlock, contractHash, 15)
+	expected := []protocol.ByteArray{{0, byte(b.Height)}, genesisBlock.Hash[:]}
+	if !reflect.DeepEqual(contract.ContractVariables, expected) {
+		t.Errorf("Expected contract variables %v but were %v\n", expected, contract.ContractVariables)
+	}
+}
+
+//The block's timestamp is only known once the block is finalized, a call which fails with it must not end up in a block.
+func TestContractTimestampFailure(t *testing.T) {
+	cleanAndPrepare()
+
+	//Fails if the timestamp is after the deadline in the contract variable.
+	contractHash := addContract([]byte{
+		56,       // TIMESTAMP
+		29, 0,    // SLOAD
+		13,       // GT
+		20, 0, 8, // JMPIF
+		50, // HALT
+		49, // ERRHALT
+	}, []protocol.ByteArray{{0}})
+	contract, _ := storage.GetAccount(contractHash)
+
+	//Finding the proof of stake takes at least a second, the timestamp of the block is after the deadline.
+	contract.ContractVariables[0] = vm.SignedByteArrayConversion(*big.NewInt(time.Now().Unix()))
+	resetStateChanges()
+
+	b := newBlock(genesisBlock.Hash, genesisBlock.HashWithoutTx, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	tx, _ := protocol.ConstrFundsTx(0x01, 1, 100000, accA.TxCnt, accAHash, contractHash, PrivKeyAccA, PrivKeyMultiSig, []byte{0})
+	storage.WriteOpenTx(tx)
+	if err := addTx(b, tx); err != nil {
+		t.Fatalf("Contract call failed before the deadline: %v\n", err)
+	}
+	addFundsTxFinal(b, tx)
+
+	err := finalizeBlock(b)
+	if callErr, ok := err.(*contractCallError); !ok || callErr.tx != tx {
+		t.Fatalf("Expected the contract call to fail with the block's timestamp but got %v\n", err)
+	}
+	if storage.ReadOpenTx(tx.Hash()) != nil || storage.ReadINVALIDOpenTx(tx.Hash()) == nil {
+		t.Errorf("Failed contract call was not removed from the mempool\n")
+	}
+}
+
+func TestContractTransferRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	//Transfers the amount in the call data to account B.
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	contractHash := addContract(append(append([]byte{
+		0, 31, // PUSH
+	}, accBHash[:]...),
+		35, // CALLDATA
+		58, // TRANSFER
+		50, // HALT
+	), nil)
+	contract, _ := storage.GetAccount(contractHash)
+	contract.Balance = 100
+	receiver, _ := storage.GetAccount(accBHash)
+	receiverBalance := receiver.Balance
+	rootBefore := stateRoot()
+
+	//The tx sends 1 coin to the contract.
+	b := newContractCallBlock(t, genesisBlock, contractHash, 15)
+	if contract.Balance != 86 || receiver.Balance != receiverBalance+15 {
+		t.Errorf("Expected balances 86 and %v after the transfer but were %v and %v\n", receiverBalance+15, contract.Balance, receiver.Balance)
+	}
+	expected := []storage.TransferJournalEntry{{contractHash, accBHash, 15}}
+	if journal := storage.ReadJournal(b.Hash); journal == nil || !reflect.DeepEqual(journal.Transfers, expected) {
+		t.Fatalf("Transfer was not journaled: %v\n", journal)
+	}
+
+	if err := rollback(b); err != nil {
+		t.Fatalf("Could not roll back block: %v\n", err)
+	}
+	if contract.Balance != 100 || receiver.Balance != receiverBalance {
+		t.Errorf("Expected balances 100 and %v after the rollback but were %v and %v\n", receiverBalance, contract.Balance, receiver.Balance)
+	}
+	if stateRoot() != rootBefore {
+		t.Errorf("State root after rollback does not match the state root before the block\n")
+	}
+}
+
+func TestContractTransferExceedingBalance(t *testing.T) {
+	cleanAndPrepare()
+
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	contractHash := addContract(append(append([]byte{
+		0, 31, // PUSH
+	}, accBHash[:]...),
+		35, // CALLDATA
+		58, // TRANSFER
+		50, // HALT
+	), nil)
+
+	//The contract only has the coin of the tx.
+	b := newBlock(genesisBlock.Hash, genesisBlock.HashWithoutTx, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	tx, _ := protocol.ConstrFundsTx(0x01, 1, 100000, accA.TxCnt, accAHash, contractHash, PrivKeyAccA, PrivKeyMultiSig, []byte{1, 0, 2})
+	if err := addTx(b, tx); err == nil {
+		t.Errorf("Expected tx with a transfer exceeding the contract's balance to be rejected\n")
+	}
+	if _, exists := b.StateCopy[accBHash]; exists && b.StateCopy[accBHash].Balance != accB.Balance {
+		t.Errorf("Rejected transfer changed the state copy\n")
+	}
+}
+
+func TestContractReorg(t *testing.T) {
+	cleanAndPrepare()
+
+	contractHash := addCounterContract()
+	contract, _ := storage.GetAccount(contractHash)
+
+	//The competing chain without contract calls is mined first and then rolled back to the genesis block.
+	var competingChain []*protocol.Block
+	prevBlock := genesisBlock
+	for height := 1; height <= 3; height++ {
+		b := newBlock(prevBlock.Hash, prevBlock.HashWithoutTx, [crypto.COMM_PROOF_LENGTH]byte{}, uint32(height))
+		if err := finalizeBlock(b); err != nil {
+			t.Fatalf("Could not finalize block: %v\n", err)
+		}
+		if err := validate(b, false); err != nil {
+			t.Fatalf("Could not validate block: %v\n", err)
+		}
+		competingChain = append(competingChain, b)
+		prevBlock = b
+	}
+	for i := len(competingChain) - 1; i >= 0; i-- {
+		if err := rollback(competingChain[i]); err != nil {
+			t.Fatalf("Could not roll back block: %v\n", err)
+		}
+	}
+	lastBlock = genesisBlock
+	slashingDict = make(map[[32]byte]SlashingProof)
+
+	//Two contract calls, which are abandoned for the longer competing chain.
+	b1 := newContractCallBlock(t, genesisBlock, contractHash, 15)
+	newContractCallBlock(t, b1, contractHash, 1)
+	if expected := []protocol.ByteArray{{0, 18}}; !reflect.DeepEqual(contract.ContractVariables, expected) {
+		t.Fatalf("Expected contract variables %v after the calls but were %v\n", expected, contract.ContractVariables)
+	}
+
+	//The competing chain commits to the state without the contract calls.
+	if err := validate(competingChain[2], false); err != nil {
+		t.Fatalf("Could not switch to the competing chain: %v\n", err)
+	}
+	if expected := []protocol.ByteArray{{0, 2}}; !reflect.DeepEqual(contract.ContractVariables, expected) {
+		t.Errorf("Expected contract variables %v after the reorganization but were %v\n", expected, contract.ContractVariables)
+	}
+	if stateRoot() != competingChain[2].StateRoot {
+		t.Errorf("State after the reorganization does not match the competing chain's state root\n")
+	}
+}
+
+func TestContractUpgradeRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	contractHash := addCounterContract()
+	contract, _ := storage.GetAccount(contractHash)
+	contract.Issuer = protocol.SerializeHashContent(rootAcc.Address)
+	code := contract.Contract
+	rootBefore := stateRoot()
+
+	//The new contract counts in the variable 1, which is added by the upgrade.
+	newCode := []byte{
+		35,    // CALLDATA
+		29, 1, // SLOAD
+		4,     // ADD
+		27, 1, // SSTORE
+		50, // HALT
+	}
+	upgrade, _ := protocol.ConstrAccUpgradeTx(1, contract.Address, PrivKeyRoot, newCode, []protocol.ByteArray{{0, 2}, {0, 7}}, 2)
+
+	b := newBlock(genesisBlock.Hash, genesisBlock.HashWithoutTx, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	if err := addTx(b, upgrade); err == nil {
+		t.Errorf("Expected upgrade to be rejected before its lock height\n")
+	}
+
+	//The call in the same block runs the new contract.
+	b = newBlock(genesisBlock.Hash, genesisBlock.HashWithoutTx, [crypto.COMM_PROOF_LENGTH]byte{}, 2)
+	if err := addTx(b, upgrade); err != nil {
+		t.Fatalf("Could not add upgrade: %v\n", err)
+	}
+	storage.WriteOpenTx(upgrade)
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	tx, _ := protocol.ConstrFundsTx(0x01, 1, 100000, accA.TxCnt, accAHash, contractHash, PrivKeyAccA, PrivKeyMultiSig, []byte{1, 0, 3})
+	if err := addTx(b, tx); err != nil {
+		t.Fatalf("Could not add contract call: %v\n", err)
+	}
+	addFundsTxFinal(b, tx)
+	storage.WriteOpenTx(tx)
+
+	if err := finalizeBlock(b); err != nil {
+		t.Fatalf("Could not finalize block: %v\n", err)
+	}
+	if err := validate(b, false); err != nil {
+		t.Fatalf("Could not validate block: %v\n", err)
+	}
+
+	if !reflect.DeepEqual(contract.Contract, newCode) {
+		t.Errorf("Contract was not upgraded: %v\n", contract.Contract)
+	}
+	if expected := []protocol.ByteArray{{0, 2}, {0, 10}}; !reflect.DeepEqual(contract.ContractVariables, expected) {
+		t.Errorf("Expected contract variables %v after the call but were %v\n", expected, contract.ContractVariables)
+	}
+	if journal := storage.ReadJournal(b.Hash); journal == nil || len(journal.Upgrades) != 1 {
+		t.Fatalf("Upgrade was not journaled: %v\n", journal)
+	}
+	history := storage.ReadContractHistory(contractHash)
+	if len(history) != 1 || history[0].Type != protocol.CONTRACT_UPGRADE || history[0].OldCode != protocol.CodeHash(code) || history[0].NewCode != protocol.CodeHash(newCode) {
+		t.Errorf("Upgrade is not in the contract history: %v\n", history)
+	}
+
+	if err := rollback(b); err != nil {
+		t.Fatalf("Could not roll back block: %v\n", err)
+	}
+	if !reflect.DeepEqual(contract.Contract, code) {
+		t.Errorf("Contract was not restored: %v\n", contract.Contract)
+	}
+	if expected := []protocol.ByteArray{{0, 2}}; !reflect.DeepEqual(contract.ContractVariables, expected) {
+		t.Errorf("Expected contract variables %v after the rollback but were %v\n", expected, contract.ContractVariables)
+	}
+	if history := storage.ReadContractHistory(contractHash); len(history) != 0 {
+		t.Errorf("Contract history of the rolled back block was not deleted: %v\n", history)
+	}
+	if stateRoot() != rootBefore {
+		t.Errorf("State root after rollback does not match the state root before the block\n")
+	}
+}
+
+func TestContractSelfDestructRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	//Sends the balance to account B and removes itself.
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	code := append(append([]byte{
+		0, 31, // PUSH
+	}, accBHash[:]...),
+		66, // SELFDESTRUCT
+	)
+	contractHash := addContract(code, []protocol.ByteArray{{0, 2}})
+	contract, _ := storage.GetAccount(contractHash)
+	contract.Balance = 100
+	receiver, _ := storage.GetAccount(accBHash)
+	receiverBalance := receiver.Balance
+	rootBefore := stateRoot()
+
+	//The balance includes the coin of the tx.
+	b := newContractCallBlock(t, genesisBlock, contractHash, 15)
+	if contract.Balance != 0 || receiver.Balance != receiverBalance+101 {
+		t.Errorf("Expected balances 0 and %v after the self-destruct but were %v and %v\n", receiverBalance+101, contract.Balance, receiver.Balance)
+	}
+	if contract.Contract != nil || contract.ContractVariables != nil {
+		t.Errorf("Contract was not removed: %v, %v\n", contract.Contract, contract.ContractVariables)
+	}
+	if journal := storage.ReadJournal(b.Hash); journal == nil || len(journal.SelfDestructs) != 1 || journal.SelfDestructs[0].Amount != 101 {
+		t.Fatalf("Self-destruct was not journaled: %v\n", journal)
+	}
+	history := storage.ReadContractHistory(contractHash)
+	if len(history) != 1 || history[0].Type != protocol.CONTRACT_SELFDESTRUCT || history[0].Beneficiary != accBHash || history[0].Amount != 101 {
+		t.Errorf("Self-destruct is not in the contract history: %v\n", history)
+	}
+
+	if err := rollback(b); err != nil {
+		t.Fatalf("Could not roll back block: %v\n", err)
+	}
+	if contract.Balance != 100 || receiver.Balance != receiverBalance {
+		t.Errorf("Expected balances 100 and %v after the rollback but were %v and %v\n", receiverBalance, contract.Balance, receiver.Balance)
+	}
+	if !reflect.DeepEqual(contract.Contract, code) || !reflect.DeepEqual(contract.ContractVariables, []protocol.ByteArray{{0, 2}}) {
+		t.Errorf("Contract was not restored: %v, %v\n", contract.Contract, contract.ContractVariables)
+	}
+	if history := storage.ReadContractHistory(contractHash); len(history) != 0 {
+		t.Errorf("Contract history of the rolled back block was not deleted: %v\n", history)
+	}
+	if stateRoot() != rootBefore {
+		t.Errorf("State root after rollback does not match the state root before the block\n")
+	}
+}
+
+func TestComputeStateRoot_Speculative(t *testing.T) {
+	cleanAndPrepare()
+
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	code := append(append([]byte{
+		0, 31, // PUSH
+	}, accBHash[:]...),
+		66, // SELFDESTRUCT
+	)
+	contractHash := addContract(code, nil)
+	contract, _ := storage.GetAccount(contractHash)
+	rootBefore := stateRoot()
+
+	b := newBlock(genesisBlock.Hash, genesisBlock.HashWithoutTx, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	tx, _ := protocol.ConstrFundsTx(0x01, 1, 100000, accA.TxCnt, accAHash, contractHash, PrivKeyAccA, PrivKeyMultiSig, []byte{1, 0, 1})
+	if err := addTx(b, tx); err != nil {
+		t.Fatalf("Could not add contract call: %v\n", err)
+	}
+	addFundsTxFinal(b, tx)
+	storage.WriteOpenTx(tx)
+
+	//The block is applied to a copy, neither the state nor the database is touched.
+	if err := finalizeBlock(b); err != nil {
+		t.Fatalf("Could not finalize block: %v\n", err)
+	}
+	if stateRoot() != rootBefore || contract.Contract == nil {
+		t.Errorf("Computing the state root changed the state\n")
+	}
+	if storage.ReadReceipt(tx.Hash()) != nil || len(storage.ReadContractHistory(contractHash)) != 0 {
+		t.Errorf("Computing the state root wrote to the database\n")
+	}
+
+	if err := validate(b, false); err != nil {
+		t.Fatalf("Could not validate block: %v\n", err)
+	}
+	if stateRoot() != b.StateRoot {
+		t.Errorf("State after validation does not match the block's state root: %x vs. %x\n", stateRoot(), b.StateRoot)
+	}
+	if storage.ReadReceipt(tx.Hash()) == nil || len(storage.ReadContractHistory(contractHash)) != 1 {
+		t.Errorf("Receipt and contract history were not written when the block was validated\n")
+	}
+}
+
+func TestStateTree_Incremental(t *testing.T) {
+	cleanAndPrepare()
+
+	contractHash := addCounterContract()
+	stateRoot()
+
+	//The tree is only updated with the accounts the blocks change, it must match the tree over all accounts.
+	b := newContractCallBlock(t, genesisBlock, contractHash, 15)
+	if root := protocol.BuildStateTree(storage.State).Root(); stateRoot() != root || stateTree == nil {
+		t.Errorf("Updated state tree does not match the state after validation: %x vs. %x\n", stateRoot(), root)
+	}
+
+	if err := rollback(b); err != nil {
+		t.Fatalf("Could not roll back block: %v\n", err)
+	}
+	if root := protocol.BuildStateTree(storage.State).Root(); stateRoot() != root {
+		t.Errorf("Updated state tree does not match the state after rollback: %x vs. %x\n", stateRoot(), root)
+	}
+}
+
+func TestClosedStateTree(t *testing.T) {
+	cleanAndPrepare()
+
+	contractHash := addCounterContract()
+	b := newContractCallBlock(t, genesisBlock, contractHash, 15)
+
+	block, tree := storage.ReadClosedStateTree()
+	if block != b || tree.Root() != b.StateRoot {
+		t.Fatalf("State tree of the validated block was not published\n")
+	}
+
+	//Proofs of the published tree stay valid while the miner changes the state.
+	contract, _ := storage.GetAccount(contractHash)
+	contract.Balance += 1000
+	acc, siblings, err := tree.Proof(contractHash)
+	if err != nil {
+		t.Fatalf("Could not create proof: %v\n", err)
+	}
+	if acc.Balance+1000 != contract.Balance {
+		t.Errorf("Account of the published tree was changed with the state\n")
+	}
+	if err := protocol.VerifyAccountProof(b.StateRoot, &protocol.AccountProof{b.Hash, b.Height, acc, siblings}); err != nil {
+		t.Errorf("Proof of the published tree was rejected: %v\n", err)
+	}
+	contract.Balance -= 1000
+
+	if err := rollback(b); err != nil {
+		t.Fatalf("Could not roll back block: %v\n", err)
+	}
+	if block, tree := storage.ReadClosedStateTree(); block.Hash != genesisBlock.Hash || tree.Root() != stateRoot() {
+		t.Errorf("State tree of the previous block was not published after the rollback\n")
+	}
+}
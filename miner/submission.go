@@ -0,0 +1,153 @@
+package miner
+
+import (
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"github.com/bazo-blockchain/bazo-miner/vm"
+	"math"
+)
+
+//Reasons why a submitted transaction is not accepted into the mempool.
+const (
+	REJECT_NOT_READY          = "not_ready"
+	REJECT_UNSUPPORTED_TX     = "unsupported_tx"
+	REJECT_DUPLICATE          = "duplicate"
+	REJECT_LOW_FEE            = "low_fee"
+	REJECT_INVALID_AMOUNT     = "invalid_amount"
+	REJECT_UNKNOWN_ACCOUNT    = "unknown_account"
+	REJECT_TXCNT_MISMATCH     = "txcnt_mismatch"
+	REJECT_INSUFFICIENT_FUNDS = "insufficient_funds"
+	REJECT_SAME_ACCOUNT       = "same_account"
+	REJECT_INVALID_CONTRACT   = "invalid_contract"
+	REJECT_INVALID_SIG        = "invalid_signature"
+)
+
+type TxRejection struct {
+	Reason  string
+	Message string
+}
+
+func (r *TxRejection) Error() string {
+	return r.Message
+}
+
+func reject(reason string, format string, a ...interface{}) *TxRejection {
+	return &TxRejection{reason, fmt.Sprintf(format, a...)}
+}
+
+//Transactions submitted by clients (e.g., over the HTTP API) are checked here before they enter the mempool.
+//In contrast to the txs received by the p2p package, they are verified right away, such that the client gets
+//to know why a tx has been rejected.
+func SubmitTx(tx protocol.Transaction) (txHash [32]byte, err error) {
+	if err := verifySubmission(tx); err != nil {
+		return txHash, err
+	}
+
+	txHash = tx.Hash()
+	if storage.ReadOpenTx(txHash) != nil || storage.ReadClosedTx(txHash) != nil {
+		return txHash, reject(REJECT_DUPLICATE, "Tx (%x) is already known.", txHash[0:8])
+	}
+
+	storage.WriteOpenTx(tx)
+	broadcastTx(tx)
+
+	return txHash, nil
+}
+
+//Checks the tx against the current state, which is read under the validation lock, such that no block is applied or
+//rolled back meanwhile.
+func verifySubmission(tx protocol.Transaction) *TxRejection {
+	blockValidation.Lock()
+	defer blockValidation.Unlock()
+
+	if activeParameters == nil {
+		return reject(REJECT_NOT_READY, "Miner is not initialized yet.")
+	}
+
+	if err := preVerify(tx); err != nil {
+		return err
+	}
+
+	//preVerify() reports everything else verify() checks, so a failure here is due to a signature.
+	//verify() sets the account hashes of a funds tx, so the hash is only final afterwards.
+	if !verify(tx) {
+		return reject(REJECT_INVALID_SIG, "Signature of tx could not be verified.")
+	}
+
+	return nil
+}
+
+//State dependent checks which verify() does not report on.
+func preVerify(tx protocol.Transaction) *TxRejection {
+	switch tx.(type) {
+	case *protocol.FundsTx, *protocol.AccTx, *protocol.ConfigTx, *protocol.StakeTx:
+	default:
+		return reject(REJECT_UNSUPPORTED_TX, "Tx type %T can't be submitted.", tx)
+	}
+
+	if tx.TxFee() < activeParameters.Fee_minimum {
+		return reject(REJECT_LOW_FEE, "Transaction fee too low: %v (minimum is: %v)", tx.TxFee(), activeParameters.Fee_minimum)
+	}
+
+	switch tx.(type) {
+	case *protocol.FundsTx:
+		fundsTx := tx.(*protocol.FundsTx)
+		if fundsTx.Amount == 0 || fundsTx.Amount > MAX_MONEY {
+			return reject(REJECT_INVALID_AMOUNT, "Invalid transaction amount: %v", fundsTx.Amount)
+		}
+
+		accFrom, accTo := storage.State[fundsTx.From], storage.State[fundsTx.To]
+		if accFrom == nil {
+			return reject(REJECT_UNKNOWN_ACCOUNT, "Sender account (%x) non existent.", fundsTx.From[0:8])
+		}
+		if accTo == nil {
+			return reject(REJECT_UNKNOWN_ACCOUNT, "Receiver account (%x) non existent.", fundsTx.To[0:8])
+		}
+
+		//Txs with a higher txCnt stay in the mempool until their predecessors arrive.
+		if fundsTx.TxCnt < accFrom.TxCnt {
+			return reject(REJECT_TXCNT_MISMATCH, "Sender txCnt does not match: %v (tx.txCnt) vs. %v (state txCnt)", fundsTx.TxCnt, accFrom.TxCnt)
+		}
+
+		if fundsTx.From == fundsTx.To {
+			return reject(REJECT_SAME_ACCOUNT, "Sender and receiver (%x) are the same account.", fundsTx.From[0:8])
+		}
+
+		if fundsTx.Fee > math.MaxUint64-fundsTx.Amount {
+			return reject(REJECT_INVALID_AMOUNT, "Amount %v and fee %v overflow.", fundsTx.Amount, fundsTx.Fee)
+		}
+
+		if fundsTx.Amount+fundsTx.Fee > accFrom.Balance {
+			return reject(REJECT_INSUFFICIENT_FUNDS, "Not enough funds: balance %v, amount + fee %v", accFrom.Balance, fundsTx.Amount+fundsTx.Fee)
+		}
+	case *protocol.AccTx:
+		accTx := tx.(*protocol.AccTx)
+		variables := accTx.ContractVariables
+		if accTx.Header == protocol.ACCTX_UPGRADE {
+			acc := storage.State[protocol.SerializeHashContent(accTx.PubKey)]
+			if acc == nil {
+				return reject(REJECT_UNKNOWN_ACCOUNT, "Contract account (%x) non existent.", accTx.PubKey[0:8])
+			}
+			if len(acc.Contract) == 0 || len(accTx.Contract) == 0 {
+				return reject(REJECT_INVALID_CONTRACT, "Upgrade needs a contract account (%x) and a new contract.", accTx.PubKey[0:8])
+			}
+			if len(variables) == 0 {
+				variables = acc.ContractVariables
+			}
+		}
+
+		if len(accTx.Contract) > 0 {
+			if err := vm.Verify(accTx.Contract, variables); err != nil {
+				return reject(REJECT_INVALID_CONTRACT, "Invalid contract: %v", err)
+			}
+		}
+	case *protocol.StakeTx:
+		stakeTx := tx.(*protocol.StakeTx)
+		if storage.State[stakeTx.Account] == nil {
+			return reject(REJECT_UNKNOWN_ACCOUNT, "Account (%x) non existent.", stakeTx.Account[0:8])
+		}
+	}
+
+	return nil
+}
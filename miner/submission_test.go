@@ -0,0 +1,49 @@
+package miner
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+func TestPreVerify_FundsTx(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	tx, _ := protocol.ConstrFundsTx(0x01, 10, 1, accA.TxCnt, accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+	if err := preVerify(tx); err != nil {
+		t.Errorf("Expected valid tx to pass but was rejected: %v\n", err)
+	}
+
+	tx, _ = protocol.ConstrFundsTx(0x01, 10, math.MaxUint64-5, accA.TxCnt, accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+	if err := preVerify(tx); err == nil || err.Reason != REJECT_INVALID_AMOUNT {
+		t.Errorf("Expected overflowing amount and fee to be rejected as '%v' but was: %v\n", REJECT_INVALID_AMOUNT, err)
+	}
+
+	tx, _ = protocol.ConstrFundsTx(0x01, 10, 1, accA.TxCnt, accAHash, accAHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+	if err := preVerify(tx); err == nil || err.Reason != REJECT_SAME_ACCOUNT {
+		t.Errorf("Expected tx to the sender to be rejected as '%v' but was: %v\n", REJECT_SAME_ACCOUNT, err)
+	}
+
+	tx, _ = protocol.ConstrFundsTx(0x01, accA.Balance, 1, accA.TxCnt, accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+	if err := preVerify(tx); err == nil || err.Reason != REJECT_INSUFFICIENT_FUNDS {
+		t.Errorf("Expected tx exceeding the balance to be rejected as '%v' but was: %v\n", REJECT_INSUFFICIENT_FUNDS, err)
+	}
+}
+
+func TestPreVerify_AccTxContract(t *testing.T) {
+	cleanAndPrepare()
+
+	//The contract has no variable 1.
+	invalid := []byte{
+		29, 1, // SLOAD
+		50, // HALT
+	}
+	tx, _, _ := protocol.ConstrAccTx(0, 1, [64]byte{}, PrivKeyRoot, invalid, []protocol.ByteArray{{0, 2}})
+	if err := preVerify(tx); err == nil || err.Reason != REJECT_INVALID_CONTRACT {
+		t.Errorf("Expected invalid contract to be rejected as '%v' but was: %v\n", REJECT_INVALID_CONTRACT, err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
+	"github.com/bazo-blockchain/bazo-miner/vm"
 )
 
 //We can't use polymorphism, e.g. we can't use tx.verify() because the Transaction interface doesn't declare
@@ -101,6 +102,18 @@ func verifyAccTx(tx *protocol.AccTx) bool {
 		return false
 	}
 
+	if tx.Header == protocol.ACCTX_UPGRADE {
+		return verifyAccUpgradeTx(tx)
+	}
+
+	//Contracts which can't be executed are not deployed.
+	if len(tx.Contract) > 0 {
+		if err := vm.Verify(tx.Contract, tx.ContractVariables); err != nil {
+			logger.Printf("Invalid contract: %v\n", err)
+			return false
+		}
+	}
+
 	r, s := new(big.Int), new(big.Int)
 	pub1, pub2 := new(big.Int), new(big.Int)
 
@@ -123,6 +136,36 @@ func verifyAccTx(tx *protocol.AccTx) bool {
 	return false
 }
 
+//An upgrade must be signed by the root key which issued the contract account. The new contract must be executable
+//with the variables the account has after the upgrade.
+func verifyAccUpgradeTx(tx *protocol.AccTx) bool {
+	acc, err := storage.GetAccount(protocol.SerializeHashContent(tx.PubKey))
+	if err != nil || len(acc.Contract) == 0 || len(tx.Contract) == 0 {
+		return false
+	}
+
+	issuer, exists := storage.RootKeys[tx.Issuer]
+	if !exists || tx.Issuer != acc.Issuer {
+		return false
+	}
+
+	variables := acc.ContractVariables
+	if len(tx.ContractVariables) > 0 {
+		variables = tx.ContractVariables
+	}
+	if err := vm.Verify(tx.Contract, variables); err != nil {
+		logger.Printf("Invalid contract: %v\n", err)
+		return false
+	}
+
+	r, s := new(big.Int).SetBytes(tx.Sig[:32]), new(big.Int).SetBytes(tx.Sig[32:])
+	pub1, pub2 := new(big.Int).SetBytes(issuer.Address[:32]), new(big.Int).SetBytes(issuer.Address[32:])
+	pubKey := ecdsa.PublicKey{elliptic.P256(), pub1, pub2}
+	txHash := tx.Hash()
+
+	return ecdsa.Verify(&pubKey, txHash[:], r, s)
+}
+
 func verifyConfigTx(tx *protocol.ConfigTx) bool {
 	if tx == nil {
 		return false
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
 )
 
 func TestFundsTxVerification(t *testing.T) {
@@ -58,3 +59,66 @@ func TestConfigTx(t *testing.T) {
 		t.Error("ConfigTx verification malfunctioning!")
 	}
 }
+
+func TestVerifyAccTx_Contract(t *testing.T) {
+	valid := []byte{
+		35,    // CALLDATA
+		29, 0, // SLOAD
+		4,     // ADD
+		27, 0, // SSTORE
+		50, // HALT
+	}
+	tx, _, _ := protocol.ConstrAccTx(0, 1, [64]byte{}, PrivKeyRoot, valid, []protocol.ByteArray{{0, 2}})
+	if !verifyAccTx(tx) {
+		t.Errorf("Expected AccTx with a valid contract to be verified\n")
+	}
+
+	//The contract has no variable 1.
+	invalid := []byte{
+		29, 1, // SLOAD
+		50, // HALT
+	}
+	tx, _, _ = protocol.ConstrAccTx(0, 1, [64]byte{}, PrivKeyRoot, invalid, []protocol.ByteArray{{0, 2}})
+	if verifyAccTx(tx) {
+		t.Errorf("Expected AccTx with an invalid contract to be rejected\n")
+	}
+}
+
+func TestVerifyAccTx_Upgrade(t *testing.T) {
+	cleanAndPrepare()
+
+	contractHash := addCounterContract()
+	contract, _ := storage.GetAccount(contractHash)
+	contract.Issuer = protocol.SerializeHashContent(rootAcc.Address)
+
+	//Reads the variable 1, which the account only has with the new variables.
+	code := []byte{
+		35,    // CALLDATA
+		29, 1, // SLOAD
+		4,     // ADD
+		27, 1, // SSTORE
+		50, // HALT
+	}
+	variables := []protocol.ByteArray{{0, 2}, {0, 7}}
+
+	tx, _ := protocol.ConstrAccUpgradeTx(1, contract.Address, PrivKeyRoot, code, variables, 0)
+	if !verifyAccTx(tx) {
+		t.Errorf("Expected upgrade signed by the issuer to be verified\n")
+	}
+
+	tx, _ = protocol.ConstrAccUpgradeTx(1, contract.Address, PrivKeyRoot, code, nil, 0)
+	if verifyAccTx(tx) {
+		t.Errorf("Expected upgrade with a contract which can't run with the kept variables to be rejected\n")
+	}
+
+	tx, _ = protocol.ConstrAccUpgradeTx(1, contract.Address, PrivKeyAccA, code, variables, 0)
+	if verifyAccTx(tx) {
+		t.Errorf("Expected upgrade signed by another key than the issuer's to be rejected\n")
+	}
+
+	contract.Issuer = [32]byte{}
+	tx, _ = protocol.ConstrAccUpgradeTx(1, contract.Address, PrivKeyRoot, code, variables, 0)
+	if verifyAccTx(tx) {
+		t.Errorf("Expected upgrade of a contract issued by another root key to be rejected\n")
+	}
+}
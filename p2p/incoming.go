@@ -47,6 +47,8 @@ func processIncomingMsg(p *peer, header *Header, payload []byte) {
 		accRes(p, payload)
 	case ROOTACC_REQ:
 		rootAccRes(p, payload)
+	case ACC_PROOF_REQ:
+		accProofRes(p, payload)
 	case MINER_PING:
 		pongRes(p, payload, MINER_PING)
 	case CLIENT_PING:
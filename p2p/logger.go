@@ -39,6 +39,7 @@ func InitLogging() {
 	LogMapping[30] = "UNKNOWNTX_REQ"
 	LogMapping[31] = "SPECIALTX_REQ"
 	LogMapping[32] = "NOT_FOUND_TX_REQ"
+	LogMapping[33] = "ACC_PROOF_REQ"
 
 	LogMapping[40] = "FUNDSTX_RES"
 	LogMapping[41] = "ACCTX_RES"
@@ -50,6 +51,7 @@ func InitLogging() {
 	LogMapping[47] = "ROOTACC_RES"
 	LogMapping[48] = "INTERMEDIATE_NODES_RES"
 	LogMapping[49] = "AGGTX_RES"
+	LogMapping[50] = "ACC_PROOF_RES"
 
 	LogMapping[130] = "NEIGHBOR_REQ"
 	LogMapping[140] = "NEIGHBOR_RES"
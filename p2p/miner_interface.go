@@ -79,8 +79,9 @@ func forwardBlockToMiner(p *peer, payload []byte) {
 //	if !BlockAlreadyReceived(storage.ReadReceivedBlockStash(),block.Hash){
 		if len(BlockIn) > 0 {
 			var block *protocol.Block
-			block = block.Decode(payload)
-			logger.Printf("Inside ForwardBlockToMiner --> len(BlockIn) = %v for block %x", len(BlockIn), block.Hash[0:8])
+			if block = block.Decode(payload); block != nil {
+				logger.Printf("Inside ForwardBlockToMiner --> len(BlockIn) = %v for block %x", len(BlockIn), block.Hash[0:8])
+			}
 		}
 		BlockIn <- payload
 //	}
@@ -223,6 +224,9 @@ func forwardTxReqToMiner(p *peer, payload []byte, txType uint8) {
 func forwardBlockReqToMiner(p *peer, payload []byte) {
 	var block *protocol.Block
 	block = block.Decode(payload)
+	if block == nil {
+		return
+	}
 
 	blockStashMutex.Lock()
 	if !BlockAlreadyReceived(storage.ReadReceivedBlockStash(), block.Hash) {
@@ -269,6 +269,40 @@ func rootAccRes(p *peer, payload []byte) {
 	sendData(p, packet)
 }
 
+//Responds with an account and its proof against the state root of the last closed block. Light clients verify
+//it with protocol.VerifyAccountProof instead of trusting the miner (as they have to with ACC_RES).
+func accProofRes(p *peer, payload []byte) {
+	var packet []byte
+	var hash [32]byte
+	if len(payload) != 32 {
+		return
+	}
+	copy(hash[:], payload[0:32])
+
+	if proof := _accProofRes(hash); proof != nil {
+		packet = BuildPacket(ACC_PROOF_RES, proof.Encode())
+	} else {
+		packet = BuildPacket(NOT_FOUND, nil)
+	}
+
+	sendData(p, packet)
+}
+
+func _accProofRes(hash [32]byte) *protocol.AccountProof {
+	//The miner publishes the tree of every block it closes, the State itself may already be ahead of it.
+	block, tree := storage.ReadClosedStateTree()
+	if block == nil || tree.Root() != block.StateRoot {
+		return nil
+	}
+
+	acc, siblings, err := tree.Proof(hash)
+	if err != nil {
+		return nil
+	}
+
+	return &protocol.AccountProof{block.Hash, block.Height, acc, siblings}
+}
+
 //Completes the handshake with another miner.
 func pongRes(p *peer, payload []byte, peerType uint) {
 	//Payload consists of a 2 bytes array (port number [big endian encoded]).
@@ -180,6 +180,11 @@ func extractHeader(headerData []byte) *Header {
 }
 
 func IsBootstrap() bool {
+	//Without network (e.g., when importing a chain) there is no bootstrap server.
+	if !strings.Contains(storage.Bootstrap_Server, ":") || !strings.Contains(Ipport, ":") {
+		return false
+	}
+
 	//Set thisPort global, this will be the listening port for incoming connection
 	bootstrapPort := strings.Split(storage.Bootstrap_Server, ":")[1]
 	thisPort := strings.Split(Ipport, ":")[1]
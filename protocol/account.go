@@ -50,34 +50,57 @@ func (acc *Account) Hash() [32]byte {
 	return SerializeHashContent(acc.Address)
 }
 
+//Canonical encoding (see encoding.go) of the fields Address, Issuer, Balance, TxCnt, IsStaking, CommitmentKey,
+//StakingBlockHeight, Contract and ContractVariables.
 func (acc *Account) Encode() []byte {
 	if acc == nil {
 		return nil
 	}
 
-	encoded := Account{
-		Address:            acc.Address,
-		Issuer:             acc.Issuer,
-		Balance:            acc.Balance,
-		TxCnt:              acc.TxCnt,
-		IsStaking:          acc.IsStaking,
-		CommitmentKey:   	acc.CommitmentKey,
-		StakingBlockHeight: acc.StakingBlockHeight,
-		Contract:           acc.Contract,
-		ContractVariables:  acc.ContractVariables,
-	}
-
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encoded)
-	return buffer.Bytes()
+	enc := newEncoder(ENCODING_ACCOUNT)
+	enc.fixed(acc.Address[:])
+	enc.fixed(acc.Issuer[:])
+	enc.uint64(acc.Balance)
+	enc.uint32(acc.TxCnt)
+	enc.bool(acc.IsStaking)
+	enc.fixed(acc.CommitmentKey[:])
+	enc.uint32(acc.StakingBlockHeight)
+	enc.bytes(acc.Contract)
+	enc.byteArrays(acc.ContractVariables)
+	return enc.Bytes()
 }
 
 func (*Account) Decode(encoded []byte) (acc *Account) {
-	var decoded Account
-	buffer := bytes.NewBuffer(encoded)
-	decoder := gob.NewDecoder(buffer)
-	decoder.Decode(&decoded)
-	return &decoded
+	if !isCanonicalEncoding(encoded) {
+		//Legacy gob encoding
+		var decoded Account
+		buffer := bytes.NewBuffer(encoded)
+		decoder := gob.NewDecoder(buffer)
+		decoder.Decode(&decoded)
+		return &decoded
+	}
+
+	dec := newDecoder(ENCODING_ACCOUNT, encoded)
+	if dec == nil {
+		return nil
+	}
+
+	acc = new(Account)
+	dec.fixed(acc.Address[:])
+	dec.fixed(acc.Issuer[:])
+	acc.Balance = dec.uint64()
+	acc.TxCnt = dec.uint32()
+	acc.IsStaking = dec.bool()
+	dec.fixed(acc.CommitmentKey[:])
+	acc.StakingBlockHeight = dec.uint32()
+	acc.Contract = dec.bytes()
+	acc.ContractVariables = dec.byteArrays()
+
+	if !dec.ok() {
+		return nil
+	}
+
+	return acc
 }
 
 func (acc Account) String() string {
@@ -11,6 +11,10 @@ import (
 
 const (
 	ACCTX_SIZE = 169
+
+	//Header of an AccTx which replaces the contract of the existing account PubKey. It has to be signed by the
+	//account's issuer. Without ContractVariables, the account keeps its variables, otherwise they are replaced.
+	ACCTX_UPGRADE = 0x04
 )
 
 type AccTx struct {
@@ -21,6 +25,7 @@ type AccTx struct {
 	Sig               [64]byte
 	Contract          []byte
 	ContractVariables []ByteArray
+	LockHeight        uint32 //Upgrades only: the upgrade is not valid in blocks below this height
 }
 
 func ConstrAccTx(header byte, fee uint64, address [64]byte, rootPrivKey *ecdsa.PrivateKey, contract []byte, contractVariables []ByteArray) (tx *AccTx, newAccAddress *ecdsa.PrivateKey, err error) {
@@ -66,11 +71,60 @@ func ConstrAccTx(header byte, fee uint64, address [64]byte, rootPrivKey *ecdsa.P
 	return tx, newAccAddress, nil
 }
 
+//Constructs an upgrade of the contract of the account with the address, signed with the issuer's key.
+func ConstrAccUpgradeTx(fee uint64, address [64]byte, issuerPrivKey *ecdsa.PrivateKey, contract []byte, contractVariables []ByteArray, lockHeight uint32) (tx *AccTx, err error) {
+	tx = new(AccTx)
+	tx.Header = ACCTX_UPGRADE
+	tx.Fee = fee
+	tx.PubKey = address
+	tx.Contract = contract
+	tx.ContractVariables = contractVariables
+	tx.LockHeight = lockHeight
+
+	var issuerPublicKey [64]byte
+	issuerPubKey1, issuerPubKey2 := issuerPrivKey.PublicKey.X.Bytes(), issuerPrivKey.PublicKey.Y.Bytes()
+	copy(issuerPublicKey[32-len(issuerPubKey1):32], issuerPubKey1)
+	copy(issuerPublicKey[64-len(issuerPubKey2):], issuerPubKey2)
+	tx.Issuer = SerializeHashContent(issuerPublicKey)
+
+	txHash := tx.Hash()
+	r, s, err := ecdsa.Sign(rand.Reader, issuerPrivKey, txHash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	copy(tx.Sig[32-len(r.Bytes()):32], r.Bytes())
+	copy(tx.Sig[64-len(s.Bytes()):], s.Bytes())
+
+	return tx, nil
+}
+
+//The lock height is only part of the hash (and the encoding) of upgrades, the other AccTxs are hashed as before.
 func (tx *AccTx) Hash() [32]byte {
 	if tx == nil {
 		return [32]byte{}
 	}
 
+	if tx.Header == ACCTX_UPGRADE {
+		return SerializeHashContent(struct {
+			Header            byte
+			Issuer            [32]byte
+			Fee               uint64
+			PubKey            [64]byte
+			Contract          []byte
+			ContractVariables []ByteArray
+			LockHeight        uint32
+		}{
+			tx.Header,
+			tx.Issuer,
+			tx.Fee,
+			tx.PubKey,
+			tx.Contract,
+			tx.ContractVariables,
+			tx.LockHeight,
+		})
+	}
+
 	txHash := struct {
 		Header            byte
 		Issuer            [32]byte
@@ -90,30 +144,59 @@ func (tx *AccTx) Hash() [32]byte {
 	return SerializeHashContent(txHash)
 }
 
+//Canonical encoding (see encoding.go) of the fields Header, Issuer, Fee, PubKey, Sig, Contract and
+//ContractVariables, followed by LockHeight if the tx is an upgrade.
 func (tx *AccTx) Encode() []byte {
 	if tx == nil {
 		return nil
 	}
 
-	encoded := AccTx{
-		Header: tx.Header,
-		Issuer: tx.Issuer,
-		Fee:    tx.Fee,
-		PubKey: tx.PubKey,
-		Sig:    tx.Sig,
+	enc := newEncoder(ENCODING_ACCTX)
+	enc.byte(tx.Header)
+	enc.fixed(tx.Issuer[:])
+	enc.uint64(tx.Fee)
+	enc.fixed(tx.PubKey[:])
+	enc.fixed(tx.Sig[:])
+	enc.bytes(tx.Contract)
+	enc.byteArrays(tx.ContractVariables)
+	if tx.Header == ACCTX_UPGRADE {
+		enc.uint32(tx.LockHeight)
 	}
-
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encoded)
-	return buffer.Bytes()
+	return enc.Bytes()
 }
 
 func (*AccTx) Decode(encoded []byte) (tx *AccTx) {
-	var decoded AccTx
-	buffer := bytes.NewBuffer(encoded)
-	decoder := gob.NewDecoder(buffer)
-	decoder.Decode(&decoded)
-	return &decoded
+	if !isCanonicalEncoding(encoded) {
+		//Legacy gob encoding
+		var decoded AccTx
+		buffer := bytes.NewBuffer(encoded)
+		decoder := gob.NewDecoder(buffer)
+		decoder.Decode(&decoded)
+		return &decoded
+	}
+
+	dec := newDecoder(ENCODING_ACCTX, encoded)
+	if dec == nil {
+		return nil
+	}
+
+	tx = new(AccTx)
+	tx.Header = dec.byte()
+	dec.fixed(tx.Issuer[:])
+	tx.Fee = dec.uint64()
+	dec.fixed(tx.PubKey[:])
+	dec.fixed(tx.Sig[:])
+	tx.Contract = dec.bytes()
+	tx.ContractVariables = dec.byteArrays()
+	if tx.Header == ACCTX_UPGRADE {
+		tx.LockHeight = dec.uint32()
+	}
+
+	if !dec.ok() {
+		return nil
+	}
+
+	return tx
 }
 
 func (tx *AccTx) TxFee() uint64 { return tx.Fee }
@@ -133,7 +216,8 @@ func (tx AccTx) String() string {
 			"PubKey: %x\n"+
 			"Sig: %x\n"+
 			"Contract: %v\n"+
-			"ContractVariables: %v\n",
+			"ContractVariables: %v\n"+
+			"LockHeight: %v\n",
 		tx.Header,
 		tx.Issuer[0:8],
 		tx.Fee,
@@ -141,5 +225,6 @@ func (tx AccTx) String() string {
 		tx.Sig[0:8],
 		tx.Contract[:],
 		tx.ContractVariables[:],
+		tx.LockHeight,
 	)
 }
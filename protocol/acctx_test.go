@@ -112,6 +112,37 @@ func TestAccTxSerialization(t *testing.T) {
 	}
 }
 
+func TestAccUpgradeTx(t *testing.T) {
+	tx, err := ConstrAccUpgradeTx(1, accA.Address, RootPrivKey, []byte{1, 2}, nil, 100)
+	if err != nil {
+		t.Fatalf("Upgrade could not be constructed: %v", err)
+	}
+
+	if tx.Header != ACCTX_UPGRADE || tx.PubKey != accA.Address || tx.LockHeight != 100 {
+		t.Errorf("Upgrade has not been constructed correctly: %v", tx)
+	}
+
+	var decodedTx *AccTx
+	if decodedTx = decodedTx.Decode(tx.Encode()); !reflect.DeepEqual(tx, decodedTx) {
+		t.Errorf("AccTx serialization failed: %v vs. %v\n", tx, decodedTx)
+	}
+
+	//The lock height is signed as well.
+	hash := tx.Hash()
+	tx.LockHeight = 0
+	if tx.Hash() == hash {
+		t.Errorf("The lock height does not change the hash of an upgrade")
+	}
+
+	//Other AccTxs don't have a lock height.
+	accTx, _, _ := ConstrAccTx(0, 1, accA.Address, RootPrivKey, nil, nil)
+	encoded, hash := accTx.Encode(), accTx.Hash()
+	accTx.LockHeight = 100
+	if accTx.Hash() != hash || !reflect.DeepEqual(encoded, accTx.Encode()) {
+		t.Errorf("The lock height changes the encoding of an AccTx which is no upgrade")
+	}
+}
+
 func getAddressFromPubKey(pubKey *ecdsa.PublicKey) (address [64]byte) {
 	copy(address[:32], pubKey.X.Bytes())
 	copy(address[32:], pubKey.Y.Bytes())
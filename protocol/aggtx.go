@@ -63,31 +63,55 @@ func (tx *AggTx) Hash() (hash [32]byte) {
 	return SerializeHashContent(txHash)
 }
 
-//when we serialize the struct with binary.Write, unexported field get serialized as well, undesired
-//behavior. Therefore, writing own encoder/decoder
+//Canonical encoding (see encoding.go) of the fields Amount, Fee, From, To, AggregatedTxSlice, Aggregated, Block and
+//MerkleRoot.
 func (tx *AggTx) Encode() (encodedTx []byte) {
-	// Encode
-	encodeData := AggTx{
-		Amount: 				tx.Amount,
-		Fee:    				tx.Fee,
-		From:					tx.From,
-		To:    					tx.To,
-		AggregatedTxSlice: 		tx.AggregatedTxSlice,
-		Aggregated:				tx.Aggregated,
-		Block: 					tx.Block,
-		MerkleRoot:				tx.MerkleRoot,
+	if tx == nil {
+		return nil
 	}
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encodeData)
-	return buffer.Bytes()
+
+	enc := newEncoder(ENCODING_AGGTX)
+	enc.uint64(tx.Amount)
+	enc.uint64(tx.Fee)
+	enc.hashes(tx.From)
+	enc.hashes(tx.To)
+	enc.hashes(tx.AggregatedTxSlice)
+	enc.bool(tx.Aggregated)
+	enc.fixed(tx.Block[:])
+	enc.fixed(tx.MerkleRoot[:])
+	return enc.Bytes()
 }
 
 func (*AggTx) Decode(encodedTx []byte) *AggTx {
-	var decoded AggTx
-	buffer := bytes.NewBuffer(encodedTx)
-	decoder := gob.NewDecoder(buffer)
-	decoder.Decode(&decoded)
-	return &decoded
+	if !isCanonicalEncoding(encodedTx) {
+		//Legacy gob encoding
+		var decoded AggTx
+		buffer := bytes.NewBuffer(encodedTx)
+		decoder := gob.NewDecoder(buffer)
+		decoder.Decode(&decoded)
+		return &decoded
+	}
+
+	dec := newDecoder(ENCODING_AGGTX, encodedTx)
+	if dec == nil {
+		return nil
+	}
+
+	tx := new(AggTx)
+	tx.Amount = dec.uint64()
+	tx.Fee = dec.uint64()
+	tx.From = dec.hashes()
+	tx.To = dec.hashes()
+	tx.AggregatedTxSlice = dec.hashes()
+	tx.Aggregated = dec.bool()
+	dec.fixed(tx.Block[:])
+	dec.fixed(tx.MerkleRoot[:])
+
+	if !dec.ok() {
+		return nil
+	}
+
+	return tx
 }
 
 func (tx *AggTx) TxFee() uint64 { return tx.Fee }
@@ -13,7 +13,7 @@ const (
 	HASH_LEN                = 32
 	HEIGHT_LEN				= 4
 	//All fixed sizes form the Block struct are 254
-	MIN_BLOCKSIZE           = 393 + crypto.COMM_PROOF_LENGTH + 1 // = 650 bytes
+	MIN_BLOCKSIZE           = 425 + crypto.COMM_PROOF_LENGTH + 1 // = 682 bytes
 	MIN_BLOCKHEADER_SIZE    = 104
 	BLOOM_FILTER_ERROR_RATE = 0.1
 )
@@ -38,6 +38,7 @@ type Block struct {
 	Nonce                 [8]byte
 	Timestamp             int64
 	MerkleRoot            [32]byte
+	StateRoot             [32]byte 			//root of the state tree after this block
 	NrAccTx               uint16
 	NrFundsTx             uint16
 	NrStakeTx             uint16
@@ -49,7 +50,7 @@ type Block struct {
 	ConflictingBlockHashWithoutTx1 [32]byte
 	ConflictingBlockHashWithoutTx2 [32]byte
 	StateCopy             map[[32]byte]*Account //won't be serialized, just keeping track of local state changes
-	// ==> 216 bytes + crypto.COMM_PROOF_LENGTH
+	// ==> 248 bytes + crypto.COMM_PROOF_LENGTH
 
 	AccTxData    		 [][32]byte
 	FundsTxData  		 [][32]byte
@@ -74,34 +75,7 @@ func (block *Block) HashBlock() [32]byte {
 		return [32]byte{}
 	}
 
-	blockHash := struct {
-		prevHash              			[32]byte
-		prevHashWithoutTx     			[32]byte
-		timestamp             			int64
-		merkleRoot            			[32]byte
-		beneficiary           			[32]byte
-		commitmentProof       			[crypto.COMM_PROOF_LENGTH]byte
-		slashedAddress        			[32]byte
-		conflictingBlockHash1 			[32]byte
-		conflictingBlockHash2 			[32]byte
-		conflictingBlockHashWithoutTx1 	[32]byte
-		conflictingBlockHashWithoutTx2 	[32]byte
-		Aggregated			  			bool
-	}{
-		block.PrevHash,
-		block.PrevHashWithoutTx,
-		block.Timestamp,
-		block.MerkleRoot,
-		block.Beneficiary,
-		block.CommitmentProof,
-		block.SlashedAddress,
-		block.ConflictingBlockHash1,
-		block.ConflictingBlockHash2,
-		block.ConflictingBlockHashWithoutTx1,
-		block.ConflictingBlockHashWithoutTx2,
-		false,
-	}
-	return SerializeHashContent(blockHash)
+	return block.hashHeader(block.MerkleRoot, false)
 }
 
 func (block *Block) HashBlockWithoutMerkleRoot() [32]byte {
@@ -109,11 +83,49 @@ func (block *Block) HashBlockWithoutMerkleRoot() [32]byte {
 		return [32]byte{}
 	}
 
+	return block.hashHeader([32]byte{}, true)
+}
+
+//Blocks created before the state root was introduced carry a zero state root. Their hash leaves the state root out,
+//so that they hash to the same value as before and the existing chain can still be replayed.
+func (block *Block) hashHeader(merkleRoot [32]byte, aggregated bool) [32]byte {
+	if block.StateRoot == [32]byte{} {
+		blockHash := struct {
+			prevHash              			[32]byte
+			prevHashWithoutTx     			[32]byte
+			timestamp             			int64
+			merkleRoot            			[32]byte
+			beneficiary           			[32]byte
+			commitmentProof       			[crypto.COMM_PROOF_LENGTH]byte
+			slashedAddress        			[32]byte
+			conflictingBlockHash1 			[32]byte
+			conflictingBlockHash2 			[32]byte
+			conflictingBlockHashWithoutTx1 	[32]byte
+			conflictingBlockHashWithoutTx2 	[32]byte
+			Aggregated			  			bool
+		}{
+			block.PrevHash,
+			block.PrevHashWithoutTx,
+			block.Timestamp,
+			merkleRoot,
+			block.Beneficiary,
+			block.CommitmentProof,
+			block.SlashedAddress,
+			block.ConflictingBlockHash1,
+			block.ConflictingBlockHash2,
+			block.ConflictingBlockHashWithoutTx1,
+			block.ConflictingBlockHashWithoutTx2,
+			aggregated,
+		}
+		return SerializeHashContent(blockHash)
+	}
+
 	blockHash := struct {
 		prevHash              			[32]byte
-		prevHashWithoutTx	  			[32]byte
+		prevHashWithoutTx     			[32]byte
 		timestamp             			int64
 		merkleRoot            			[32]byte
+		stateRoot             			[32]byte
 		beneficiary           			[32]byte
 		commitmentProof       			[crypto.COMM_PROOF_LENGTH]byte
 		slashedAddress        			[32]byte
@@ -121,12 +133,13 @@ func (block *Block) HashBlockWithoutMerkleRoot() [32]byte {
 		conflictingBlockHash2 			[32]byte
 		conflictingBlockHashWithoutTx1 	[32]byte
 		conflictingBlockHashWithoutTx2 	[32]byte
-		Aggregated			 			bool
+		Aggregated			  			bool
 	}{
 		block.PrevHash,
 		block.PrevHashWithoutTx,
 		block.Timestamp,
-		[32]byte{},
+		merkleRoot,
+		block.StateRoot,
 		block.Beneficiary,
 		block.CommitmentProof,
 		block.SlashedAddress,
@@ -134,7 +147,7 @@ func (block *Block) HashBlockWithoutMerkleRoot() [32]byte {
 		block.ConflictingBlockHash2,
 		block.ConflictingBlockHashWithoutTx1,
 		block.ConflictingBlockHashWithoutTx2,
-		true,
+		aggregated,
 	}
 	return SerializeHashContent(blockHash)
 }
@@ -172,7 +185,8 @@ func (block *Block) GetHeaderSize() uint64 {
 		reflect.TypeOf(block.NrElementsBF).Size() +
 		reflect.TypeOf(block.Height).Size() +
 		reflect.TypeOf(block.Beneficiary).Size() +
-		reflect.TypeOf(block.Aggregated).Size())
+		reflect.TypeOf(block.Aggregated).Size() +
+		reflect.TypeOf(block.StateRoot).Size())
 
 	size += int(block.GetBloomFilterSize())
 
@@ -183,6 +197,7 @@ func (block *Block) GetBodySize() uint64 {
 	size := int(reflect.TypeOf(block.Nonce).Size() +
 		reflect.TypeOf(block.Timestamp).Size() +
 		reflect.TypeOf(block.MerkleRoot).Size() +
+		reflect.TypeOf(block.StateRoot).Size() +
 		reflect.TypeOf(block.NrAccTx).Size() +
 		reflect.TypeOf(block.NrFundsTx).Size() +
 		reflect.TypeOf(block.NrStakeTx).Size() +
@@ -220,49 +235,54 @@ func (block *Block) GetBloomFilterSize() uint64 {
 	return uint64(size)
 }
 
+//Canonical encoding (see encoding.go) of the header fields Header, Hash, PrevHash, HashWithoutTx, PrevHashWithoutTx,
+//NrConfigTx, NrElementsBF, BloomFilter, Height, Beneficiary and Aggregated, followed by the body fields Nonce,
+//Timestamp, MerkleRoot, StateRoot, NrAccTx, NrFundsTx, NrStakeTx, NrAggTx, SlashedAddress, CommitmentProof,
+//ConflictingBlockHash1, ConflictingBlockHash2, ConflictingBlockHashWithoutTx1, ConflictingBlockHashWithoutTx2,
+//AccTxData, FundsTxData, ConfigTxData, StakeTxData and AggTxData. The bloom filter is encoded as byte slice in the
+//format of its WriteTo function. StateCopy is not encoded.
 func (block *Block) Encode() []byte {
 	if block == nil {
 		return nil
 	}
 
-	encoded := Block{
-		Header:                			block.Header,
-		Hash:                  			block.Hash,
-		PrevHash:              			block.PrevHash,
-		HashWithoutTx:         			block.HashWithoutTx,
-		PrevHashWithoutTx:     			block.PrevHashWithoutTx,
-		Aggregated:			   			block.Aggregated,
-		Nonce:                 			block.Nonce,
-		Timestamp:             			block.Timestamp,
-		MerkleRoot:            			block.MerkleRoot,
-		Beneficiary:           			block.Beneficiary,
-		NrAccTx:               			block.NrAccTx,
-		NrFundsTx:             			block.NrFundsTx,
-		NrConfigTx:            			block.NrConfigTx,
-		NrStakeTx:             			block.NrStakeTx,
-		NrAggTx:         			block.NrAggTx,
-		NrElementsBF:          			block.NrElementsBF,
-		BloomFilter:           			block.BloomFilter,
-		SlashedAddress:        			block.SlashedAddress,
-		Height:                			block.Height,
-		CommitmentProof:	   			block.CommitmentProof,
-		ConflictingBlockHash1: 			block.ConflictingBlockHash1,
-		ConflictingBlockHash2: 			block.ConflictingBlockHash2,
-		ConflictingBlockHashWithoutTx1: block.ConflictingBlockHashWithoutTx1,
-		ConflictingBlockHashWithoutTx2: block.ConflictingBlockHashWithoutTx2,
-
-		AccTxData:    		   			block.AccTxData,
-		FundsTxData:  		   			block.FundsTxData,
-		ConfigTxData: 		   			block.ConfigTxData,
-		StakeTxData:  		   			block.StakeTxData,
-		AggTxData:	   					block.AggTxData,
-	}
-
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encoded)
-	return buffer.Bytes()
+	enc := newEncoder(ENCODING_BLOCK)
+	enc.byte(block.Header)
+	enc.fixed(block.Hash[:])
+	enc.fixed(block.PrevHash[:])
+	enc.fixed(block.HashWithoutTx[:])
+	enc.fixed(block.PrevHashWithoutTx[:])
+	enc.byte(block.NrConfigTx)
+	enc.uint16(block.NrElementsBF)
+	enc.bloomFilter(block.BloomFilter)
+	enc.uint32(block.Height)
+	enc.fixed(block.Beneficiary[:])
+	enc.bool(block.Aggregated)
+
+	enc.fixed(block.Nonce[:])
+	enc.int64(block.Timestamp)
+	enc.fixed(block.MerkleRoot[:])
+	enc.fixed(block.StateRoot[:])
+	enc.uint16(block.NrAccTx)
+	enc.uint16(block.NrFundsTx)
+	enc.uint16(block.NrStakeTx)
+	enc.uint16(block.NrAggTx)
+	enc.fixed(block.SlashedAddress[:])
+	enc.fixed(block.CommitmentProof[:])
+	enc.fixed(block.ConflictingBlockHash1[:])
+	enc.fixed(block.ConflictingBlockHash2[:])
+	enc.fixed(block.ConflictingBlockHashWithoutTx1[:])
+	enc.fixed(block.ConflictingBlockHashWithoutTx2[:])
+
+	enc.hashes(block.AccTxData)
+	enc.hashes(block.FundsTxData)
+	enc.hashes(block.ConfigTxData)
+	enc.hashes(block.StakeTxData)
+	enc.hashes(block.AggTxData)
+	return enc.Bytes()
 }
 
+//Encodes the block with the header fields only, the body fields are zero.
 func (block *Block) EncodeHeader() []byte {
 	if block == nil {
 		return nil
@@ -280,11 +300,10 @@ func (block *Block) EncodeHeader() []byte {
 		Height:       		block.Height,
 		Beneficiary:  		block.Beneficiary,
 		Aggregated:			block.Aggregated,
+		StateRoot:			block.StateRoot,
 	}
 
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encoded)
-	return buffer.Bytes()
+	return encoded.Encode()
 }
 
 func (block *Block) Decode(encoded []byte) (b *Block) {
@@ -292,11 +311,59 @@ func (block *Block) Decode(encoded []byte) (b *Block) {
 		return nil
 	}
 
-	var decoded Block
-	buffer := bytes.NewBuffer(encoded)
-	decoder := gob.NewDecoder(buffer)
-	decoder.Decode(&decoded)
-	return &decoded
+	if !isCanonicalEncoding(encoded) {
+		//Legacy gob encoding
+		var decoded Block
+		buffer := bytes.NewBuffer(encoded)
+		decoder := gob.NewDecoder(buffer)
+		decoder.Decode(&decoded)
+		return &decoded
+	}
+
+	dec := newDecoder(ENCODING_BLOCK, encoded)
+	if dec == nil {
+		return nil
+	}
+
+	b = new(Block)
+	b.Header = dec.byte()
+	dec.fixed(b.Hash[:])
+	dec.fixed(b.PrevHash[:])
+	dec.fixed(b.HashWithoutTx[:])
+	dec.fixed(b.PrevHashWithoutTx[:])
+	b.NrConfigTx = dec.byte()
+	b.NrElementsBF = dec.uint16()
+	b.BloomFilter = dec.bloomFilter()
+	b.Height = dec.uint32()
+	dec.fixed(b.Beneficiary[:])
+	b.Aggregated = dec.bool()
+
+	dec.fixed(b.Nonce[:])
+	b.Timestamp = dec.int64()
+	dec.fixed(b.MerkleRoot[:])
+	dec.fixed(b.StateRoot[:])
+	b.NrAccTx = dec.uint16()
+	b.NrFundsTx = dec.uint16()
+	b.NrStakeTx = dec.uint16()
+	b.NrAggTx = dec.uint16()
+	dec.fixed(b.SlashedAddress[:])
+	dec.fixed(b.CommitmentProof[:])
+	dec.fixed(b.ConflictingBlockHash1[:])
+	dec.fixed(b.ConflictingBlockHash2[:])
+	dec.fixed(b.ConflictingBlockHashWithoutTx1[:])
+	dec.fixed(b.ConflictingBlockHashWithoutTx2[:])
+
+	b.AccTxData = dec.hashes()
+	b.FundsTxData = dec.hashes()
+	b.ConfigTxData = dec.hashes()
+	b.StakeTxData = dec.hashes()
+	b.AggTxData = dec.hashes()
+
+	if !dec.ok() {
+		return nil
+	}
+
+	return b
 }
 
 func (block Block) String() string {
@@ -307,6 +374,7 @@ func (block Block) String() string {
 		"Nonce: %x\n"+
 		"Timestamp: %v\n"+
 		"MerkleRoot: %x\n"+
+		"StateRoot: %x\n"+
 		"Beneficiary: %x\n"+
 		"Amount of fundsTx: %v --> %x\n"+
 		"Amount of accTx: %v --> %x\n"+
@@ -325,6 +393,7 @@ func (block Block) String() string {
 		block.Nonce,
 		block.Timestamp,
 		block.MerkleRoot[0:8],
+		block.StateRoot[0:8],
 		block.Beneficiary[0:8],
 		block.NrFundsTx, block.FundsTxData,
 		block.NrAccTx, block.AccTxData,
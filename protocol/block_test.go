@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
 	"math/rand"
 	"reflect"
 	"testing"
@@ -54,6 +55,45 @@ func TestBlockHash(t *testing.T) {
 	}
 }
 
+func TestBlockHash_StateRoot(t *testing.T) {
+	block := NewBlock([32]byte{1}, 100)
+	block.Timestamp = 1500000000
+	block.MerkleRoot = [32]byte{2}
+	block.Beneficiary = [32]byte{3}
+
+	//Blocks without a state root hash to the same value as before the state root was introduced.
+	legacyHash := SerializeHashContent(struct {
+		prevHash                       [32]byte
+		prevHashWithoutTx              [32]byte
+		timestamp                      int64
+		merkleRoot                     [32]byte
+		beneficiary                    [32]byte
+		commitmentProof                [crypto.COMM_PROOF_LENGTH]byte
+		slashedAddress                 [32]byte
+		conflictingBlockHash1          [32]byte
+		conflictingBlockHash2          [32]byte
+		conflictingBlockHashWithoutTx1 [32]byte
+		conflictingBlockHashWithoutTx2 [32]byte
+		Aggregated                     bool
+	}{
+		prevHash:    block.PrevHash,
+		timestamp:   block.Timestamp,
+		merkleRoot:  block.MerkleRoot,
+		beneficiary: block.Beneficiary,
+	})
+	if block.HashBlock() != legacyHash {
+		t.Errorf("Hash of a block without a state root changed: %x vs. %x\n", block.HashBlock(), legacyHash)
+	}
+
+	block.StateRoot = [32]byte{4}
+	if block.HashBlock() == legacyHash {
+		t.Errorf("Block hash does not include the state root\n")
+	}
+	if block.HashBlockWithoutMerkleRoot() == block.HashBlock() {
+		t.Errorf("Block hash without merkle root includes the merkle root\n")
+	}
+}
+
 func TestBlockSerialization(t *testing.T) {
 	randVar := rand.New(rand.NewSource(time.Now().Unix()))
 
@@ -65,6 +105,7 @@ func TestBlockSerialization(t *testing.T) {
 	rand.Read(block.Nonce[:])
 	block.Timestamp = time.Now().Unix()
 	rand.Read(block.MerkleRoot[:])
+	rand.Read(block.StateRoot[:])
 	rand.Read(block.Beneficiary[:])
 	block.NrAccTx = uint16(randVar.Uint32())
 	block.NrFundsTx = uint16(randVar.Uint32())
@@ -104,6 +145,7 @@ func TestBlockHeaderSerialization(t *testing.T) {
 
 	blockHeader.Height = uint32(randVar.Uint32())
 	rand.Read(blockHeader.Beneficiary[:])
+	rand.Read(blockHeader.StateRoot[:])
 
 	var compareBlockHeader Block
 	encodedBlock := blockHeader.EncodeHeader()
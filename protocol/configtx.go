@@ -1,7 +1,6 @@
 package protocol
 
 import (
-	"bytes"
 	"crypto/ecdsa"
 	"crypto/rand"
 	"encoding/binary"
@@ -107,47 +106,52 @@ func (tx *ConfigTx) Hash() (hash [32]byte) {
 	return SerializeHashContent(txHash)
 }
 
+//Canonical encoding (see encoding.go) of the fields Header, Id, Payload, Fee, TxCnt and Sig.
 func (tx *ConfigTx) Encode() (encodedTx []byte) {
-
 	if tx == nil {
 		return nil
 	}
 
-	var buf bytes.Buffer
-	var payloadBuf [8]byte
-	var feeBuf [8]byte
-
-	binary.Write(&buf, binary.BigEndian, tx.Payload)
-	copy(payloadBuf[:], buf.Bytes())
-	buf.Reset()
-	binary.Write(&buf, binary.BigEndian, tx.Fee)
-	copy(feeBuf[:], buf.Bytes())
-	buf.Reset()
-
-	encodedTx = make([]byte, CONFIGTX_SIZE)
-	encodedTx[0] = tx.Header
-	encodedTx[1] = tx.Id
-	copy(encodedTx[2:10], payloadBuf[:])
-	copy(encodedTx[10:18], feeBuf[:])
-	encodedTx[18] = byte(tx.TxCnt)
-	copy(encodedTx[19:83], tx.Sig[:])
-
-	return encodedTx
+	enc := newEncoder(ENCODING_CONFIGTX)
+	enc.byte(tx.Header)
+	enc.byte(tx.Id)
+	enc.uint64(tx.Payload)
+	enc.uint64(tx.Fee)
+	enc.byte(tx.TxCnt)
+	enc.fixed(tx.Sig[:])
+	return enc.Bytes()
 }
 
 func (*ConfigTx) Decode(encodedTx []byte) (tx *ConfigTx) {
+	tx = new(ConfigTx)
+
+	//Legacy encoding with fixed size, the canonical encoding is never of that size, even if the header equals
+	//the version byte.
+	if len(encodedTx) == CONFIGTX_SIZE {
+		tx.Header = encodedTx[0]
+		tx.Id = encodedTx[1]
+		tx.Payload = binary.BigEndian.Uint64(encodedTx[2:10])
+		tx.Fee = binary.BigEndian.Uint64(encodedTx[10:18])
+		tx.TxCnt = uint8(encodedTx[18])
+		copy(tx.Sig[:], encodedTx[19:83])
+		return tx
+	}
 
-	if len(encodedTx) != CONFIGTX_SIZE {
+	dec := newDecoder(ENCODING_CONFIGTX, encodedTx)
+	if dec == nil {
 		return nil
 	}
 
-	tx = new(ConfigTx)
-	tx.Header = encodedTx[0]
-	tx.Id = encodedTx[1]
-	tx.Payload = binary.BigEndian.Uint64(encodedTx[2:10])
-	tx.Fee = binary.BigEndian.Uint64(encodedTx[10:18])
-	tx.TxCnt = uint8(encodedTx[18])
-	copy(tx.Sig[:], encodedTx[19:83])
+	tx.Header = dec.byte()
+	tx.Id = dec.byte()
+	tx.Payload = dec.uint64()
+	tx.Fee = dec.uint64()
+	tx.TxCnt = dec.byte()
+	dec.fixed(tx.Sig[:])
+
+	if !dec.ok() {
+		return nil
+	}
 
 	return tx
 }
@@ -0,0 +1,82 @@
+package protocol
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	CONTRACT_UPGRADE      = 0x01
+	CONTRACT_SELFDESTRUCT = 0x02
+)
+
+//A change of the contract of an account after its creation, either an upgrade by an AccTx of the issuer or the
+//self-destruct of the contract.
+type ContractHistoryEntry struct {
+	Account     [32]byte
+	Type        byte
+	BlockHeight uint32
+	TxHash      [32]byte //The AccTx of an upgrade, the FundsTx whose contract call self-destructed the contract
+	OldCode     [32]byte //Hash of the contract before the change
+	NewCode     [32]byte //Hash of the contract after the change, the hash of no code after a self-destruct
+	Beneficiary [32]byte //Self-destruct only: the account which received the balance
+	Amount      uint64   //Self-destruct only: the balance sent to the beneficiary
+}
+
+//Hash of contract code, as stored in the contract history.
+func CodeHash(code []byte) [32]byte {
+	return sha3.Sum256(code)
+}
+
+//Canonical encoding (see encoding.go) of the fields Account, Type, BlockHeight, TxHash, OldCode, NewCode, Beneficiary
+//and Amount.
+func (entry *ContractHistoryEntry) Encode() []byte {
+	if entry == nil {
+		return nil
+	}
+
+	enc := newEncoder(ENCODING_CONTRACTHISTORY)
+	enc.fixed(entry.Account[:])
+	enc.byte(entry.Type)
+	enc.uint32(entry.BlockHeight)
+	enc.fixed(entry.TxHash[:])
+	enc.fixed(entry.OldCode[:])
+	enc.fixed(entry.NewCode[:])
+	enc.fixed(entry.Beneficiary[:])
+	enc.uint64(entry.Amount)
+	return enc.Bytes()
+}
+
+func (*ContractHistoryEntry) Decode(encoded []byte) (entry *ContractHistoryEntry) {
+	dec := newDecoder(ENCODING_CONTRACTHISTORY, encoded)
+	if dec == nil {
+		return nil
+	}
+
+	entry = new(ContractHistoryEntry)
+	dec.fixed(entry.Account[:])
+	entry.Type = dec.byte()
+	entry.BlockHeight = dec.uint32()
+	dec.fixed(entry.TxHash[:])
+	dec.fixed(entry.OldCode[:])
+	dec.fixed(entry.NewCode[:])
+	dec.fixed(entry.Beneficiary[:])
+	entry.Amount = dec.uint64()
+
+	if !dec.ok() {
+		return nil
+	}
+
+	return entry
+}
+
+func (entry ContractHistoryEntry) String() string {
+	switch entry.Type {
+	case CONTRACT_UPGRADE:
+		return fmt.Sprintf("Height: %v, Upgrade (tx %x): %x -> %x", entry.BlockHeight, entry.TxHash[0:8], entry.OldCode[0:8], entry.NewCode[0:8])
+	case CONTRACT_SELFDESTRUCT:
+		return fmt.Sprintf("Height: %v, Self-destruct (tx %x): %x, %v to %x", entry.BlockHeight, entry.TxHash[0:8], entry.OldCode[0:8], entry.Amount, entry.Beneficiary[0:8])
+	}
+	return fmt.Sprintf("Height: %v, Unknown change %v (tx %x)", entry.BlockHeight, entry.Type, entry.TxHash[0:8])
+}
@@ -0,0 +1,338 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/willf/bloom"
+)
+
+//Canonical binary encoding of blocks, accounts, transactions and receipts.
+//
+//Every encoding starts with the version byte and a type byte, followed by the fields of the type in the order in
+//which they are listed at the type's Encode function:
+//  - integers are big-endian and of the field's size (uint8: 1 byte, uint16: 2 bytes, ..., int64: 8 bytes)
+//  - bools are a single byte, 0x00 or 0x01
+//  - fixed-size byte arrays (hashes, signatures, ...) are written as they are
+//  - byte slices are prefixed with their length as uint32
+//  - lists (of hashes or byte slices) are prefixed with their number of elements as uint32
+//
+//The encoding of a value is therefore unique and does not depend on Go. Data written before the canonical encoding
+//was introduced (gob, or the fixed-size encoding of ConfigTx and StakeTx) is still decoded. Gob data never starts with
+//a byte in the range 0x80-0xf7, hence the version byte.
+const (
+	ENCODING_VERSION = 0x81
+
+	ENCODING_FUNDSTX         = 0x01
+	ENCODING_ACCTX           = 0x02
+	ENCODING_CONFIGTX        = 0x03
+	ENCODING_STAKETX         = 0x04
+	ENCODING_AGGTX           = 0x05
+	ENCODING_BLOCK           = 0x06
+	ENCODING_ACCOUNT         = 0x07
+	ENCODING_RECEIPT         = 0x08
+	ENCODING_CONTRACTHISTORY = 0x09
+)
+
+type encoder struct {
+	buffer bytes.Buffer
+}
+
+func newEncoder(encodingType byte) *encoder {
+	enc := new(encoder)
+	enc.buffer.WriteByte(ENCODING_VERSION)
+	enc.buffer.WriteByte(encodingType)
+	return enc
+}
+
+func (enc *encoder) Bytes() []byte {
+	return enc.buffer.Bytes()
+}
+
+func (enc *encoder) byte(b byte) {
+	enc.buffer.WriteByte(b)
+}
+
+func (enc *encoder) bool(b bool) {
+	if b {
+		enc.buffer.WriteByte(1)
+	} else {
+		enc.buffer.WriteByte(0)
+	}
+}
+
+func (enc *encoder) uint16(i uint16) {
+	binary.Write(&enc.buffer, binary.BigEndian, i)
+}
+
+func (enc *encoder) uint32(i uint32) {
+	binary.Write(&enc.buffer, binary.BigEndian, i)
+}
+
+func (enc *encoder) uint64(i uint64) {
+	binary.Write(&enc.buffer, binary.BigEndian, i)
+}
+
+func (enc *encoder) int64(i int64) {
+	binary.Write(&enc.buffer, binary.BigEndian, i)
+}
+
+//Fixed-size data, without length.
+func (enc *encoder) fixed(data []byte) {
+	enc.buffer.Write(data)
+}
+
+func (enc *encoder) bytes(data []byte) {
+	enc.uint32(uint32(len(data)))
+	enc.buffer.Write(data)
+}
+
+func (enc *encoder) hashes(hashes [][32]byte) {
+	enc.uint32(uint32(len(hashes)))
+	for _, hash := range hashes {
+		enc.buffer.Write(hash[:])
+	}
+}
+
+func (enc *encoder) byteArrays(arrays []ByteArray) {
+	enc.uint32(uint32(len(arrays)))
+	for _, array := range arrays {
+		enc.bytes(array)
+	}
+}
+
+//A nil bloom filter is encoded as empty byte slice.
+func (enc *encoder) bloomFilter(filter *bloom.BloomFilter) {
+	enc.bytes(encodeBloomFilter(filter))
+}
+
+func encodeBloomFilter(filter *bloom.BloomFilter) []byte {
+	if filter == nil {
+		return nil
+	}
+
+	var encoded bytes.Buffer
+	filter.WriteTo(&encoded)
+	return encoded.Bytes()
+}
+
+//The filter's size m and k are followed by the bit set's length (which equals m) and its 64 bit words. The length is
+//checked before decoding, the bit set is allocated with it.
+func decodeBloomFilter(encoded []byte) *bloom.BloomFilter {
+	if len(encoded) < 24 || len(encoded)%8 != 0 {
+		return nil
+	}
+
+	m, length := binary.BigEndian.Uint64(encoded[0:8]), binary.BigEndian.Uint64(encoded[16:24])
+	words := length / 64
+	if length%64 != 0 {
+		words++
+	}
+	if m == 0 || m != length || words != uint64(len(encoded)-24)/8 {
+		return nil
+	}
+
+	filter := new(bloom.BloomFilter)
+	if n, err := filter.ReadFrom(bytes.NewReader(encoded)); err != nil || n != int64(len(encoded)) {
+		return nil
+	}
+
+	return filter
+}
+
+//The decoder stops at the first malformed field, ok() tells whether all fields could be read.
+type decoder struct {
+	data   []byte
+	failed bool
+}
+
+//Returns nil if the data is not in the canonical encoding of the given type.
+func newDecoder(encodingType byte, data []byte) *decoder {
+	if !isCanonicalEncoding(data) || data[1] != encodingType {
+		return nil
+	}
+
+	return &decoder{data: data[2:]}
+}
+
+func isCanonicalEncoding(data []byte) bool {
+	return len(data) >= 2 && data[0] == ENCODING_VERSION
+}
+
+//All data must have been consumed, trailing bytes make the encoding ambiguous.
+func (dec *decoder) ok() bool {
+	return !dec.failed && len(dec.data) == 0
+}
+
+func (dec *decoder) next(n uint64) []byte {
+	if dec.failed || uint64(len(dec.data)) < n {
+		dec.failed = true
+		return nil
+	}
+
+	next := dec.data[:n]
+	dec.data = dec.data[n:]
+	return next
+}
+
+func (dec *decoder) byte() byte {
+	if next := dec.next(1); len(next) == 1 {
+		return next[0]
+	}
+	return 0
+}
+
+func (dec *decoder) bool() bool {
+	switch dec.byte() {
+	case 0:
+		return false
+	case 1:
+		return true
+	default:
+		dec.failed = true
+		return false
+	}
+}
+
+func (dec *decoder) uint16() uint16 {
+	if next := dec.next(2); len(next) == 2 {
+		return binary.BigEndian.Uint16(next)
+	}
+	return 0
+}
+
+func (dec *decoder) uint32() uint32 {
+	if next := dec.next(4); len(next) == 4 {
+		return binary.BigEndian.Uint32(next)
+	}
+	return 0
+}
+
+func (dec *decoder) uint64() uint64 {
+	if next := dec.next(8); len(next) == 8 {
+		return binary.BigEndian.Uint64(next)
+	}
+	return 0
+}
+
+func (dec *decoder) int64() int64 {
+	return int64(dec.uint64())
+}
+
+func (dec *decoder) fixed(data []byte) {
+	copy(data, dec.next(uint64(len(data))))
+}
+
+//Empty byte slices are decoded as nil, like gob does.
+func (dec *decoder) bytes() []byte {
+	length := uint64(dec.uint32())
+	if dec.failed || length == 0 {
+		return nil
+	}
+
+	next := dec.next(length)
+	if dec.failed {
+		return nil
+	}
+
+	data := make([]byte, length)
+	copy(data, next)
+	return data
+}
+
+func (dec *decoder) hashes() (hashes [][32]byte) {
+	count := uint64(dec.uint32())
+	if dec.failed || count*32 > uint64(len(dec.data)) {
+		dec.failed = true
+		return nil
+	}
+
+	for i := uint64(0); i < count; i++ {
+		var hash [32]byte
+		dec.fixed(hash[:])
+		hashes = append(hashes, hash)
+	}
+
+	return hashes
+}
+
+func (dec *decoder) byteArrays() (arrays []ByteArray) {
+	//Every element has at least its length prefix.
+	count := uint64(dec.uint32())
+	if dec.failed || count*4 > uint64(len(dec.data)) {
+		dec.failed = true
+		return nil
+	}
+
+	for i := uint64(0); i < count && !dec.failed; i++ {
+		arrays = append(arrays, ByteArray(dec.bytes()))
+	}
+
+	return arrays
+}
+
+func (dec *decoder) bloomFilter() *bloom.BloomFilter {
+	encoded := dec.bytes()
+	if dec.failed || encoded == nil {
+		return nil
+	}
+
+	filter := decodeBloomFilter(encoded)
+	if filter == nil {
+		dec.failed = true
+	}
+
+	return filter
+}
+
+//Decodes a transaction of any type from its canonical encoding. Legacy config and stake txs are recognized by their
+//fixed size, other legacy encodings don't tell the transaction type and return nil.
+func DecodeTx(encoded []byte) Transaction {
+	if !isCanonicalEncoding(encoded) {
+		return decodeLegacyTx(encoded)
+	}
+
+	//The typed nil pointers of failed decodings must not end up in the interface.
+	switch encoded[1] {
+	case ENCODING_FUNDSTX:
+		var tx *FundsTx
+		if tx = tx.Decode(encoded); tx != nil {
+			return tx
+		}
+	case ENCODING_ACCTX:
+		var tx *AccTx
+		if tx = tx.Decode(encoded); tx != nil {
+			return tx
+		}
+	case ENCODING_CONFIGTX:
+		var tx *ConfigTx
+		if tx = tx.Decode(encoded); tx != nil {
+			return tx
+		}
+	case ENCODING_STAKETX:
+		var tx *StakeTx
+		if tx = tx.Decode(encoded); tx != nil {
+			return tx
+		}
+	case ENCODING_AGGTX:
+		var tx *AggTx
+		if tx = tx.Decode(encoded); tx != nil {
+			return tx
+		}
+	}
+
+	//A legacy config or stake tx may have a header that equals the version byte.
+	return decodeLegacyTx(encoded)
+}
+
+func decodeLegacyTx(encoded []byte) Transaction {
+	switch len(encoded) {
+	case CONFIGTX_SIZE:
+		var tx *ConfigTx
+		return tx.Decode(encoded)
+	case STAKETX_SIZE:
+		var tx *StakeTx
+		return tx.Decode(encoded)
+	}
+
+	return nil
+}
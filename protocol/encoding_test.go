@@ -0,0 +1,272 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/willf/bloom"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+//Fills data with a recognizable pattern, such that the golden vectors stay readable.
+func fill(data []byte, start byte) {
+	for i := range data {
+		data[i] = start + byte(i)
+	}
+}
+
+func encodingTestValues() map[string]interface{} {
+	fundsTx := &FundsTx{Header: 0x01, Amount: 1000, Fee: 1, TxCnt: 7, Aggregated: true, Data: []byte("data")}
+	fill(fundsTx.From[:], 0x10)
+	fill(fundsTx.To[:], 0x20)
+	fill(fundsTx.Sig1[:], 0x30)
+	fill(fundsTx.Sig2[:], 0x40)
+	fill(fundsTx.Block[:], 0x50)
+
+	accTx := &AccTx{Header: 0x02, Fee: 2, Contract: []byte{0x01, 0x02}, ContractVariables: []ByteArray{{0x03}, nil, {0x04, 0x05}}}
+	fill(accTx.Issuer[:], 0x10)
+	fill(accTx.PubKey[:], 0x20)
+	fill(accTx.Sig[:], 0x30)
+
+	configTx := &ConfigTx{Header: 0x03, Id: FEE_MINIMUM_ID, Payload: 5, Fee: 3, TxCnt: 2}
+	fill(configTx.Sig[:], 0x10)
+
+	stakeTx := &StakeTx{Header: 0x04, Fee: 4, IsStaking: true}
+	fill(stakeTx.Account[:], 0x10)
+	fill(stakeTx.Sig[:], 0x20)
+	fill(stakeTx.CommitmentKey[:], 0x30)
+
+	aggTx := &AggTx{Amount: 500, Fee: 5, From: [][32]byte{{0x01}}, To: [][32]byte{{0x02}, {0x03}}, AggregatedTxSlice: [][32]byte{{0x04}, {0x05}}}
+	fill(aggTx.Block[:], 0x10)
+	fill(aggTx.MerkleRoot[:], 0x20)
+
+	acc := &Account{Balance: 1000, TxCnt: 3, IsStaking: true, StakingBlockHeight: 10, Contract: []byte{0x01}, ContractVariables: []ByteArray{{0x02, 0x03}}}
+	fill(acc.Address[:], 0x10)
+	fill(acc.Issuer[:], 0x20)
+	fill(acc.CommitmentKey[:], 0x30)
+
+	block := &Block{Header: 0x05, NrConfigTx: 1, NrElementsBF: 2, Height: 42, Aggregated: true, Timestamp: 1500000000, NrAccTx: 1, NrFundsTx: 1, NrStakeTx: 1, NrAggTx: 1}
+	fill(block.Hash[:], 0x01)
+	fill(block.PrevHash[:], 0x02)
+	fill(block.HashWithoutTx[:], 0x03)
+	fill(block.PrevHashWithoutTx[:], 0x04)
+	fill(block.Beneficiary[:], 0x05)
+	fill(block.Nonce[:], 0x06)
+	fill(block.MerkleRoot[:], 0x07)
+	fill(block.StateRoot[:], 0x08)
+	fill(block.SlashedAddress[:], 0x09)
+	fill(block.CommitmentProof[:], 0x0a)
+	fill(block.ConflictingBlockHash1[:], 0x0b)
+	fill(block.ConflictingBlockHash2[:], 0x0c)
+	fill(block.ConflictingBlockHashWithoutTx1[:], 0x0d)
+	fill(block.ConflictingBlockHashWithoutTx2[:], 0x0e)
+	block.AccTxData = [][32]byte{accTx.Hash()}
+	block.FundsTxData = [][32]byte{fundsTx.Hash()}
+	block.ConfigTxData = [][32]byte{configTx.Hash()}
+	block.StakeTxData = [][32]byte{stakeTx.Hash()}
+	block.AggTxData = [][32]byte{aggTx.Hash()}
+	block.BloomFilter = bloom.New(64, 2)
+	block.BloomFilter.Add(fundsTx.From[:])
+
+	return map[string]interface{}{
+		"fundstx":  fundsTx,
+		"acctx":    accTx,
+		"configtx": configTx,
+		"staketx":  stakeTx,
+		"aggtx":    aggTx,
+		"account":  acc,
+		"block":    block,
+	}
+}
+
+func encode(value interface{}) []byte {
+	return value.(interface{ Encode() []byte }).Encode()
+}
+
+func decode(value interface{}, encoded []byte) interface{} {
+	switch value.(type) {
+	case *FundsTx:
+		var tx *FundsTx
+		return tx.Decode(encoded)
+	case *AccTx:
+		var tx *AccTx
+		return tx.Decode(encoded)
+	case *ConfigTx:
+		var tx *ConfigTx
+		return tx.Decode(encoded)
+	case *StakeTx:
+		var tx *StakeTx
+		return tx.Decode(encoded)
+	case *AggTx:
+		var tx *AggTx
+		return tx.Decode(encoded)
+	case *Account:
+		var acc *Account
+		return acc.Decode(encoded)
+	case *Block:
+		var block *Block
+		return block.Decode(encoded)
+	}
+	return nil
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	for name, value := range encodingTestValues() {
+		encoded := encode(value)
+		if encoded[0] != ENCODING_VERSION {
+			t.Errorf("%v: encoding does not start with the version byte: %x", name, encoded[0])
+		}
+
+		decoded := decode(value, encoded)
+		if !reflect.DeepEqual(value, decoded) {
+			t.Errorf("%v: encoding/decoding failed: %v vs. %v", name, value, decoded)
+		}
+
+		if !bytes.Equal(encoded, encode(decoded)) {
+			t.Errorf("%v: re-encoding the decoded value yields different data", name)
+		}
+	}
+}
+
+//The golden vectors in testdata/encoding pin the encoding down, clients in other languages can test against them.
+func TestEncodingGoldenVectors(t *testing.T) {
+	for name, value := range encodingTestValues() {
+		golden, err := ioutil.ReadFile(filepath.Join("testdata", "encoding", name+".hex"))
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+
+		expected, err := hex.DecodeString(strings.TrimSpace(string(golden)))
+		if err != nil {
+			t.Fatalf("%v: invalid golden vector: %v", name, err)
+		}
+
+		if encoded := encode(value); !bytes.Equal(encoded, expected) {
+			t.Errorf("%v: encoding does not match the golden vector:\n%x\nvs.\n%x", name, encoded, expected)
+		}
+
+		if decoded := decode(value, expected); !reflect.DeepEqual(value, decoded) {
+			t.Errorf("%v: golden vector decodes to %v", name, decoded)
+		}
+	}
+}
+
+func TestEncodingLegacy(t *testing.T) {
+	for name, value := range encodingTestValues() {
+		var legacy []byte
+		switch v := value.(type) {
+		case *ConfigTx:
+			legacy = make([]byte, CONFIGTX_SIZE)
+			legacy[0], legacy[1] = v.Header, v.Id
+			binary.BigEndian.PutUint64(legacy[2:10], v.Payload)
+			binary.BigEndian.PutUint64(legacy[10:18], v.Fee)
+			legacy[18] = v.TxCnt
+			copy(legacy[19:], v.Sig[:])
+		case *StakeTx:
+			legacy = make([]byte, STAKETX_SIZE)
+			legacy[0] = v.Header
+			binary.BigEndian.PutUint64(legacy[1:9], v.Fee)
+			legacy[9] = 1
+			copy(legacy[10:42], v.Account[:])
+			copy(legacy[42:106], v.Sig[:])
+			copy(legacy[106:106+crypto.COMM_KEY_LENGTH], v.CommitmentKey[:])
+		default:
+			buffer := new(bytes.Buffer)
+			if err := gob.NewEncoder(buffer).Encode(value); err != nil {
+				t.Fatalf("%v: %v", name, err)
+			}
+			legacy = buffer.Bytes()
+		}
+
+		if decoded := decode(value, legacy); !reflect.DeepEqual(value, decoded) {
+			t.Errorf("%v: legacy decoding failed: %v vs. %v", name, value, decoded)
+		}
+	}
+}
+
+func TestDecodeTxLegacy(t *testing.T) {
+	//Legacy txs whose header equals the version byte look like canonical encodings of other types.
+	configTx := &ConfigTx{Header: ENCODING_VERSION, Id: ENCODING_FUNDSTX, Payload: 5, Fee: 3, TxCnt: 2}
+	fill(configTx.Sig[:], 0x10)
+	legacyConfigTx := make([]byte, CONFIGTX_SIZE)
+	legacyConfigTx[0], legacyConfigTx[1] = configTx.Header, configTx.Id
+	binary.BigEndian.PutUint64(legacyConfigTx[2:10], configTx.Payload)
+	binary.BigEndian.PutUint64(legacyConfigTx[10:18], configTx.Fee)
+	legacyConfigTx[18] = configTx.TxCnt
+	copy(legacyConfigTx[19:], configTx.Sig[:])
+
+	stakeTx := &StakeTx{Header: ENCODING_VERSION, Fee: 4, IsStaking: true}
+	fill(stakeTx.Account[:], 0x10)
+	fill(stakeTx.Sig[:], 0x20)
+	fill(stakeTx.CommitmentKey[:], 0x30)
+	legacyStakeTx := make([]byte, STAKETX_SIZE)
+	legacyStakeTx[0] = stakeTx.Header
+	binary.BigEndian.PutUint64(legacyStakeTx[1:9], stakeTx.Fee)
+	legacyStakeTx[9] = 1
+	copy(legacyStakeTx[10:42], stakeTx.Account[:])
+	copy(legacyStakeTx[42:106], stakeTx.Sig[:])
+	copy(legacyStakeTx[106:106+crypto.COMM_KEY_LENGTH], stakeTx.CommitmentKey[:])
+
+	if tx := DecodeTx(legacyConfigTx); !reflect.DeepEqual(configTx, tx) {
+		t.Errorf("Legacy config tx decoding failed: %v vs. %v", configTx, tx)
+	}
+	if tx := DecodeTx(legacyStakeTx); !reflect.DeepEqual(stakeTx, tx) {
+		t.Errorf("Legacy stake tx decoding failed: %v vs. %v", stakeTx, tx)
+	}
+}
+
+func TestEncodingMalformed(t *testing.T) {
+	for name, value := range encodingTestValues() {
+		encoded := encode(value)
+
+		if decoded := decode(value, encoded[:len(encoded)-1]); !reflect.ValueOf(decoded).IsNil() {
+			t.Errorf("%v: truncated data has been decoded", name)
+		}
+
+		if decoded := decode(value, append(encoded, 0x00)); !reflect.ValueOf(decoded).IsNil() {
+			t.Errorf("%v: data with trailing bytes has been decoded", name)
+		}
+
+		wrongType := append([]byte{}, encoded...)
+		wrongType[1] = 0xff
+		if decoded := decode(value, wrongType); !reflect.ValueOf(decoded).IsNil() {
+			t.Errorf("%v: data of another type has been decoded", name)
+		}
+	}
+
+	//A length prefix beyond the end of the data must not be allocated.
+	enc := newEncoder(ENCODING_ACCOUNT)
+	enc.fixed(make([]byte, 64+32+8+4+1+crypto.COMM_KEY_LENGTH+4))
+	enc.uint32(0xffffffff)
+	var acc *Account
+	if acc = acc.Decode(enc.Bytes()); acc != nil {
+		t.Error("Account with an invalid contract length has been decoded")
+	}
+}
+
+func TestDecodeTx(t *testing.T) {
+	for name, value := range encodingTestValues() {
+		tx := DecodeTx(encode(value))
+		switch value.(type) {
+		case *Block, *Account:
+			if tx != nil {
+				t.Errorf("%v: decoded as transaction: %v", name, tx)
+			}
+		default:
+			if !reflect.DeepEqual(value, tx) {
+				t.Errorf("%v: decoding as transaction failed: %v vs. %v", name, value, tx)
+			}
+		}
+	}
+
+	encoded := encode(encodingTestValues()["fundstx"])
+	if tx := DecodeTx(encoded[:len(encoded)-1]); tx != nil {
+		t.Errorf("Truncated transaction has been decoded: %v", tx)
+	}
+}
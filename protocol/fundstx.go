@@ -91,34 +91,61 @@ func (tx *FundsTx) Hash() (hash [32]byte) {
 	return SerializeHashContent(txHash)
 }
 
-//when we serialize the struct with binary.Write, unexported field get serialized as well, undesired
-//behavior. Therefore, writing own encoder/decoder
+//Canonical encoding (see encoding.go) of the fields Header, Amount, Fee, TxCnt, From, To, Sig1, Sig2, Aggregated,
+//Block and Data.
 func (tx *FundsTx) Encode() (encodedTx []byte) {
-	// Encode
-	encodeData := FundsTx{
-		Header: 	tx.Header,
-		Amount: 	tx.Amount,
-		Fee:    	tx.Fee,
-		TxCnt:  	tx.TxCnt,
-		From:   	tx.From,
-		To:     	tx.To,
-		Sig1:   	tx.Sig1,
-		Sig2:   	tx.Sig2,
-		Data:   	tx.Data,
-		Aggregated: tx.Aggregated,
-		Block: 		tx.Block,
+	if tx == nil {
+		return nil
 	}
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encodeData)
-	return buffer.Bytes()
+
+	enc := newEncoder(ENCODING_FUNDSTX)
+	enc.byte(tx.Header)
+	enc.uint64(tx.Amount)
+	enc.uint64(tx.Fee)
+	enc.uint32(tx.TxCnt)
+	enc.fixed(tx.From[:])
+	enc.fixed(tx.To[:])
+	enc.fixed(tx.Sig1[:])
+	enc.fixed(tx.Sig2[:])
+	enc.bool(tx.Aggregated)
+	enc.fixed(tx.Block[:])
+	enc.bytes(tx.Data)
+	return enc.Bytes()
 }
 
 func (*FundsTx) Decode(encodedTx []byte) *FundsTx {
-	var decoded FundsTx
-	buffer := bytes.NewBuffer(encodedTx)
-	decoder := gob.NewDecoder(buffer)
-	decoder.Decode(&decoded)
-	return &decoded
+	if !isCanonicalEncoding(encodedTx) {
+		//Legacy gob encoding
+		var decoded FundsTx
+		buffer := bytes.NewBuffer(encodedTx)
+		decoder := gob.NewDecoder(buffer)
+		decoder.Decode(&decoded)
+		return &decoded
+	}
+
+	dec := newDecoder(ENCODING_FUNDSTX, encodedTx)
+	if dec == nil {
+		return nil
+	}
+
+	tx := new(FundsTx)
+	tx.Header = dec.byte()
+	tx.Amount = dec.uint64()
+	tx.Fee = dec.uint64()
+	tx.TxCnt = dec.uint32()
+	dec.fixed(tx.From[:])
+	dec.fixed(tx.To[:])
+	dec.fixed(tx.Sig1[:])
+	dec.fixed(tx.Sig2[:])
+	tx.Aggregated = dec.bool()
+	dec.fixed(tx.Block[:])
+	tx.Data = dec.bytes()
+
+	if !dec.ok() {
+		return nil
+	}
+
+	return tx
 }
 
 func (tx *FundsTx) TxFee() uint64 { return tx.Fee }
@@ -0,0 +1,513 @@
+package protocol
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+//JSON representation of blocks, accounts and transactions. All byte arrays and byte slices are hex encoded, lists
+//of hashes become lists of hex strings. The bloom filter of a block is hex encoded in its binary format (see
+//encodeBloomFilter), an empty string stands for no bloom filter. A block's StateCopy is local bookkeeping and is
+//not represented.
+
+type blockJSON struct {
+	Header                         byte     `json:"header"`
+	Hash                           string   `json:"hash"`
+	PrevHash                       string   `json:"prevHash"`
+	HashWithoutTx                  string   `json:"hashWithoutTx"`
+	PrevHashWithoutTx              string   `json:"prevHashWithoutTx"`
+	NrConfigTx                     uint8    `json:"nrConfigTx"`
+	NrElementsBF                   uint16   `json:"nrElementsBF"`
+	BloomFilter                    string   `json:"bloomFilter"`
+	Height                         uint32   `json:"height"`
+	Beneficiary                    string   `json:"beneficiary"`
+	Aggregated                     bool     `json:"aggregated"`
+	Nonce                          string   `json:"nonce"`
+	Timestamp                      int64    `json:"timestamp"`
+	MerkleRoot                     string   `json:"merkleRoot"`
+	StateRoot                      string   `json:"stateRoot"`
+	NrAccTx                        uint16   `json:"nrAccTx"`
+	NrFundsTx                      uint16   `json:"nrFundsTx"`
+	NrStakeTx                      uint16   `json:"nrStakeTx"`
+	NrAggTx                        uint16   `json:"nrAggTx"`
+	SlashedAddress                 string   `json:"slashedAddress"`
+	CommitmentProof                string   `json:"commitmentProof"`
+	ConflictingBlockHash1          string   `json:"conflictingBlockHash1"`
+	ConflictingBlockHash2          string   `json:"conflictingBlockHash2"`
+	ConflictingBlockHashWithoutTx1 string   `json:"conflictingBlockHashWithoutTx1"`
+	ConflictingBlockHashWithoutTx2 string   `json:"conflictingBlockHashWithoutTx2"`
+	AccTxData                      []string `json:"accTxData"`
+	FundsTxData                    []string `json:"fundsTxData"`
+	ConfigTxData                   []string `json:"configTxData"`
+	StakeTxData                    []string `json:"stakeTxData"`
+	AggTxData                      []string `json:"aggTxData"`
+}
+
+type accountJSON struct {
+	Address            string   `json:"address"`
+	Issuer             string   `json:"issuer"`
+	Balance            uint64   `json:"balance"`
+	TxCnt              uint32   `json:"txCnt"`
+	IsStaking          bool     `json:"isStaking"`
+	CommitmentKey      string   `json:"commitmentKey"`
+	StakingBlockHeight uint32   `json:"stakingBlockHeight"`
+	Contract           string   `json:"contract"`
+	ContractVariables  []string `json:"contractVariables"`
+}
+
+type fundsTxJSON struct {
+	Header     byte   `json:"header"`
+	Amount     uint64 `json:"amount"`
+	Fee        uint64 `json:"fee"`
+	TxCnt      uint32 `json:"txCnt"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Sig1       string `json:"sig1"`
+	Sig2       string `json:"sig2"`
+	Aggregated bool   `json:"aggregated"`
+	Block      string `json:"block"`
+	Data       string `json:"data"`
+}
+
+type accTxJSON struct {
+	Header            byte     `json:"header"`
+	Issuer            string   `json:"issuer"`
+	Fee               uint64   `json:"fee"`
+	PubKey            string   `json:"pubKey"`
+	Sig               string   `json:"sig"`
+	Contract          string   `json:"contract"`
+	ContractVariables []string `json:"contractVariables"`
+	LockHeight        uint32   `json:"lockHeight,omitempty"`
+}
+
+type configTxJSON struct {
+	Header  byte   `json:"header"`
+	Id      uint8  `json:"id"`
+	Payload uint64 `json:"payload"`
+	Fee     uint64 `json:"fee"`
+	TxCnt   uint8  `json:"txCnt"`
+	Sig     string `json:"sig"`
+}
+
+type stakeTxJSON struct {
+	Header        byte   `json:"header"`
+	Fee           uint64 `json:"fee"`
+	IsStaking     bool   `json:"isStaking"`
+	Account       string `json:"account"`
+	Sig           string `json:"sig"`
+	CommitmentKey string `json:"commitmentKey"`
+}
+
+type aggTxJSON struct {
+	Amount            uint64   `json:"amount"`
+	Fee               uint64   `json:"fee"`
+	From              []string `json:"from"`
+	To                []string `json:"to"`
+	AggregatedTxSlice []string `json:"aggregatedTxSlice"`
+	Aggregated        bool     `json:"aggregated"`
+	Block             string   `json:"block"`
+	MerkleRoot        string   `json:"merkleRoot"`
+}
+
+func (block Block) MarshalJSON() ([]byte, error) {
+	return json.Marshal(blockJSON{
+		Header:                         block.Header,
+		Hash:                           hex.EncodeToString(block.Hash[:]),
+		PrevHash:                       hex.EncodeToString(block.PrevHash[:]),
+		HashWithoutTx:                  hex.EncodeToString(block.HashWithoutTx[:]),
+		PrevHashWithoutTx:              hex.EncodeToString(block.PrevHashWithoutTx[:]),
+		NrConfigTx:                     block.NrConfigTx,
+		NrElementsBF:                   block.NrElementsBF,
+		BloomFilter:                    hex.EncodeToString(encodeBloomFilter(block.BloomFilter)),
+		Height:                         block.Height,
+		Beneficiary:                    hex.EncodeToString(block.Beneficiary[:]),
+		Aggregated:                     block.Aggregated,
+		Nonce:                          hex.EncodeToString(block.Nonce[:]),
+		Timestamp:                      block.Timestamp,
+		MerkleRoot:                     hex.EncodeToString(block.MerkleRoot[:]),
+		StateRoot:                      hex.EncodeToString(block.StateRoot[:]),
+		NrAccTx:                        block.NrAccTx,
+		NrFundsTx:                      block.NrFundsTx,
+		NrStakeTx:                      block.NrStakeTx,
+		NrAggTx:                        block.NrAggTx,
+		SlashedAddress:                 hex.EncodeToString(block.SlashedAddress[:]),
+		CommitmentProof:                hex.EncodeToString(block.CommitmentProof[:]),
+		ConflictingBlockHash1:          hex.EncodeToString(block.ConflictingBlockHash1[:]),
+		ConflictingBlockHash2:          hex.EncodeToString(block.ConflictingBlockHash2[:]),
+		ConflictingBlockHashWithoutTx1: hex.EncodeToString(block.ConflictingBlockHashWithoutTx1[:]),
+		ConflictingBlockHashWithoutTx2: hex.EncodeToString(block.ConflictingBlockHashWithoutTx2[:]),
+		AccTxData:                      hashesToHex(block.AccTxData),
+		FundsTxData:                    hashesToHex(block.FundsTxData),
+		ConfigTxData:                   hashesToHex(block.ConfigTxData),
+		StakeTxData:                    hashesToHex(block.StakeTxData),
+		AggTxData:                      hashesToHex(block.AggTxData),
+	})
+}
+
+func (block *Block) UnmarshalJSON(data []byte) error {
+	var decoded blockJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	dec := new(hexDecoder)
+	b := Block{
+		Header:       decoded.Header,
+		NrConfigTx:   decoded.NrConfigTx,
+		NrElementsBF: decoded.NrElementsBF,
+		Height:       decoded.Height,
+		Aggregated:   decoded.Aggregated,
+		Timestamp:    decoded.Timestamp,
+		NrAccTx:      decoded.NrAccTx,
+		NrFundsTx:    decoded.NrFundsTx,
+		NrStakeTx:    decoded.NrStakeTx,
+		NrAggTx:      decoded.NrAggTx,
+	}
+	dec.fixed("hash", decoded.Hash, b.Hash[:])
+	dec.fixed("prevHash", decoded.PrevHash, b.PrevHash[:])
+	dec.fixed("hashWithoutTx", decoded.HashWithoutTx, b.HashWithoutTx[:])
+	dec.fixed("prevHashWithoutTx", decoded.PrevHashWithoutTx, b.PrevHashWithoutTx[:])
+	if bloomFilter := dec.bytes("bloomFilter", decoded.BloomFilter); bloomFilter != nil {
+		if b.BloomFilter = decodeBloomFilter(bloomFilter); b.BloomFilter == nil {
+			dec.fail("bloomFilter", "invalid bloom filter")
+		}
+	}
+	dec.fixed("beneficiary", decoded.Beneficiary, b.Beneficiary[:])
+	dec.fixed("nonce", decoded.Nonce, b.Nonce[:])
+	dec.fixed("merkleRoot", decoded.MerkleRoot, b.MerkleRoot[:])
+	dec.fixed("stateRoot", decoded.StateRoot, b.StateRoot[:])
+	dec.fixed("slashedAddress", decoded.SlashedAddress, b.SlashedAddress[:])
+	dec.fixed("commitmentProof", decoded.CommitmentProof, b.CommitmentProof[:])
+	dec.fixed("conflictingBlockHash1", decoded.ConflictingBlockHash1, b.ConflictingBlockHash1[:])
+	dec.fixed("conflictingBlockHash2", decoded.ConflictingBlockHash2, b.ConflictingBlockHash2[:])
+	dec.fixed("conflictingBlockHashWithoutTx1", decoded.ConflictingBlockHashWithoutTx1, b.ConflictingBlockHashWithoutTx1[:])
+	dec.fixed("conflictingBlockHashWithoutTx2", decoded.ConflictingBlockHashWithoutTx2, b.ConflictingBlockHashWithoutTx2[:])
+	b.AccTxData = dec.hashes("accTxData", decoded.AccTxData)
+	b.FundsTxData = dec.hashes("fundsTxData", decoded.FundsTxData)
+	b.ConfigTxData = dec.hashes("configTxData", decoded.ConfigTxData)
+	b.StakeTxData = dec.hashes("stakeTxData", decoded.StakeTxData)
+	b.AggTxData = dec.hashes("aggTxData", decoded.AggTxData)
+
+	if dec.err != nil {
+		return dec.err
+	}
+
+	*block = b
+	return nil
+}
+
+func (acc Account) MarshalJSON() ([]byte, error) {
+	return json.Marshal(accountJSON{
+		Address:            hex.EncodeToString(acc.Address[:]),
+		Issuer:             hex.EncodeToString(acc.Issuer[:]),
+		Balance:            acc.Balance,
+		TxCnt:              acc.TxCnt,
+		IsStaking:          acc.IsStaking,
+		CommitmentKey:      hex.EncodeToString(acc.CommitmentKey[:]),
+		StakingBlockHeight: acc.StakingBlockHeight,
+		Contract:           hex.EncodeToString(acc.Contract),
+		ContractVariables:  byteArraysToHex(acc.ContractVariables),
+	})
+}
+
+func (acc *Account) UnmarshalJSON(data []byte) error {
+	var decoded accountJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	dec := new(hexDecoder)
+	a := Account{
+		Balance:            decoded.Balance,
+		TxCnt:              decoded.TxCnt,
+		IsStaking:          decoded.IsStaking,
+		StakingBlockHeight: decoded.StakingBlockHeight,
+	}
+	dec.fixed("address", decoded.Address, a.Address[:])
+	dec.fixed("issuer", decoded.Issuer, a.Issuer[:])
+	dec.fixed("commitmentKey", decoded.CommitmentKey, a.CommitmentKey[:])
+	a.Contract = dec.bytes("contract", decoded.Contract)
+	a.ContractVariables = dec.byteArrays("contractVariables", decoded.ContractVariables)
+
+	if dec.err != nil {
+		return dec.err
+	}
+
+	*acc = a
+	return nil
+}
+
+func (tx FundsTx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fundsTxJSON{
+		Header:     tx.Header,
+		Amount:     tx.Amount,
+		Fee:        tx.Fee,
+		TxCnt:      tx.TxCnt,
+		From:       hex.EncodeToString(tx.From[:]),
+		To:         hex.EncodeToString(tx.To[:]),
+		Sig1:       hex.EncodeToString(tx.Sig1[:]),
+		Sig2:       hex.EncodeToString(tx.Sig2[:]),
+		Aggregated: tx.Aggregated,
+		Block:      hex.EncodeToString(tx.Block[:]),
+		Data:       hex.EncodeToString(tx.Data),
+	})
+}
+
+func (tx *FundsTx) UnmarshalJSON(data []byte) error {
+	var decoded fundsTxJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	dec := new(hexDecoder)
+	t := FundsTx{
+		Header:     decoded.Header,
+		Amount:     decoded.Amount,
+		Fee:        decoded.Fee,
+		TxCnt:      decoded.TxCnt,
+		Aggregated: decoded.Aggregated,
+	}
+	dec.fixed("from", decoded.From, t.From[:])
+	dec.fixed("to", decoded.To, t.To[:])
+	dec.fixed("sig1", decoded.Sig1, t.Sig1[:])
+	dec.fixed("sig2", decoded.Sig2, t.Sig2[:])
+	dec.fixed("block", decoded.Block, t.Block[:])
+	t.Data = dec.bytes("data", decoded.Data)
+
+	if dec.err != nil {
+		return dec.err
+	}
+
+	*tx = t
+	return nil
+}
+
+func (tx AccTx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(accTxJSON{
+		Header:            tx.Header,
+		Issuer:            hex.EncodeToString(tx.Issuer[:]),
+		Fee:               tx.Fee,
+		PubKey:            hex.EncodeToString(tx.PubKey[:]),
+		Sig:               hex.EncodeToString(tx.Sig[:]),
+		Contract:          hex.EncodeToString(tx.Contract),
+		ContractVariables: byteArraysToHex(tx.ContractVariables),
+		LockHeight:        tx.LockHeight,
+	})
+}
+
+func (tx *AccTx) UnmarshalJSON(data []byte) error {
+	var decoded accTxJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	dec := new(hexDecoder)
+	t := AccTx{
+		Header:     decoded.Header,
+		Fee:        decoded.Fee,
+		LockHeight: decoded.LockHeight,
+	}
+	dec.fixed("issuer", decoded.Issuer, t.Issuer[:])
+	dec.fixed("pubKey", decoded.PubKey, t.PubKey[:])
+	dec.fixed("sig", decoded.Sig, t.Sig[:])
+	t.Contract = dec.bytes("contract", decoded.Contract)
+	t.ContractVariables = dec.byteArrays("contractVariables", decoded.ContractVariables)
+
+	if dec.err != nil {
+		return dec.err
+	}
+
+	*tx = t
+	return nil
+}
+
+func (tx ConfigTx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(configTxJSON{
+		Header:  tx.Header,
+		Id:      tx.Id,
+		Payload: tx.Payload,
+		Fee:     tx.Fee,
+		TxCnt:   tx.TxCnt,
+		Sig:     hex.EncodeToString(tx.Sig[:]),
+	})
+}
+
+func (tx *ConfigTx) UnmarshalJSON(data []byte) error {
+	var decoded configTxJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	dec := new(hexDecoder)
+	t := ConfigTx{
+		Header:  decoded.Header,
+		Id:      decoded.Id,
+		Payload: decoded.Payload,
+		Fee:     decoded.Fee,
+		TxCnt:   decoded.TxCnt,
+	}
+	dec.fixed("sig", decoded.Sig, t.Sig[:])
+
+	if dec.err != nil {
+		return dec.err
+	}
+
+	*tx = t
+	return nil
+}
+
+func (tx StakeTx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stakeTxJSON{
+		Header:        tx.Header,
+		Fee:           tx.Fee,
+		IsStaking:     tx.IsStaking,
+		Account:       hex.EncodeToString(tx.Account[:]),
+		Sig:           hex.EncodeToString(tx.Sig[:]),
+		CommitmentKey: hex.EncodeToString(tx.CommitmentKey[:]),
+	})
+}
+
+func (tx *StakeTx) UnmarshalJSON(data []byte) error {
+	var decoded stakeTxJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	dec := new(hexDecoder)
+	t := StakeTx{
+		Header:    decoded.Header,
+		Fee:       decoded.Fee,
+		IsStaking: decoded.IsStaking,
+	}
+	dec.fixed("account", decoded.Account, t.Account[:])
+	dec.fixed("sig", decoded.Sig, t.Sig[:])
+	dec.fixed("commitmentKey", decoded.CommitmentKey, t.CommitmentKey[:])
+
+	if dec.err != nil {
+		return dec.err
+	}
+
+	*tx = t
+	return nil
+}
+
+func (tx AggTx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(aggTxJSON{
+		Amount:            tx.Amount,
+		Fee:               tx.Fee,
+		From:              hashesToHex(tx.From),
+		To:                hashesToHex(tx.To),
+		AggregatedTxSlice: hashesToHex(tx.AggregatedTxSlice),
+		Aggregated:        tx.Aggregated,
+		Block:             hex.EncodeToString(tx.Block[:]),
+		MerkleRoot:        hex.EncodeToString(tx.MerkleRoot[:]),
+	})
+}
+
+func (tx *AggTx) UnmarshalJSON(data []byte) error {
+	var decoded aggTxJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	dec := new(hexDecoder)
+	t := AggTx{
+		Amount:     decoded.Amount,
+		Fee:        decoded.Fee,
+		Aggregated: decoded.Aggregated,
+	}
+	t.From = dec.hashes("from", decoded.From)
+	t.To = dec.hashes("to", decoded.To)
+	t.AggregatedTxSlice = dec.hashes("aggregatedTxSlice", decoded.AggregatedTxSlice)
+	dec.fixed("block", decoded.Block, t.Block[:])
+	dec.fixed("merkleRoot", decoded.MerkleRoot, t.MerkleRoot[:])
+
+	if dec.err != nil {
+		return dec.err
+	}
+
+	*tx = t
+	return nil
+}
+
+func hashesToHex(hashes [][32]byte) []string {
+	encoded := make([]string, len(hashes))
+	for i, hash := range hashes {
+		encoded[i] = hex.EncodeToString(hash[:])
+	}
+
+	return encoded
+}
+
+func byteArraysToHex(arrays []ByteArray) []string {
+	encoded := make([]string, len(arrays))
+	for i, array := range arrays {
+		encoded[i] = hex.EncodeToString(array)
+	}
+
+	return encoded
+}
+
+//Keeps the first error, such that all fields can be decoded in a row and checked once.
+type hexDecoder struct {
+	err error
+}
+
+func (dec *hexDecoder) fail(field string, reason string) {
+	if dec.err == nil {
+		dec.err = errors.New(fmt.Sprintf("Invalid %v: %v", field, reason))
+	}
+}
+
+//Fixed-size fields must have the exact length, an empty string stands for all zeros.
+func (dec *hexDecoder) fixed(field string, encoded string, data []byte) {
+	if encoded == "" {
+		return
+	}
+
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil {
+		dec.fail(field, err.Error())
+		return
+	}
+	if len(decoded) != len(data) {
+		dec.fail(field, fmt.Sprintf("expected %v bytes but got %v", len(data), len(decoded)))
+		return
+	}
+
+	copy(data, decoded)
+}
+
+//Empty byte slices are decoded as nil, like the binary encoding does.
+func (dec *hexDecoder) bytes(field string, encoded string) []byte {
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil {
+		dec.fail(field, err.Error())
+		return nil
+	}
+	if len(decoded) == 0 {
+		return nil
+	}
+
+	return decoded
+}
+
+func (dec *hexDecoder) hashes(field string, encoded []string) (hashes [][32]byte) {
+	for _, encodedHash := range encoded {
+		var hash [32]byte
+		dec.fixed(field, encodedHash, hash[:])
+		hashes = append(hashes, hash)
+	}
+
+	return hashes
+}
+
+func (dec *hexDecoder) byteArrays(field string, encoded []string) (arrays []ByteArray) {
+	for _, encodedArray := range encoded {
+		arrays = append(arrays, ByteArray(dec.bytes(field, encodedArray)))
+	}
+
+	return arrays
+}
@@ -0,0 +1,90 @@
+package protocol
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	for name, value := range encodingTestValues() {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+
+		decoded := reflect.New(reflect.TypeOf(value).Elem()).Interface()
+		if err := json.Unmarshal(encoded, decoded); err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+
+		if !reflect.DeepEqual(value, decoded) {
+			t.Errorf("%v: JSON marshalling/unmarshalling failed: %v vs. %v", name, value, decoded)
+		}
+	}
+}
+
+func TestJSONFields(t *testing.T) {
+	tx := encodingTestValues()["fundstx"].(*FundsTx)
+
+	encoded, _ := json.Marshal(tx)
+	var fields map[string]interface{}
+	json.Unmarshal(encoded, &fields)
+
+	if fields["from"] != hex.EncodeToString(tx.From[:]) {
+		t.Errorf("Expected from '%x' but was '%v'", tx.From, fields["from"])
+	}
+	if fields["amount"] != float64(tx.Amount) {
+		t.Errorf("Expected amount '%v' but was '%v'", tx.Amount, fields["amount"])
+	}
+	if fields["data"] != hex.EncodeToString(tx.Data) {
+		t.Errorf("Expected data '%x' but was '%v'", tx.Data, fields["data"])
+	}
+
+	//Blocks are marshalled as values as well, e.g., when they are part of another struct.
+	block := *encodingTestValues()["block"].(*Block)
+	block.StateCopy = map[[32]byte]*Account{{}: new(Account)}
+	encoded, _ = json.Marshal(struct{ Block Block }{block})
+	if !strings.Contains(string(encoded), `"stateRoot":"`+hex.EncodeToString(block.StateRoot[:])+`"`) {
+		t.Errorf("Block has not been marshalled with hex encoded fields: %s", encoded)
+	}
+	if strings.Contains(strings.ToLower(string(encoded)), "statecopy") {
+		t.Errorf("StateCopy of a block has been marshalled: %s", encoded)
+	}
+}
+
+func TestJSONBlockWithoutBloomFilter(t *testing.T) {
+	block := &Block{PrevHash: [32]byte{1}, Height: 1}
+
+	encoded, _ := json.Marshal(block)
+	decoded := new(Block)
+	if err := json.Unmarshal(encoded, decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.BloomFilter != nil || !reflect.DeepEqual(block, decoded) {
+		t.Errorf("JSON marshalling/unmarshalling of a block without bloom filter failed: %v vs. %v", block, decoded)
+	}
+}
+
+func TestJSONInvalid(t *testing.T) {
+	invalid := map[string]string{
+		"not hex":       `{"from":"xyz"}`,
+		"wrong length":  `{"from":"0102"}`,
+		"invalid slice": `{"data":"0"}`,
+	}
+
+	for name, encoded := range invalid {
+		tx := new(FundsTx)
+		if err := json.Unmarshal([]byte(encoded), tx); err == nil {
+			t.Errorf("%v: invalid JSON has been unmarshalled: %v", name, encoded)
+		}
+	}
+
+	block := new(Block)
+	if err := json.Unmarshal([]byte(`{"bloomFilter":"0102"}`), block); err == nil {
+		t.Error("Block with an invalid bloom filter has been unmarshalled")
+	}
+}
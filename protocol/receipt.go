@@ -0,0 +1,95 @@
+package protocol
+
+import (
+	"bytes"
+	"fmt"
+)
+
+//An event emitted by a contract with the LOG opcodes. Account is the contract which emitted it, which differs from
+//the tx's receiver if the event was emitted by a contract called with CALLEXT.
+type Event struct {
+	Account [32]byte
+	Topics  []ByteArray
+	Data    ByteArray
+}
+
+//Outcome of the contract execution of a tx. A failed execution invalidates the block, receipts are therefore only
+//written for successful executions.
+type Receipt struct {
+	TxHash  [32]byte
+	GasUsed uint64
+	Events  []Event
+}
+
+//Returns whether one of the events has the topic, at any position.
+func (receipt *Receipt) HasTopic(topic []byte) bool {
+	for _, event := range receipt.Events {
+		for _, eventTopic := range event.Topics {
+			if bytes.Equal(eventTopic, topic) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+//Canonical encoding (see encoding.go) of the fields TxHash, GasUsed and Events. Every event is
+//encoded as Account, Topics and Data, prefixed with the number of events.
+func (receipt *Receipt) Encode() []byte {
+	if receipt == nil {
+		return nil
+	}
+
+	enc := newEncoder(ENCODING_RECEIPT)
+	enc.fixed(receipt.TxHash[:])
+	enc.uint64(receipt.GasUsed)
+	enc.uint32(uint32(len(receipt.Events)))
+	for _, event := range receipt.Events {
+		enc.fixed(event.Account[:])
+		enc.byteArrays(event.Topics)
+		enc.bytes(event.Data)
+	}
+	return enc.Bytes()
+}
+
+func (*Receipt) Decode(encoded []byte) (receipt *Receipt) {
+	dec := newDecoder(ENCODING_RECEIPT, encoded)
+	if dec == nil {
+		return nil
+	}
+
+	receipt = new(Receipt)
+	dec.fixed(receipt.TxHash[:])
+	receipt.GasUsed = dec.uint64()
+
+	//Every event has at least its account and two length prefixes.
+	count := uint64(dec.uint32())
+	if dec.failed || count*40 > uint64(len(dec.data)) {
+		return nil
+	}
+	for i := uint64(0); i < count; i++ {
+		var event Event
+		dec.fixed(event.Account[:])
+		event.Topics = dec.byteArrays()
+		event.Data = dec.bytes()
+		receipt.Events = append(receipt.Events, event)
+	}
+
+	if !dec.ok() {
+		return nil
+	}
+
+	return receipt
+}
+
+func (receipt Receipt) String() string {
+	return fmt.Sprintf(
+		"TxHash: %x, "+
+			"GasUsed: %v, "+
+			"Events: %v",
+		receipt.TxHash[0:8],
+		receipt.GasUsed,
+		len(receipt.Events),
+	)
+}
@@ -0,0 +1,73 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReceiptSerialization(t *testing.T) {
+	receipt := &Receipt{
+		TxHash:  [32]byte{1},
+		GasUsed: 1234,
+		Events: []Event{
+			{Account: [32]byte{2}, Topics: []ByteArray{{'a'}, {'b', 'c'}}, Data: ByteArray{1, 2, 3}},
+			{Account: [32]byte{3}},
+		},
+	}
+
+	var decoded *Receipt
+	decoded = decoded.Decode(receipt.Encode())
+	if !reflect.DeepEqual(receipt, decoded) {
+		t.Errorf("Receipt encoding/decoding failed: %v vs. %v", receipt, decoded)
+	}
+
+	empty := &Receipt{TxHash: [32]byte{4}, GasUsed: 10}
+	if decoded = decoded.Decode(empty.Encode()); !reflect.DeepEqual(empty, decoded) {
+		t.Errorf("Receipt encoding/decoding failed: %v vs. %v", empty, decoded)
+	}
+
+	encoded := receipt.Encode()
+	for _, invalid := range [][]byte{encoded[:len(encoded)-1], append(encoded, 0), (&Account{}).Encode()} {
+		if decoded = decoded.Decode(invalid); decoded != nil {
+			t.Errorf("Invalid encoding has been decoded to %v", decoded)
+		}
+	}
+}
+
+func TestReceiptHasTopic(t *testing.T) {
+	receipt := &Receipt{Events: []Event{{Topics: []ByteArray{{'a'}}}, {Topics: []ByteArray{{'b'}, {'c'}}}}}
+
+	for _, topic := range []string{"a", "b", "c"} {
+		if !receipt.HasTopic([]byte(topic)) {
+			t.Errorf("Expected receipt to have topic %v", topic)
+		}
+	}
+	if receipt.HasTopic([]byte("d")) {
+		t.Errorf("Expected receipt not to have topic d")
+	}
+}
+
+func TestContractHistoryEntrySerialization(t *testing.T) {
+	entry := &ContractHistoryEntry{
+		Account:     [32]byte{1},
+		Type:        CONTRACT_SELFDESTRUCT,
+		BlockHeight: 42,
+		TxHash:      [32]byte{2},
+		OldCode:     CodeHash([]byte{3}),
+		NewCode:     CodeHash(nil),
+		Beneficiary: [32]byte{4},
+		Amount:      500,
+	}
+
+	var decoded *ContractHistoryEntry
+	if decoded = decoded.Decode(entry.Encode()); !reflect.DeepEqual(entry, decoded) {
+		t.Errorf("Contract history encoding/decoding failed: %v vs. %v", entry, decoded)
+	}
+
+	encoded := entry.Encode()
+	for _, invalid := range [][]byte{encoded[:len(encoded)-1], append(encoded, 0), (&Receipt{}).Encode()} {
+		if decoded = decoded.Decode(invalid); decoded != nil {
+			t.Errorf("Invalid encoding has been decoded to %v", decoded)
+		}
+	}
+}
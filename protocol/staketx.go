@@ -71,56 +71,51 @@ func (tx *StakeTx) Hash() (hash [32]byte) {
 	return SerializeHashContent(txHash)
 }
 
-//when we serialize the struct with binary.Write, unexported field get serialized as well, undesired
-//behavior. Therefore, writing own encoder/decoder
+//Canonical encoding (see encoding.go) of the fields Header, Fee, IsStaking, Account, Sig and CommitmentKey.
 func (tx *StakeTx) Encode() (encodedTx []byte) {
 	if tx == nil {
 		return nil
 	}
 
-	var fee [8]byte
-	var isStaking byte
-
-	binary.BigEndian.PutUint64(fee[:], tx.Fee)
-
-	if tx.IsStaking == true {
-		isStaking = 1
-	} else {
-		isStaking = 0
-	}
-
-	encodedTx = make([]byte, STAKETX_SIZE)
-
-	encodedTx[0] = tx.Header
-	copy(encodedTx[1:9], fee[:])
-	encodedTx[9] = isStaking
-	copy(encodedTx[10:42], tx.Account[:])
-	copy(encodedTx[42:106], tx.Sig[:])
-	copy(encodedTx[106:106+crypto.COMM_KEY_LENGTH], tx.CommitmentKey[:])
-
-	return encodedTx
+	enc := newEncoder(ENCODING_STAKETX)
+	enc.byte(tx.Header)
+	enc.uint64(tx.Fee)
+	enc.bool(tx.IsStaking)
+	enc.fixed(tx.Account[:])
+	enc.fixed(tx.Sig[:])
+	enc.fixed(tx.CommitmentKey[:])
+	return enc.Bytes()
 }
 
 func (*StakeTx) Decode(encodedTx []byte) (tx *StakeTx) {
 	tx = new(StakeTx)
 
-	if len(encodedTx) != STAKETX_SIZE {
-		return nil
+	//Legacy encoding with fixed size, the canonical encoding is never of that size, even if the header equals
+	//the version byte.
+	if len(encodedTx) == STAKETX_SIZE {
+		tx.Header = encodedTx[0]
+		tx.Fee = binary.BigEndian.Uint64(encodedTx[1:9])
+		tx.IsStaking = encodedTx[9] != 0
+		copy(tx.Account[:], encodedTx[10:42])
+		copy(tx.Sig[:], encodedTx[42:106])
+		copy(tx.CommitmentKey[:], encodedTx[106:106+crypto.COMM_KEY_LENGTH])
+		return tx
 	}
 
-	var isStakingAsByte byte
+	dec := newDecoder(ENCODING_STAKETX, encodedTx)
+	if dec == nil {
+		return nil
+	}
 
-	tx.Header = encodedTx[0]
-	tx.Fee = binary.BigEndian.Uint64(encodedTx[1:9])
-	isStakingAsByte = encodedTx[9]
-	copy(tx.Account[:], encodedTx[10:42])
-	copy(tx.Sig[:], encodedTx[42:106])
-	copy(tx.CommitmentKey[:], encodedTx[106:106+crypto.COMM_KEY_LENGTH])
+	tx.Header = dec.byte()
+	tx.Fee = dec.uint64()
+	tx.IsStaking = dec.bool()
+	dec.fixed(tx.Account[:])
+	dec.fixed(tx.Sig[:])
+	dec.fixed(tx.CommitmentKey[:])
 
-	if isStakingAsByte == 0 {
-		tx.IsStaking = false
-	} else {
-		tx.IsStaking = true
+	if !dec.ok() {
+		return nil
 	}
 
 	return tx
@@ -0,0 +1,239 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	STATE_TREE_DEPTH  = 256
+	STATE_LEAF_PREFIX = 0x00
+	STATE_NODE_PREFIX = 0x01
+)
+
+//StateTree is a sparse Merkle tree over all accounts, keyed by the account hash. Conceptually every account sits at
+//depth 256, but a subtree which contains a single account is replaced by the account's leaf and an empty subtree
+//hashes to the zero hash. The tree therefore only grows with the number of accounts, not with the key length.
+//
+//Trees are immutable, Update returns a new tree which shares the unchanged subtrees with the original. The tree of a
+//state can thus be updated with the accounts a block changed, instead of being built from all accounts again.
+type StateTree struct {
+	root *stateNode
+}
+
+//A node is either the leaf of an account or an inner node, nil children are empty subtrees. Leaves keep the encoded
+//account, such that proofs return the account as it was in the tree's state.
+type stateNode struct {
+	leaf        bool
+	key         [32]byte
+	account     []byte
+	left, right *stateNode
+	hash        [32]byte
+}
+
+func BuildStateTree(state map[[32]byte]*Account) *StateTree {
+	var keys [][32]byte
+	for hash := range state {
+		keys = append(keys, hash)
+	}
+
+	return new(StateTree).Update(state, keys)
+}
+
+//Returns the tree in which the accounts with the given keys are replaced by their accounts in the state. Accounts
+//which are not in the state (anymore) are removed from the tree.
+func (tree *StateTree) Update(state map[[32]byte]*Account, keys [][32]byte) *StateTree {
+	root := tree.root
+	for _, key := range keys {
+		if acc := state[key]; acc != nil {
+			root = insertStateLeaf(root, &stateNode{leaf: true, key: key, account: acc.Encode(), hash: stateLeafHash(key, acc)}, 0)
+		} else {
+			root = removeStateLeaf(root, key, 0)
+		}
+	}
+
+	return &StateTree{root: root}
+}
+
+//Membership proof of an account in the state tree of a block. A light client which trusts the block header can
+//verify the account with VerifyAccountProof, without trusting the miner which sent the proof.
+type AccountProof struct {
+	BlockHash [32]byte
+	Height    uint32
+	Account   *Account
+	Siblings  [][32]byte //From the root down to the account's leaf
+}
+
+func (tree *StateTree) Root() [32]byte {
+	return tree.root.subtreeHash()
+}
+
+//Returns the account in the tree and the sibling hashes on the path from the root to the account's leaf.
+func (tree *StateTree) Proof(key [32]byte) (acc *Account, siblings [][32]byte, err error) {
+	node := tree.root
+	for depth := 0; node != nil && !node.leaf; depth++ {
+		if bitAt(key, depth) == 0 {
+			siblings = append(siblings, node.right.subtreeHash())
+			node = node.left
+		} else {
+			siblings = append(siblings, node.left.subtreeHash())
+			node = node.right
+		}
+	}
+
+	if node == nil || node.key != key {
+		return nil, nil, errors.New(fmt.Sprintf("Account (%x) is not in the state tree.", key[0:8]))
+	}
+
+	return acc.Decode(node.account), siblings, nil
+}
+
+//Checks that the proof's account is part of the state with the given root (e.g., the StateRoot of a block header).
+func VerifyAccountProof(stateRoot [32]byte, proof *AccountProof) error {
+	if proof == nil || proof.Account == nil {
+		return errors.New("Proof does not contain an account.")
+	}
+	if len(proof.Siblings) > STATE_TREE_DEPTH {
+		return errors.New("Proof is longer than the depth of the state tree.")
+	}
+
+	key := proof.Account.Hash()
+	hash := stateLeafHash(key, proof.Account)
+	for depth := len(proof.Siblings) - 1; depth >= 0; depth-- {
+		if bitAt(key, depth) == 0 {
+			hash = stateNodeHash(hash, proof.Siblings[depth])
+		} else {
+			hash = stateNodeHash(proof.Siblings[depth], hash)
+		}
+	}
+
+	if hash != stateRoot {
+		return errors.New(fmt.Sprintf("Proof of account (%x) does not match the state root %x.", key[0:8], stateRoot[0:8]))
+	}
+
+	return nil
+}
+
+func (proof *AccountProof) Encode() []byte {
+	if proof == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(proof)
+	return buffer.Bytes()
+}
+
+func (*AccountProof) Decode(encoded []byte) (proof *AccountProof, err error) {
+	var decoded AccountProof
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return &decoded, nil
+}
+
+//The nodes on the path to the leaf are copied, the original subtree is not changed.
+func insertStateLeaf(node *stateNode, leaf *stateNode, depth int) *stateNode {
+	if node == nil || node.leaf && node.key == leaf.key {
+		return leaf
+	}
+
+	if node.leaf {
+		//The subtree contains two accounts now, the existing leaf moves one level down.
+		inner := new(stateNode)
+		if bitAt(node.key, depth) == 0 {
+			inner.left = node
+		} else {
+			inner.right = node
+		}
+		node = inner
+	}
+
+	updated := *node
+	if bitAt(leaf.key, depth) == 0 {
+		updated.left = insertStateLeaf(node.left, leaf, depth+1)
+	} else {
+		updated.right = insertStateLeaf(node.right, leaf, depth+1)
+	}
+	updated.hash = stateNodeHash(updated.left.subtreeHash(), updated.right.subtreeHash())
+
+	return &updated
+}
+
+func removeStateLeaf(node *stateNode, key [32]byte, depth int) *stateNode {
+	if node == nil || node.leaf {
+		if node != nil && node.key == key {
+			return nil
+		}
+		return node
+	}
+
+	updated := *node
+	if bitAt(key, depth) == 0 {
+		updated.left = removeStateLeaf(node.left, key, depth+1)
+	} else {
+		updated.right = removeStateLeaf(node.right, key, depth+1)
+	}
+	if updated.left == node.left && updated.right == node.right {
+		return node
+	}
+
+	//A subtree with a single account is replaced by the account's leaf.
+	if updated.left == nil && (updated.right == nil || updated.right.leaf) {
+		return updated.right
+	}
+	if updated.right == nil && updated.left.leaf {
+		return updated.left
+	}
+	updated.hash = stateNodeHash(updated.left.subtreeHash(), updated.right.subtreeHash())
+
+	return &updated
+}
+
+func (node *stateNode) subtreeHash() [32]byte {
+	if node == nil {
+		return [32]byte{}
+	}
+	return node.hash
+}
+
+//All fields of the account are committed to, the key binds the leaf to its position in the tree.
+func stateLeafHash(key [32]byte, acc *Account) [32]byte {
+	content := SerializeHashContent(struct {
+		address            [64]byte
+		issuer             [32]byte
+		balance            uint64
+		txCnt              uint32
+		isStaking          bool
+		commitmentKey      [crypto.COMM_KEY_LENGTH]byte
+		stakingBlockHeight uint32
+		contract           []byte
+		contractVariables  []ByteArray
+	}{
+		acc.Address,
+		acc.Issuer,
+		acc.Balance,
+		acc.TxCnt,
+		acc.IsStaking,
+		acc.CommitmentKey,
+		acc.StakingBlockHeight,
+		acc.Contract,
+		acc.ContractVariables,
+	})
+	return sha3.Sum256(append(append([]byte{STATE_LEAF_PREFIX}, key[:]...), content[:]...))
+}
+
+func stateNodeHash(left, right [32]byte) [32]byte {
+	return sha3.Sum256(append(append([]byte{STATE_NODE_PREFIX}, left[:]...), right[:]...))
+}
+
+//Bits are counted from the most significant bit of the first byte.
+func bitAt(key [32]byte, index int) byte {
+	return (key[index/8] >> uint(7-index%8)) & 1
+}
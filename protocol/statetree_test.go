@@ -0,0 +1,214 @@
+package protocol
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestBuildStateTreeEmpty(t *testing.T) {
+	if root := BuildStateTree(nil).Root(); root != [32]byte{} {
+		t.Errorf("Root of the empty state should be the zero hash but was %x\n", root)
+	}
+}
+
+func TestBuildStateTree(t *testing.T) {
+	//The first bit of the keys decides on which side of the root they are.
+	var keyLeft, keyRight [32]byte
+	keyLeft[0], keyRight[0] = 0x01, 0x80
+
+	accLeft, accRight := new(Account), new(Account)
+	rand.Read(accLeft.Address[:])
+	rand.Read(accRight.Address[:])
+	accRight.Balance = 100
+
+	single := BuildStateTree(map[[32]byte]*Account{keyLeft: accLeft})
+	if single.Root() != stateLeafHash(keyLeft, accLeft) {
+		t.Errorf("Root of a single account should be its leaf: %x vs. %x\n", single.Root(), stateLeafHash(keyLeft, accLeft))
+	}
+
+	tree := BuildStateTree(map[[32]byte]*Account{keyLeft: accLeft, keyRight: accRight})
+	expected := stateNodeHash(stateLeafHash(keyLeft, accLeft), stateLeafHash(keyRight, accRight))
+	if tree.Root() != expected {
+		t.Errorf("Root does not match: %x vs. %x\n", tree.Root(), expected)
+	}
+
+	//Keys which share a prefix are separated further down the tree.
+	var keyLeft2 [32]byte
+	keyLeft2[0] = 0x02
+	tree = BuildStateTree(map[[32]byte]*Account{keyLeft: accLeft, keyLeft2: accRight})
+	expected = stateLeafHash(keyLeft, accLeft)
+	expected = stateNodeHash(expected, stateLeafHash(keyLeft2, accRight))
+	for i := 0; i < 6; i++ {
+		expected = stateNodeHash(expected, [32]byte{})
+	}
+	if tree.Root() != expected {
+		t.Errorf("Root does not match: %x vs. %x\n", tree.Root(), expected)
+	}
+}
+
+func TestStateTreeCommitsToAccounts(t *testing.T) {
+	state := make(map[[32]byte]*Account)
+	for i := 0; i < 100; i++ {
+		acc := new(Account)
+		rand.Read(acc.Address[:])
+		state[acc.Hash()] = acc
+	}
+
+	root := BuildStateTree(state).Root()
+	if root != BuildStateTree(state).Root() {
+		t.Errorf("State tree is not deterministic\n")
+	}
+
+	for _, acc := range state {
+		acc.Balance++
+		if BuildStateTree(state).Root() == root {
+			t.Errorf("Balance change did not change the state root\n")
+		}
+		acc.Balance--
+
+		acc.ContractVariables = []ByteArray{{0x01}}
+		if BuildStateTree(state).Root() == root {
+			t.Errorf("Contract variable change did not change the state root\n")
+		}
+		acc.ContractVariables = nil
+		break
+	}
+
+	if BuildStateTree(state).Root() != root {
+		t.Errorf("Reverted state does not have its former root\n")
+	}
+}
+
+func TestStateTreeUpdate(t *testing.T) {
+	state := make(map[[32]byte]*Account)
+	var keys [][32]byte
+	for i := 0; i < 100; i++ {
+		acc := new(Account)
+		rand.Read(acc.Address[:])
+		state[acc.Hash()] = acc
+		keys = append(keys, acc.Hash())
+	}
+
+	tree := BuildStateTree(state)
+	root := tree.Root()
+
+	//Change, remove and add accounts, the updated tree must equal the tree built from scratch.
+	changed := keys[:10]
+	for _, key := range keys[:5] {
+		state[key].Balance++
+	}
+	for _, key := range keys[5:10] {
+		delete(state, key)
+	}
+	for i := 0; i < 5; i++ {
+		acc := new(Account)
+		rand.Read(acc.Address[:])
+		state[acc.Hash()] = acc
+		changed = append(changed, acc.Hash())
+	}
+
+	updated := tree.Update(state, changed)
+	if updated.Root() != BuildStateTree(state).Root() {
+		t.Errorf("Updated tree does not match the tree built from the state: %x vs. %x\n", updated.Root(), BuildStateTree(state).Root())
+	}
+	if tree.Root() != root {
+		t.Errorf("Update changed the original tree\n")
+	}
+
+	//Removing all accounts but one leaves the account's leaf.
+	var last [32]byte
+	for key := range state {
+		last = key
+	}
+	var all [][32]byte
+	for key := range state {
+		if key != last {
+			all = append(all, key)
+		}
+	}
+	single := updated.Update(map[[32]byte]*Account{last: state[last]}, all)
+	if single.Root() != stateLeafHash(last, state[last]) {
+		t.Errorf("Root of the remaining account should be its leaf: %x vs. %x\n", single.Root(), stateLeafHash(last, state[last]))
+	}
+	if empty := single.Update(nil, [][32]byte{last}); empty.Root() != [32]byte{} {
+		t.Errorf("Root of the empty tree should be the zero hash but was %x\n", empty.Root())
+	}
+}
+
+func TestAccountProof(t *testing.T) {
+	state := make(map[[32]byte]*Account)
+	for i := 0; i < 50; i++ {
+		acc := new(Account)
+		rand.Read(acc.Address[:])
+		acc.Balance = uint64(i)
+		state[acc.Hash()] = acc
+	}
+
+	tree := BuildStateTree(state)
+	for hash, acc := range state {
+		treeAcc, siblings, err := tree.Proof(hash)
+		if err != nil {
+			t.Fatalf("Could not create proof: %v\n", err)
+		}
+		if !reflect.DeepEqual(treeAcc, acc) {
+			t.Errorf("Proof does not contain the account of the state: %v vs. %v\n", treeAcc, acc)
+		}
+
+		proof := &AccountProof{Account: treeAcc, Siblings: siblings}
+		decoded, err := proof.Decode(proof.Encode())
+		if err != nil {
+			t.Fatalf("Could not decode proof: %v\n", err)
+		}
+		if err := VerifyAccountProof(tree.Root(), decoded); err != nil {
+			t.Errorf("Valid proof was rejected: %v\n", err)
+		}
+
+		//A miner can't lie about the account.
+		forged := *acc
+		forged.Balance += 1000
+		if err := VerifyAccountProof(tree.Root(), &AccountProof{Account: &forged, Siblings: siblings}); err == nil {
+			t.Errorf("Proof of a forged account was accepted\n")
+		}
+	}
+
+	var unknown [32]byte
+	if _, _, err := tree.Proof(unknown); err == nil {
+		t.Errorf("Proof of an account which is not in the state was created\n")
+	}
+
+	//A tree with a single account has an empty proof.
+	for hash, acc := range state {
+		single := BuildStateTree(map[[32]byte]*Account{hash: acc})
+		if _, siblings, _ := single.Proof(hash); len(siblings) != 0 || VerifyAccountProof(single.Root(), &AccountProof{Account: acc}) != nil {
+			t.Errorf("Proof of a single account should be empty and valid\n")
+		}
+		break
+	}
+
+	if _, err := new(AccountProof).Decode([]byte{1, 2, 3}); err == nil {
+		t.Errorf("Undecodable proof was decoded\n")
+	}
+}
+
+//The tree keeps the accounts as they were when the tree was updated, later changes to the state are not visible.
+func TestAccountProof_Immutable(t *testing.T) {
+	acc := new(Account)
+	rand.Read(acc.Address[:])
+	acc.Balance = 100
+	state := map[[32]byte]*Account{acc.Hash(): acc}
+
+	tree := BuildStateTree(state)
+	acc.Balance = 200
+
+	treeAcc, siblings, err := tree.Proof(acc.Hash())
+	if err != nil {
+		t.Fatalf("Could not create proof: %v\n", err)
+	}
+	if treeAcc.Balance != 100 {
+		t.Errorf("Account in the tree was changed with the state: %v vs. %v\n", treeAcc.Balance, 100)
+	}
+	if err := VerifyAccountProof(tree.Root(), &AccountProof{Account: treeAcc, Siblings: siblings}); err != nil {
+		t.Errorf("Proof of the account in the tree was rejected: %v\n", err)
+	}
+}
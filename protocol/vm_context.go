@@ -2,27 +2,71 @@ package protocol
 
 import (
 	"errors"
+	"fmt"
 )
 
+//The context a contract is executed in by the vm, which refers to it as vm.Context.
+type VMContext interface {
+	GetContract() []byte
+	GetContractVariable(index int) ([]byte, error)
+	SetContractVariable(index int, value []byte) error
+	GetAddress() [64]byte
+	GetIssuer() [32]byte
+	GetBalance() uint64
+	GetSender() [32]byte
+	GetAmount() uint64
+	GetTransactionData() []byte
+	GetFee() uint64
+	GetSig1() [64]byte
+	NewCallContext(address [32]byte, data []byte, fee uint64) (VMContext, error)
+	EmitEvent(topics [][]byte, data []byte) error
+	GetBlockHeight() uint32
+	GetBlockTimestamp() int64
+	GetPrevBlockHash() [32]byte
+	Transfer(to [32]byte, amount uint64) error
+	SelfDestruct(beneficiary [32]byte) error
+}
+
+var _ VMContext = (*Context)(nil)
+
 type Context struct {
 	Account
-	changes []Change
+	changes       []Change
+	events        []Event
+	transfers     []Transfer
+	selfDestructs []SelfDestruct
 	FundsTx
+	accounts func(address [32]byte) (*Account, error)
+	caller   *Context
+	block    blockContext
+}
+
+//The block which contains the transaction.
+type blockContext struct {
+	height    uint32
+	timestamp int64
+	prevHash  [32]byte
 }
 
 type Change struct {
-	index int
-	value []byte
+	account [32]byte
+	index   int
+	value   []byte
 }
 
-func NewChange(index int, value []byte) Change {
-	return Change{index, value}
+func NewChange(account [32]byte, index int, value []byte) Change {
+	return Change{account, index, value}
 }
 
 func (c *Change) GetChange() (int, []byte) {
 	return c.index, c.value
 }
 
+//Returns the address of the contract account the variable belongs to.
+func (c *Change) GetAccount() [32]byte {
+	return c.account
+}
+
 func NewContext(account Account, fundsTx FundsTx) *Context {
 	newContext := Context{
 		Account: account,
@@ -55,15 +99,181 @@ func (c *Context) SetContractVariable(index int, value []byte) error {
 	cp := make([]byte, len(value))
 	copy(cp, value)
 
-	change := NewChange(index, cp)
-	c.changes = append(c.changes, change)
+	//Changes of called contracts are collected by the context of the transaction, so that all of them are either
+	//persisted or discarded together.
+	root := c.root()
+	change := NewChange(c.To, index, cp)
+	root.changes = append(root.changes, change)
+	return nil
+}
+
+//Events are collected by the context of the transaction like the changes.
+func (c *Context) EmitEvent(topics [][]byte, data []byte) error {
+	event := Event{Account: c.To, Data: append(ByteArray{}, data...)}
+	for _, topic := range topics {
+		event.Topics = append(event.Topics, append(ByteArray{}, topic...))
+	}
+
+	root := c.root()
+	root.events = append(root.events, event)
+	return nil
+}
+
+//Returns the events in the order in which they were emitted.
+func (c *Context) GetEvents() []Event {
+	return c.events
+}
+
+func (c *Context) root() *Context {
+	root := c
+	for root.caller != nil {
+		root = root.caller
+	}
+	return root
+}
+
+//Coins a contract sends from its account with TRANSFER.
+type Transfer struct {
+	From   [32]byte
+	To     [32]byte
+	Amount uint64
+}
+
+//Transfers are collected by the context of the transaction like the changes. The contract can spend its balance, the
+//amount of the transaction and what it received from called contracts. Transfers are only checked against the balance,
+//the miner applies them after the transaction.
+func (c *Context) Transfer(to [32]byte, amount uint64) error {
+	if amount == 0 {
+		return nil
+	}
+
+	if c.hasSelfDestructed() {
+		return errors.New("Contract has self-destructed")
+	}
+
+	if c.accounts != nil {
+		if acc, err := c.accounts(to); err != nil || acc == nil {
+			return errors.New(fmt.Sprintf("Account %x does not exist", to[:8]))
+		}
+	}
+
+	root := c.root()
+	available := c.Balance
+	if c.To == root.To {
+		available += root.Amount
+	}
+	for _, transfer := range root.transfers {
+		if transfer.To == c.To {
+			available += transfer.Amount
+		}
+		if transfer.From == c.To {
+			available -= transfer.Amount
+		}
+	}
+
+	if amount > available {
+		return errors.New(fmt.Sprintf("Insufficient balance: %v available, %v to transfer", available, amount))
+	}
+
+	root.transfers = append(root.transfers, Transfer{c.To, to, amount})
 	return nil
 }
 
+//Returns the transfers in the order in which they were made.
+func (c *Context) GetTransfers() []Transfer {
+	return c.transfers
+}
+
+//A contract which destroyed itself with SELFDESTRUCT.
+type SelfDestruct struct {
+	Account     [32]byte
+	Beneficiary [32]byte
+}
+
+//Self-destructs are collected by the context of the transaction like the changes. The miner removes the contract and
+//its variables after the transaction and sends the balance which remains after the transfers to the beneficiary.
+func (c *Context) SelfDestruct(beneficiary [32]byte) error {
+	if beneficiary == c.To {
+		return errors.New("Contract can't be its own beneficiary")
+	}
+
+	if c.accounts != nil {
+		if acc, err := c.accounts(beneficiary); err != nil || acc == nil {
+			return errors.New(fmt.Sprintf("Account %x does not exist", beneficiary[:8]))
+		}
+	}
+
+	if c.hasSelfDestructed() {
+		return errors.New("Contract has self-destructed")
+	}
+
+	root := c.root()
+	root.selfDestructs = append(root.selfDestructs, SelfDestruct{c.To, beneficiary})
+	return nil
+}
+
+func (c *Context) hasSelfDestructed() bool {
+	for _, selfDestruct := range c.root().selfDestructs {
+		if selfDestruct.Account == c.To {
+			return true
+		}
+	}
+	return false
+}
+
+//Returns the self-destructs in the order in which they were made.
+func (c *Context) GetSelfDestructs() []SelfDestruct {
+	return c.selfDestructs
+}
+
+//Sets the function which is used to look up the contract accounts called with CALLEXT.
+func (c *Context) SetAccountReader(accounts func(address [32]byte) (*Account, error)) {
+	c.accounts = accounts
+}
+
+//Sets the block the transaction is executed in. While a block is being built, its timestamp is not known yet.
+func (c *Context) SetBlock(height uint32, timestamp int64, prevHash [32]byte) {
+	c.block = blockContext{height, timestamp, prevHash}
+}
+
+//Returns the context for calling the contract at address from the contract of this context. The calling contract is
+//the sender of the call, the remaining fee of the caller is passed along.
+func (c *Context) NewCallContext(address [32]byte, data []byte, fee uint64) (VMContext, error) {
+	if c.accounts == nil {
+		return nil, errors.New("No accounts available")
+	}
+
+	acc, err := c.accounts(address)
+	if err != nil {
+		return nil, err
+	}
+	if acc == nil || len(acc.Contract) == 0 {
+		return nil, errors.New(fmt.Sprintf("Account %x has no contract", address[:8]))
+	}
+
+	callContext := NewContext(*acc, FundsTx{From: c.To, To: address, Fee: fee, Data: data})
+	callContext.accounts = c.accounts
+	callContext.caller = c
+	callContext.block = c.block
+	return callContext, nil
+}
+
+//Returns the writes to contract variables in the order in which they were made.
+func (c *Context) GetChanges() []Change {
+	return c.changes
+}
+
 func (c *Context) PersistChanges() {
 	for _, change := range c.changes {
 		i, value := change.GetChange()
-		c.ContractVariables[i] = value
+		if change.account == c.To || c.accounts == nil {
+			c.ContractVariables[i] = value
+			continue
+		}
+
+		if acc, err := c.accounts(change.account); err == nil && acc != nil && i < len(acc.ContractVariables) {
+			acc.ContractVariables[i] = value
+		}
 	}
 }
 
@@ -98,3 +308,15 @@ func (c *Context) GetFee() uint64 {
 func (c *Context) GetSig1() [64]byte {
 	return c.Sig1
 }
+
+func (c *Context) GetBlockHeight() uint32 {
+	return c.block.height
+}
+
+func (c *Context) GetBlockTimestamp() int64 {
+	return c.block.timestamp
+}
+
+func (c *Context) GetPrevBlockHash() [32]byte {
+	return c.block.prevHash
+}
@@ -2,6 +2,8 @@ package protocol
 
 import (
 	"bytes"
+	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -51,3 +53,120 @@ func TestVMContext_SetContractVariable_EncapsulationBreach(t *testing.T) {
 		t.Errorf("Expected result to be '%v' but was '%v'", expected, actual)
 	}
 }
+
+func TestVMContext_EmitEvent(t *testing.T) {
+	c := NewContext(Account{}, FundsTx{To: [32]byte{1}})
+	c.SetAccountReader(func(address [32]byte) (*Account, error) {
+		return &Account{Contract: []byte{0}}, nil
+	})
+
+	topic := []byte{'a'}
+	c.EmitEvent([][]byte{topic}, []byte{1})
+	topic[0] = 'b'
+
+	vmContext, _ := c.NewCallContext([32]byte{2}, nil, 0)
+	callContext := vmContext.(*Context)
+	callContext.EmitEvent(nil, []byte{2})
+
+	events := c.GetEvents()
+	if len(events) != 2 || len(callContext.GetEvents()) != 0 {
+		t.Fatalf("Expected both events in the context of the transaction but got %v", events)
+	}
+	if !bytes.Equal(events[0].Topics[0], []byte{'a'}) || events[0].Account != [32]byte{1} {
+		t.Errorf("Unexpected first event %v", events[0])
+	}
+	if !bytes.Equal(events[1].Data, []byte{2}) || events[1].Account != [32]byte{2} {
+		t.Errorf("Unexpected second event %v", events[1])
+	}
+}
+
+func TestVMContext_SetBlock(t *testing.T) {
+	c := NewContext(Account{}, FundsTx{})
+	c.SetAccountReader(func(address [32]byte) (*Account, error) {
+		return &Account{Contract: []byte{0}}, nil
+	})
+	c.SetBlock(5, 1000, [32]byte{1})
+
+	vmContext, _ := c.NewCallContext([32]byte{2}, nil, 0)
+	callContext := vmContext.(*Context)
+	for _, context := range []*Context{c, callContext} {
+		if context.GetBlockHeight() != 5 || context.GetBlockTimestamp() != 1000 || context.GetPrevBlockHash() != [32]byte{1} {
+			t.Errorf("Expected the block of the transaction but got %v, %v, %x", context.GetBlockHeight(), context.GetBlockTimestamp(), context.GetPrevBlockHash())
+		}
+	}
+}
+
+func TestVMContext_Transfer(t *testing.T) {
+	callee := &Account{Contract: []byte{0}, Balance: 5}
+	c := NewContext(Account{Balance: 10}, FundsTx{To: [32]byte{1}, Amount: 3})
+	c.SetAccountReader(func(address [32]byte) (*Account, error) {
+		if address == [32]byte{2} {
+			return callee, nil
+		}
+		if address == [32]byte{4} {
+			return nil, errors.New("Account does not exist")
+		}
+		return &Account{}, nil
+	})
+
+	//The contract can spend its balance and the amount of the tx, the callee additionally what it received.
+	if err := c.Transfer([32]byte{2}, 12); err != nil {
+		t.Fatalf("Transfer failed: %v", err)
+	}
+	vmContext, _ := c.NewCallContext([32]byte{2}, nil, 0)
+	callContext := vmContext.(*Context)
+	if err := callContext.Transfer([32]byte{3}, 17); err != nil {
+		t.Fatalf("Transfer of the callee failed: %v", err)
+	}
+
+	for _, invalid := range []*Context{c, callContext} {
+		if err := invalid.Transfer([32]byte{3}, 2); err == nil {
+			t.Errorf("Expected transfer exceeding the balance to fail")
+		}
+	}
+	if err := c.Transfer([32]byte{4}, 1); err == nil {
+		t.Errorf("Expected transfer to a missing account to fail")
+	}
+
+	expected := []Transfer{{[32]byte{1}, [32]byte{2}, 12}, {[32]byte{2}, [32]byte{3}, 17}}
+	if transfers := c.GetTransfers(); !reflect.DeepEqual(transfers, expected) {
+		t.Errorf("Expected transfers %v but were %v", expected, transfers)
+	}
+}
+
+func TestVMContext_SelfDestruct(t *testing.T) {
+	callee := &Account{Contract: []byte{0}, Balance: 5}
+	c := NewContext(Account{Balance: 10}, FundsTx{To: [32]byte{1}})
+	c.SetAccountReader(func(address [32]byte) (*Account, error) {
+		if address == [32]byte{2} {
+			return callee, nil
+		}
+		if address == [32]byte{4} {
+			return nil, errors.New("Account does not exist")
+		}
+		return &Account{}, nil
+	})
+
+	callContext, _ := c.NewCallContext([32]byte{2}, nil, 0)
+	if err := callContext.SelfDestruct([32]byte{3}); err != nil {
+		t.Fatalf("Self-destruct of the callee failed: %v", err)
+	}
+
+	if err := callContext.SelfDestruct([32]byte{3}); err == nil {
+		t.Errorf("Expected second self-destruct to fail")
+	}
+	if err := callContext.Transfer([32]byte{3}, 1); err == nil {
+		t.Errorf("Expected transfer of a self-destructed contract to fail")
+	}
+	if err := c.SelfDestruct([32]byte{1}); err == nil {
+		t.Errorf("Expected self-destruct to the contract itself to fail")
+	}
+	if err := c.SelfDestruct([32]byte{4}); err == nil {
+		t.Errorf("Expected self-destruct to a missing account to fail")
+	}
+
+	expected := []SelfDestruct{{[32]byte{2}, [32]byte{3}}}
+	if selfDestructs := c.GetSelfDestructs(); !reflect.DeepEqual(selfDestructs, expected) {
+		t.Errorf("Expected self-destructs %v but were %v", expected, selfDestructs)
+	}
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
+)
+
+//The contract history is keyed by the account, the block height and the tx hash in the "contracthistory" bucket,
+//such that the entries of an account are in the order of the blocks.
+func contractHistoryKey(account [32]byte, blockHeight uint32, txHash [32]byte) []byte {
+	key := make([]byte, 68)
+	copy(key[:32], account[:])
+	binary.BigEndian.PutUint32(key[32:36], blockHeight)
+	copy(key[36:], txHash[:])
+	return key
+}
+
+func WriteContractHistory(entry *protocol.ContractHistoryEntry) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("contracthistory")).Put(contractHistoryKey(entry.Account, entry.BlockHeight, entry.TxHash), entry.Encode())
+	})
+}
+
+//Returns the changes of the account's contract, the oldest first.
+func ReadContractHistory(account [32]byte) (entries []*protocol.ContractHistoryEntry) {
+	db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte("contracthistory")).Cursor()
+		for k, v := c.Seek(account[:]); k != nil && bytes.HasPrefix(k, account[:]); k, v = c.Next() {
+			var entry *protocol.ContractHistoryEntry
+			if entry = entry.Decode(v); entry != nil {
+				entries = append(entries, entry)
+			}
+		}
+		return nil
+	})
+
+	return entries
+}
+
+//Deletes the entry of the tx in the block at the given height.
+func DeleteContractHistory(account [32]byte, blockHeight uint32, txHash [32]byte) {
+	db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("contracthistory")).Delete(contractHistoryKey(account, blockHeight, txHash))
+	})
+}
@@ -49,6 +49,14 @@ func DeleteAllLastClosedBlock() {
 	})
 }
 
+func DeleteJournal(blockHash [32]byte) {
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("journals"))
+		err := b.Delete(blockHash[:])
+		return err
+	})
+}
+
 func DeleteOpenTx(transaction protocol.Transaction) {
 	openTxMutex.Lock()
 	delete(txMemPool, transaction.Hash())
@@ -171,4 +179,41 @@ func DeleteAll() {
 		})
 		return nil
 	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("journals"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("receipts"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("contracthistory"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	DeleteState()
+}
+
+//Removes the persisted state, the next startup replays the whole chain.
+func DeleteState() {
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("state"))
+		b.DeleteBucket(stateAccountsKey)
+		b.DeleteBucket(stateRootKeysKey)
+		b.Delete(stateMetaKey)
+		b.Delete(stateBlockHashKey)
+		return nil
+	})
 }
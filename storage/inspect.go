@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"github.com/boltdb/bolt"
+	"time"
+)
+
+type BucketStats struct {
+	Name    string
+	Entries int
+}
+
+//Opens an existing database without write access. This is used to inspect the database of a miner which is not
+//running, the buckets are not created if they don't exist.
+func InitReadOnly(dbname string) (err error) {
+	db, err = bolt.Open(dbname, 0600, &bolt.Options{Timeout: 5 * time.Second, ReadOnly: true})
+	return err
+}
+
+//Returns all buckets of the database with the number of their entries.
+func ReadBucketStats() (stats []BucketStats) {
+	db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			stats = append(stats, BucketStats{string(name), b.Stats().KeyN})
+			return nil
+		})
+	})
+
+	return stats
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
+)
+
+//Values of an account which a block overwrites and which can't be derived from the block's transactions when it
+//is rolled back.
+type StakingJournalEntry struct {
+	Account            [32]byte
+	CommitmentKey      [crypto.COMM_KEY_LENGTH]byte
+	StakingBlockHeight uint32
+}
+
+//A write of a contract to one of its variables. The entries are in the order of the writes.
+type ContractJournalEntry struct {
+	Account  [32]byte
+	Index    int
+	OldValue []byte
+	NewValue []byte
+}
+
+//Coins a contract sent to another account.
+type TransferJournalEntry struct {
+	From   [32]byte
+	To     [32]byte
+	Amount uint64
+}
+
+//A contract which an AccTx replaced, with the account's contract and variables from before the upgrade.
+type UpgradeJournalEntry struct {
+	Account           [32]byte
+	TxHash            [32]byte
+	BlockHeight       uint32
+	Contract          []byte
+	ContractVariables []protocol.ByteArray
+}
+
+//A contract which destroyed itself, with its contract and variables and the balance it sent to the beneficiary.
+type SelfDestructJournalEntry struct {
+	Account           [32]byte
+	Beneficiary       [32]byte
+	Amount            uint64
+	TxHash            [32]byte
+	BlockHeight       uint32
+	Contract          []byte
+	ContractVariables []protocol.ByteArray
+}
+
+//A journal holds the values from before a block was validated, keyed by the block hash in the "journals" bucket.
+type Journal struct {
+	Staking       []StakingJournalEntry
+	Contract      []ContractJournalEntry
+	Receipts      [][32]byte //Hashes of the txs whose receipts the block wrote
+	Transfers     []TransferJournalEntry
+	Upgrades      []UpgradeJournalEntry
+	SelfDestructs []SelfDestructJournalEntry
+}
+
+func WriteJournal(blockHash [32]byte, journal *Journal) error {
+	buffer := new(bytes.Buffer)
+	if err := gob.NewEncoder(buffer).Encode(journal); err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("journals")).Put(blockHash[:], buffer.Bytes())
+	})
+}
+
+//Returns nil if there is no journal for the given block.
+func ReadJournal(blockHash [32]byte) (journal *Journal) {
+	db.View(func(tx *bolt.Tx) error {
+		encoded := tx.Bucket([]byte("journals")).Get(blockHash[:])
+		if encoded == nil {
+			return nil
+		}
+
+		journal = new(Journal)
+		if err := gob.NewDecoder(bytes.NewBuffer(encoded)).Decode(journal); err != nil {
+			journal = nil
+		}
+		return nil
+	})
+
+	return journal
+}
@@ -73,6 +73,25 @@ func ReadLastClosedBlock() (block *protocol.Block) {
 	return block
 }
 
+//There is no index on the height, hence we walk back from the last closed block. Blocks whose transactions
+//are aggregated are looked up in the closedblockswithouttx bucket.
+func ReadClosedBlockByHeight(height uint32) (block *protocol.Block) {
+	block = ReadLastClosedBlock()
+	for block != nil && block.Height > height {
+		prevBlock := ReadClosedBlock(block.PrevHash)
+		if prevBlock == nil {
+			prevBlock = ReadClosedBlockWithoutTx(block.PrevHashWithoutTx)
+		}
+		block = prevBlock
+	}
+
+	if block == nil || block.Height != height {
+		return nil
+	}
+
+	return block
+}
+
 func ReadAllClosedBlocksWithTransactions() (allClosedBlocks []*protocol.Block) {
 
 	//This does return all blocks which are either in closedblocks or closedblockswithouttx bucket of the Database.
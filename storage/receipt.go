@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
+)
+
+//Receipts of contract executions are keyed by the tx hash in the "receipts" bucket.
+func WriteReceipt(receipt *protocol.Receipt) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("receipts")).Put(receipt.TxHash[:], receipt.Encode())
+	})
+}
+
+//Returns nil if there is no receipt for the given tx.
+func ReadReceipt(txHash [32]byte) (receipt *protocol.Receipt) {
+	db.View(func(tx *bolt.Tx) error {
+		encoded := tx.Bucket([]byte("receipts")).Get(txHash[:])
+		if encoded != nil {
+			receipt = receipt.Decode(encoded)
+		}
+		return nil
+	})
+
+	return receipt
+}
+
+//Returns the receipts with an event which has the topic.
+func ReadReceiptsByTopic(topic []byte) (receipts []*protocol.Receipt) {
+	db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("receipts")).ForEach(func(k, v []byte) error {
+			var receipt *protocol.Receipt
+			if receipt = receipt.Decode(v); receipt != nil && receipt.HasTopic(topic) {
+				receipts = append(receipts, receipt)
+			}
+			return nil
+		})
+	})
+
+	return receipts
+}
+
+func DeleteReceipt(txHash [32]byte) {
+	db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("receipts")).Delete(txHash[:])
+	})
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
+)
+
+//The account state is persisted in the "state" bucket, together with the hash of the last closed block it
+//corresponds to. A restarting miner then only has to replay the blocks after this block. The miner's chain
+//metadata (system parameters, difficulty history) is stored along with it as an opaque value.
+var (
+	stateBlockHashKey = []byte("blockhash")
+	stateMetaKey      = []byte("meta")
+	stateAccountsKey  = []byte("accounts")
+	stateRootKeysKey  = []byte("rootkeys")
+)
+
+//Replaces the last closed block and persists the current state in the same bolt transaction, such that the
+//persisted state always corresponds to the last closed block.
+func WriteLastClosedBlockWithState(block *protocol.Block, meta []byte) (err error) {
+	return db.Update(func(tx *bolt.Tx) error {
+		if err := replaceLastClosedBlock(tx, block); err != nil {
+			return err
+		}
+
+		return writeState(tx.Bucket([]byte("state")), block.Hash, meta)
+	})
+}
+
+//Like WriteLastClosedBlockWithState, but only the given accounts are written (or deleted if they are not in the state
+//anymore). The accounts must comprise all changes since the state of the block prevHash was persisted, an error is
+//returned if the persisted state does not correspond to this block.
+func WriteLastClosedBlockWithStateChanges(block *protocol.Block, meta []byte, prevHash [32]byte, accHashes [][32]byte) (err error) {
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("state"))
+		if !bytes.Equal(b.Get(stateBlockHashKey), prevHash[:]) {
+			return errors.New(fmt.Sprintf("Persisted state does not correspond to block (%x).", prevHash[0:8]))
+		}
+
+		if err := replaceLastClosedBlock(tx, block); err != nil {
+			return err
+		}
+
+		return writeStateChanges(b, block.Hash, meta, accHashes)
+	})
+}
+
+func replaceLastClosedBlock(tx *bolt.Tx, block *protocol.Block) error {
+	b := tx.Bucket([]byte("lastclosedblock"))
+	c := b.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.First() {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return b.Put(block.Hash[:], block.Encode())
+}
+
+//The whole state is rewritten, accounts which were removed (e.g., by a rollback) must not survive in the bucket.
+func writeState(b *bolt.Bucket, blockHash [32]byte, meta []byte) error {
+	for _, key := range [][]byte{stateAccountsKey, stateRootKeysKey} {
+		if b.Bucket(key) != nil {
+			if err := b.DeleteBucket(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	accounts, err := b.CreateBucket(stateAccountsKey)
+	if err != nil {
+		return err
+	}
+	for hash, acc := range State {
+		if err := accounts.Put(hash[:], acc.Encode()); err != nil {
+			return err
+		}
+	}
+
+	rootKeys, err := b.CreateBucket(stateRootKeysKey)
+	if err != nil {
+		return err
+	}
+	for hash := range RootKeys {
+		if err := rootKeys.Put(hash[:], []byte{}); err != nil {
+			return err
+		}
+	}
+
+	return writeStateBlock(b, blockHash, meta)
+}
+
+func writeStateChanges(b *bolt.Bucket, blockHash [32]byte, meta []byte, accHashes [][32]byte) error {
+	accounts, rootKeys := b.Bucket(stateAccountsKey), b.Bucket(stateRootKeysKey)
+	if accounts == nil || rootKeys == nil {
+		return errors.New("Persisted state is incomplete.")
+	}
+
+	for _, hash := range accHashes {
+		var err error
+		if acc := State[hash]; acc != nil {
+			err = accounts.Put(hash[:], acc.Encode())
+		} else {
+			err = accounts.Delete(hash[:])
+		}
+		if err != nil {
+			return err
+		}
+
+		if IsRootKey(hash) {
+			err = rootKeys.Put(hash[:], []byte{})
+		} else {
+			err = rootKeys.Delete(hash[:])
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeStateBlock(b, blockHash, meta)
+}
+
+func writeStateBlock(b *bolt.Bucket, blockHash [32]byte, meta []byte) error {
+	if err := b.Put(stateMetaKey, meta); err != nil {
+		return err
+	}
+
+	return b.Put(stateBlockHashKey, blockHash[:])
+}
+
+//Returns the hash of the block the persisted state corresponds to, or an error if no state was persisted yet.
+func ReadStateBlockHash() (blockHash [32]byte, err error) {
+	db.View(func(tx *bolt.Tx) error {
+		if encodedHash := tx.Bucket([]byte("state")).Get(stateBlockHashKey); encodedHash != nil {
+			copy(blockHash[:], encodedHash)
+		} else {
+			err = errors.New("No state persisted.")
+		}
+		return nil
+	})
+
+	return blockHash, err
+}
+
+//Returns the metadata persisted along with the state.
+func ReadStateMeta() (meta []byte) {
+	db.View(func(tx *bolt.Tx) error {
+		meta = append([]byte{}, tx.Bucket([]byte("state")).Get(stateMetaKey)...)
+		return nil
+	})
+
+	return meta
+}
+
+//Replaces the in-memory State and RootKeys with the persisted state.
+func LoadState() (blockHash [32]byte, err error) {
+	if blockHash, err = ReadStateBlockHash(); err != nil {
+		return blockHash, err
+	}
+
+	state := make(map[[32]byte]*protocol.Account)
+	rootKeys := make(map[[32]byte]*protocol.Account)
+
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("state"))
+		accounts, roots := b.Bucket(stateAccountsKey), b.Bucket(stateRootKeysKey)
+		if accounts == nil || roots == nil {
+			return errors.New("Persisted state is incomplete.")
+		}
+
+		var acc *protocol.Account
+		err := accounts.ForEach(func(k, v []byte) error {
+			var hash [32]byte
+			copy(hash[:], k)
+			if state[hash] = acc.Decode(v); state[hash] == nil {
+				return errors.New(fmt.Sprintf("Account (%x) in the persisted state could not be decoded.", hash[0:8]))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return roots.ForEach(func(k, v []byte) error {
+			var hash [32]byte
+			copy(hash[:], k)
+			if state[hash] == nil {
+				return errors.New(fmt.Sprintf("Root account (%x) not in the persisted state.", hash[0:8]))
+			}
+			//Root keys share the account with the state, as they do when accounts are created.
+			rootKeys[hash] = state[hash]
+			return nil
+		})
+	})
+	if err != nil {
+		return blockHash, err
+	}
+
+	for hash := range State {
+		delete(State, hash)
+	}
+	for hash, acc := range state {
+		State[hash] = acc
+	}
+	for hash := range RootKeys {
+		delete(RootKeys, hash)
+	}
+	for hash, acc := range rootKeys {
+		RootKeys[hash] = acc
+	}
+
+	return blockHash, nil
+}
+
+//Publishes the state tree of the last closed block. The tree is immutable, it can be read without holding the miner's
+//validation lock while the miner already applies the next block to the State.
+func WriteClosedStateTree(block *protocol.Block, tree *protocol.StateTree) {
+	closedStateTreeMutex.Lock()
+	defer closedStateTreeMutex.Unlock()
+
+	closedStateBlock = block
+	closedStateTree = tree
+}
+
+//Returns the last closed block and its state tree, or nil if no tree was published yet.
+func ReadClosedStateTree() (block *protocol.Block, tree *protocol.StateTree) {
+	closedStateTreeMutex.Lock()
+	defer closedStateTreeMutex.Unlock()
+
+	return closedStateBlock, closedStateTree
+}
@@ -33,6 +33,9 @@ var (
 	openFundsTxBeforeAggregationMutex	= &sync.Mutex{}
 	txcntToTxMapMutex					= &sync.Mutex{}
 	ReceivedBlockStashMutex				= &sync.Mutex{}
+	closedStateBlock					*protocol.Block
+	closedStateTree						*protocol.StateTree
+	closedStateTreeMutex				= &sync.Mutex{}
 )
 
 const (
@@ -133,6 +136,34 @@ func Init(dbname string, bootstrapIpport string) {
 		}
 		return nil
 	})
+	db.Update(func(tx *bolt.Tx) error {
+		_, err = tx.CreateBucket([]byte("state"))
+		if err != nil {
+			return fmt.Errorf(ERROR_MSG+"Create bucket: %s", err)
+		}
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		_, err = tx.CreateBucket([]byte("journals"))
+		if err != nil {
+			return fmt.Errorf(ERROR_MSG+"Create bucket: %s", err)
+		}
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		_, err = tx.CreateBucket([]byte("receipts"))
+		if err != nil {
+			return fmt.Errorf(ERROR_MSG+"Create bucket: %s", err)
+		}
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		_, err = tx.CreateBucket([]byte("contracthistory"))
+		if err != nil {
+			return fmt.Errorf(ERROR_MSG+"Create bucket: %s", err)
+		}
+		return nil
+	})
 }
 
 func TearDown() {
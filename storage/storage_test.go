@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
 )
 
 //In-memory, k/v storage is tested with the test below
@@ -295,4 +296,271 @@ func TestReadWriteDeleteBlock(t *testing.T) {
 	if ReadLastClosedBlock() != nil {
 		t.Error("Failed to delete last closed block from storage.\n")
 	}
-}
\ No newline at end of file
+}
+
+func TestReadClosedBlockByHeight(t *testing.T) {
+	DeleteAll()
+
+	var blocks []*protocol.Block
+	prevHash := [32]byte{}
+	for height := uint32(0); height < 5; height++ {
+		block := protocol.NewBlock(prevHash, height)
+		block.Timestamp = int64(height)
+		block.Hash = block.HashBlock()
+		block.HashWithoutTx = block.Hash
+		block.PrevHashWithoutTx = prevHash
+		prevHash = block.Hash
+		blocks = append(blocks, block)
+	}
+
+	//Block 2 only exists without its transactions.
+	for i, block := range blocks {
+		if i == 2 {
+			WriteClosedBlockWithoutTx(block)
+		} else {
+			WriteClosedBlock(block)
+		}
+	}
+	WriteLastClosedBlock(blocks[4])
+
+	for _, block := range blocks {
+		readBlock := ReadClosedBlockByHeight(block.Height)
+		if readBlock == nil || readBlock.Hash != block.Hash {
+			t.Errorf("Block at height %v could not be read: %v\n", block.Height, readBlock)
+		}
+	}
+
+	if block := ReadClosedBlockByHeight(5); block != nil {
+		t.Errorf("Expected no block at height 5 but was %v\n", block)
+	}
+
+	entries := make(map[string]int)
+	for _, bucket := range ReadBucketStats() {
+		entries[bucket.Name] = bucket.Entries
+	}
+	if entries["closedblocks"] != 4 || entries["closedblockswithouttx"] != 1 {
+		t.Errorf("Expected 4 closed blocks and 1 closed block without tx but was %v and %v\n", entries["closedblocks"], entries["closedblockswithouttx"])
+	}
+}
+
+func TestWriteLastClosedBlockWithState(t *testing.T) {
+	DeleteAll()
+
+	stateBefore, rootKeysBefore := State, RootKeys
+	defer func() {
+		State, RootKeys = stateBefore, rootKeysBefore
+	}()
+	State = make(map[[32]byte]*protocol.Account)
+	RootKeys = make(map[[32]byte]*protocol.Account)
+
+	accAHash, rootAccHash := accA.Hash(), rootAcc.Hash()
+	State[accAHash] = &protocol.Account{Address: accA.Address, Balance: 100, TxCnt: 2}
+	State[rootAccHash] = &protocol.Account{Address: rootAcc.Address, Balance: 1000}
+	RootKeys[rootAccHash] = State[rootAccHash]
+
+	block := protocol.NewBlock([32]byte{}, 1)
+	block.Hash = block.HashBlock()
+	if err := WriteLastClosedBlockWithState(block, []byte("meta")); err != nil {
+		t.Fatalf("State could not be written: %v\n", err)
+	}
+
+	//Accounts removed from the state must not be loaded again.
+	delete(State, accAHash)
+	State[rootAccHash].Balance = 0
+	secondBlock := protocol.NewBlock(block.Hash, 2)
+	secondBlock.Hash = secondBlock.HashBlock()
+	WriteLastClosedBlockWithState(secondBlock, []byte("meta2"))
+	State[accAHash] = &protocol.Account{Address: accA.Address}
+
+	blockHash, err := LoadState()
+	if err != nil {
+		t.Fatalf("State could not be loaded: %v\n", err)
+	}
+	if meta := ReadStateMeta(); string(meta) != "meta2" {
+		t.Errorf("Expected metadata 'meta2' but was '%s'\n", meta)
+	}
+	if blockHash != secondBlock.Hash || ReadLastClosedBlock().Hash != secondBlock.Hash {
+		t.Errorf("Persisted state does not correspond to the last closed block: %x vs. %x\n", blockHash, ReadLastClosedBlock().Hash)
+	}
+	if len(State) != 1 || State[accAHash] != nil {
+		t.Errorf("Expected only the root account in the state but was %v\n", State)
+	}
+	if RootKeys[rootAccHash] != State[rootAccHash] || State[rootAccHash].Balance != 0 {
+		t.Errorf("Root account was not restored correctly: %v\n", RootKeys[rootAccHash])
+	}
+
+	DeleteState()
+	if _, err := LoadState(); err == nil {
+		t.Errorf("Expected an error when loading a deleted state\n")
+	}
+}
+
+func TestWriteLastClosedBlockWithStateChanges(t *testing.T) {
+	DeleteAll()
+
+	stateBefore, rootKeysBefore := State, RootKeys
+	defer func() {
+		State, RootKeys = stateBefore, rootKeysBefore
+	}()
+	State = make(map[[32]byte]*protocol.Account)
+	RootKeys = make(map[[32]byte]*protocol.Account)
+
+	accAHash, accBHash, rootAccHash := accA.Hash(), accB.Hash(), rootAcc.Hash()
+	State[accAHash] = &protocol.Account{Address: accA.Address, Balance: 100}
+	State[accBHash] = &protocol.Account{Address: accB.Address, Balance: 200}
+
+	block := protocol.NewBlock([32]byte{}, 1)
+	block.Hash = block.HashBlock()
+	secondBlock := protocol.NewBlock(block.Hash, 2)
+	secondBlock.Hash = secondBlock.HashBlock()
+
+	if err := WriteLastClosedBlockWithStateChanges(secondBlock, []byte("meta2"), block.Hash, nil); err == nil {
+		t.Errorf("Expected changes to be rejected without a persisted state\n")
+	}
+	WriteLastClosedBlockWithState(block, []byte("meta"))
+
+	//Only the changed accounts are written, the change of account B is not listed.
+	State[accAHash].Balance = 50
+	State[accBHash].Balance = 300
+	delete(State, accAHash)
+	State[rootAccHash] = &protocol.Account{Address: rootAcc.Address, Balance: 1000}
+	RootKeys[rootAccHash] = State[rootAccHash]
+
+	if err := WriteLastClosedBlockWithStateChanges(secondBlock, []byte("meta2"), secondBlock.Hash, nil); err == nil {
+		t.Errorf("Expected changes to be rejected if the persisted state belongs to another block\n")
+	}
+	if err := WriteLastClosedBlockWithStateChanges(secondBlock, []byte("meta2"), block.Hash, [][32]byte{accAHash, rootAccHash}); err != nil {
+		t.Fatalf("State changes could not be written: %v\n", err)
+	}
+
+	blockHash, err := LoadState()
+	if err != nil {
+		t.Fatalf("State could not be loaded: %v\n", err)
+	}
+	if blockHash != secondBlock.Hash || ReadLastClosedBlock().Hash != secondBlock.Hash || string(ReadStateMeta()) != "meta2" {
+		t.Errorf("Persisted state does not correspond to the second block: %x\n", blockHash)
+	}
+	if len(State) != 2 || State[accAHash] != nil || State[accBHash].Balance != 200 {
+		t.Errorf("Expected the unchanged account B and the root account in the state but was %v\n", State)
+	}
+	if RootKeys[rootAccHash] != State[rootAccHash] || State[rootAccHash].Balance != 1000 {
+		t.Errorf("Root account was not persisted correctly: %v\n", RootKeys[rootAccHash])
+	}
+}
+
+func TestLoadState_UndecodableAccount(t *testing.T) {
+	DeleteAll()
+
+	stateBefore, rootKeysBefore := State, RootKeys
+	defer func() {
+		State, RootKeys = stateBefore, rootKeysBefore
+	}()
+	State = map[[32]byte]*protocol.Account{accA.Hash(): {Address: accA.Address}}
+	RootKeys = make(map[[32]byte]*protocol.Account)
+
+	block := protocol.NewBlock([32]byte{}, 1)
+	block.Hash = block.HashBlock()
+	WriteLastClosedBlockWithState(block, nil)
+
+	accAHash := accA.Hash()
+	db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("state")).Bucket(stateAccountsKey).Put(accAHash[:], State[accAHash].Encode()[:10])
+	})
+
+	if _, err := LoadState(); err == nil {
+		t.Errorf("Expected an error when loading an undecodable account\n")
+	}
+	if len(State) != 1 || State[accAHash] == nil {
+		t.Errorf("Expected the state to be unchanged but was %v\n", State)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	blockHash := [32]byte{'b'}
+	journal := &Journal{
+		Staking:  []StakingJournalEntry{{Account: [32]byte{'a'}, StakingBlockHeight: 7}},
+		Contract: []ContractJournalEntry{{Account: [32]byte{'b'}, Index: 1, OldValue: []byte{1}, NewValue: []byte{2}}},
+	}
+
+	if err := WriteJournal(blockHash, journal); err != nil {
+		t.Fatalf("Journal could not be written: %v\n", err)
+	}
+	if read := ReadJournal(blockHash); !reflect.DeepEqual(read, journal) {
+		t.Errorf("Read journal does not match: %v vs. %v\n", read, journal)
+	}
+
+	DeleteJournal(blockHash)
+	if ReadJournal(blockHash) != nil {
+		t.Errorf("Journal was not deleted\n")
+	}
+}
+
+func TestReceipts(t *testing.T) {
+	receiptA := &protocol.Receipt{TxHash: [32]byte{'a'}, Events: []protocol.Event{{Topics: []protocol.ByteArray{{'t'}}}}}
+	receiptB := &protocol.Receipt{TxHash: [32]byte{'b'}, GasUsed: 5}
+
+	for _, receipt := range []*protocol.Receipt{receiptA, receiptB} {
+		if err := WriteReceipt(receipt); err != nil {
+			t.Fatalf("Receipt could not be written: %v\n", err)
+		}
+	}
+
+	if read := ReadReceipt(receiptB.TxHash); !reflect.DeepEqual(read, receiptB) {
+		t.Errorf("Read receipt does not match: %v vs. %v\n", read, receiptB)
+	}
+	if read := ReadReceiptsByTopic([]byte{'t'}); len(read) != 1 || read[0].TxHash != receiptA.TxHash {
+		t.Errorf("Expected only receipt %x with the topic but got %v\n", receiptA.TxHash[0:8], read)
+	}
+
+	DeleteReceipt(receiptA.TxHash)
+	DeleteReceipt(receiptB.TxHash)
+	if ReadReceipt(receiptA.TxHash) != nil || len(ReadReceiptsByTopic([]byte{'t'})) != 0 {
+		t.Errorf("Receipt was not deleted\n")
+	}
+}
+
+func TestContractHistory(t *testing.T) {
+	account, other := [32]byte{'a'}, [32]byte{'b'}
+	upgrade := &protocol.ContractHistoryEntry{Account: account, Type: protocol.CONTRACT_UPGRADE, BlockHeight: 300, TxHash: [32]byte{'u'}}
+	selfDestruct := &protocol.ContractHistoryEntry{Account: account, Type: protocol.CONTRACT_SELFDESTRUCT, BlockHeight: 301, TxHash: [32]byte{'s'}, Amount: 5}
+	otherUpgrade := &protocol.ContractHistoryEntry{Account: other, Type: protocol.CONTRACT_UPGRADE, BlockHeight: 1, TxHash: [32]byte{'o'}}
+
+	for _, entry := range []*protocol.ContractHistoryEntry{selfDestruct, otherUpgrade, upgrade} {
+		if err := WriteContractHistory(entry); err != nil {
+			t.Fatalf("Contract history could not be written: %v\n", err)
+		}
+	}
+
+	expected := []*protocol.ContractHistoryEntry{upgrade, selfDestruct}
+	if read := ReadContractHistory(account); !reflect.DeepEqual(read, expected) {
+		t.Errorf("Read contract history does not match: %v vs. %v\n", read, expected)
+	}
+
+	DeleteContractHistory(selfDestruct.Account, selfDestruct.BlockHeight, selfDestruct.TxHash)
+	DeleteContractHistory(upgrade.Account, upgrade.BlockHeight, upgrade.TxHash)
+	DeleteContractHistory(otherUpgrade.Account, otherUpgrade.BlockHeight, otherUpgrade.TxHash)
+	if read := ReadContractHistory(account); len(read) != 0 {
+		t.Errorf("Contract history was not deleted: %v\n", read)
+	}
+}
+
+func TestCopyState(t *testing.T) {
+	defer func(state, rootKeys map[[32]byte]*protocol.Account) {
+		State, RootKeys = state, rootKeys
+	}(State, RootKeys)
+
+	accHash := [32]byte{'a'}
+	State = map[[32]byte]*protocol.Account{accHash: {Balance: 1, ContractVariables: []protocol.ByteArray{{1}}}}
+	RootKeys = map[[32]byte]*protocol.Account{accHash: State[accHash]}
+
+	state, rootKeys := CopyState()
+	state[accHash].Balance = 2
+	state[accHash].ContractVariables[0][0] = 2
+
+	if State[accHash].Balance != 1 || State[accHash].ContractVariables[0][0] != 1 {
+		t.Errorf("Changes to the copy affected the state: %v\n", State[accHash])
+	}
+	if rootKeys[accHash] != state[accHash] {
+		t.Errorf("Root keys of the copy don't share the accounts of the copied state\n")
+	}
+}
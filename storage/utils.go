@@ -39,6 +39,33 @@ func GetAccount(hash [32]byte) (acc *protocol.Account, err error) {
 	}
 }
 
+//Returns a deep copy of State and RootKeys. As in the original, the root keys share the accounts of the state.
+func CopyState() (state map[[32]byte]*protocol.Account, rootKeys map[[32]byte]*protocol.Account) {
+	state = make(map[[32]byte]*protocol.Account)
+	for hash, acc := range State {
+		state[hash] = CopyAccount(acc)
+	}
+
+	rootKeys = make(map[[32]byte]*protocol.Account)
+	for hash := range RootKeys {
+		rootKeys[hash] = state[hash]
+	}
+
+	return state, rootKeys
+}
+
+//Returns a deep copy of the account, changes to the copy's contract variables don't affect the original.
+func CopyAccount(acc *protocol.Account) *protocol.Account {
+	accCopy := *acc
+	accCopy.Contract = append([]byte(nil), acc.Contract...)
+	accCopy.ContractVariables = nil
+	for _, variable := range acc.ContractVariables {
+		accCopy.ContractVariables = append(accCopy.ContractVariables, append(protocol.ByteArray(nil), variable...))
+	}
+
+	return &accCopy
+}
+
 func GetRootAccount(hash [32]byte) (acc *protocol.Account, err error) {
 	if IsRootKey(hash) {
 		acc, err = GetAccount(hash)
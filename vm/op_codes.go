@@ -52,6 +52,22 @@ const (
 	CHECKSIG
 	ERRHALT
 	HALT
+	LOG0 // Event without topics, the data is on top of the stack
+	LOG1 // Event with one topic below the data
+	LOG2
+	LOG3
+	HEIGHT        // Height of the block containing the transaction
+	TIMESTAMP     // Timestamp of the block containing the transaction
+	PREVHASH      // Hash of the previous block
+	TRANSFER      // Sends coins from the contract account to the address below the amount
+	CONCAT        // Appends the top of stack to the element below
+	SLICE         // Slice of the element below the start and length
+	CMP           // Compares byte strings lexicographically, unlike EQ the length matters
+	ITOB          // Integer to unsigned big-endian bytes of the width given as argument
+	BTOI          // Unsigned big-endian bytes to integer
+	CHECKMULTISIG // M of N signatures of a message, the keys and signatures are on the stack
+	CHECKSIGADDR  // Signature of a message by the key of an account hash
+	SELFDESTRUCT  // Removes the contract and sends its balance to the address on top of the stack, halts
 	//	MAPCONTAINSKEY
 )
 
@@ -89,8 +105,8 @@ var OpCodes = []OpCode{
 	{LTE, "lte", 0, nil, 1, 2},
 	{GTE, "gte", 0, nil, 1, 2},
 	{SHIFTL, "shiftl", 1, []int{BYTE}, 1, 2},
-	{SHIFTR, "shiftl", 1, []int{BYTE}, 1, 2},
-	{NOP, "nop", 0, nil, 1, 1},
+	{SHIFTR, "shiftr", 1, []int{BYTE}, 1, 2},
+	{NOP, "nop", 1, []int{BYTE}, 1, 1},
 	{JMP, "jmp", 1, []int{LABEL}, 1, 1},
 	{JMPIF, "jmpif", 1, []int{LABEL}, 1, 1},
 	{CALL, "call", 2, []int{LABEL, BYTE}, 1, 1},
@@ -123,4 +139,20 @@ var OpCodes = []OpCode{
 	{CHECKSIG, "checksig", 0, nil, 1, 2},
 	{ERRHALT, "errhalt", 0, nil, 0, 1},
 	{HALT, "halt", 0, nil, 0, 1},
+	{LOG0, "log0", 0, nil, 100, 2},
+	{LOG1, "log1", 0, nil, 200, 2},
+	{LOG2, "log2", 0, nil, 300, 2},
+	{LOG3, "log3", 0, nil, 400, 2},
+	{HEIGHT, "height", 0, nil, 1, 1},
+	{TIMESTAMP, "timestamp", 0, nil, 1, 1},
+	{PREVHASH, "prevhash", 0, nil, 1, 1},
+	{TRANSFER, "transfer", 0, nil, 1000, 2},
+	{CONCAT, "concat", 0, nil, 1, 2},
+	{SLICE, "slice", 0, nil, 1, 2},
+	{CMP, "cmp", 0, nil, 1, 2},
+	{ITOB, "itob", 1, []int{BYTE}, 1, 2},
+	{BTOI, "btoi", 0, nil, 1, 2},
+	{CHECKMULTISIG, "checkmultisig", 0, nil, 100, 2},
+	{CHECKSIGADDR, "checksigaddr", 0, nil, 100, 2},
+	{SELFDESTRUCT, "selfdestruct", 0, nil, 1000, 2},
 }
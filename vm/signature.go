@@ -0,0 +1,110 @@
+package vm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"math/big"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Maximum number of public keys of a multisig check
+const MAX_MULTISIG_KEYS = 16
+
+// Messages of the signature opcodes are hashed with SHA3-256 before the verification, i.e., the signer signs the hash
+func hashMessage(message []byte) []byte {
+	hash := sha3.Sum256(message)
+	return hash[:]
+}
+
+// Verifies the 64 byte signature (r and s) of the hash with the 64 byte address (x and y of the P-256 public key)
+func verifySignature(address []byte, hash []byte, signature []byte) bool {
+	pubKey := ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(address[:32]),
+		Y:     new(big.Int).SetBytes(address[32:]),
+	}
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+
+	return ecdsa.Verify(&pubKey, hash, r, s)
+}
+
+// Verifies M of N signatures, every signature must belong to a different key and the signatures must be in the order of
+// their keys, such that each key is tried at most once.
+func verifyMultiSig(addresses [][]byte, hash []byte, signatures [][]byte) bool {
+	key := 0
+	for _, signature := range signatures {
+		for key < len(addresses) && !verifySignature(addresses[key], hash, signature) {
+			key++
+		}
+		if key == len(addresses) {
+			return false
+		}
+		key++
+	}
+	return true
+}
+
+// Checks whether the signature of the hash was made by the key of the account, the account hash is derived from the
+// address like in the verification of transactions. The public key is recovered from the signature, since the 64 byte
+// signature has no recovery id, all candidates are tried.
+func verifySignatureOfAccount(account []byte, hash []byte, signature []byte) bool {
+	for _, pubKey := range recoverPublicKeys(hash, signature) {
+		accountHash := protocol.SerializeHashContent(crypto.GetAddressFromPubKey(pubKey))
+		if string(accountHash[:]) == string(account) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the P-256 public keys for which the signature of the hash is valid: Q = r^-1 * (s*R - e*G) for every point R
+// with r as x coordinate
+func recoverPublicKeys(hash []byte, signature []byte) (pubKeys []*ecdsa.PublicKey) {
+	curve := elliptic.P256()
+	params := curve.Params()
+
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if r.Sign() == 0 || s.Sign() == 0 || r.Cmp(params.N) >= 0 || s.Cmp(params.N) >= 0 {
+		return nil
+	}
+
+	rInverse := new(big.Int).ModInverse(r, params.N)
+	eX, eY := curve.ScalarBaseMult(hash)
+	eY.Sub(params.P, eY) // -e*G
+
+	// r is the x coordinate of R modulo N, x = r + N is possible as well since N < P
+	for _, x := range []*big.Int{new(big.Int).Set(r), new(big.Int).Add(r, params.N)} {
+		if x.Cmp(params.P) >= 0 {
+			continue
+		}
+
+		// y^2 = x^3 - 3x + b
+		ySquare := new(big.Int).Exp(x, big.NewInt(3), params.P)
+		ySquare.Sub(ySquare, new(big.Int).Mul(x, big.NewInt(3)))
+		ySquare.Add(ySquare, params.B)
+		ySquare.Mod(ySquare, params.P)
+
+		y := new(big.Int).ModSqrt(ySquare, params.P)
+		if y == nil {
+			continue
+		}
+
+		for _, rY := range []*big.Int{y, new(big.Int).Sub(params.P, y)} {
+			sX, sY := curve.ScalarMult(x, rY, s.Bytes())
+			sumX, sumY := curve.Add(sX, sY, eX, eY)
+			qX, qY := curve.ScalarMult(sumX, sumY, rInverse.Bytes())
+
+			// The point at infinity is not a valid public key
+			if qX.Sign() == 0 && qY.Sign() == 0 {
+				continue
+			}
+			pubKeys = append(pubKeys, &ecdsa.PublicKey{Curve: curve, X: qX, Y: qY})
+		}
+	}
+
+	return pubKeys
+}
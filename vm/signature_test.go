@@ -0,0 +1,38 @@
+package vm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestRecoverPublicKeys(t *testing.T) {
+	hash := hashMessage([]byte("message"))
+
+	for i := 0; i < 50; i++ {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		signature := signMessage(t, key, []byte("message"))
+
+		recovered := false
+		for _, pubKey := range recoverPublicKeys(hash, signature) {
+			if !ecdsa.Verify(pubKey, hash, new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+				t.Errorf("Recovered key %x, %x does not verify the signature", pubKey.X, pubKey.Y)
+			}
+			recovered = recovered || pubKey.X.Cmp(key.X) == 0 && pubKey.Y.Cmp(key.Y) == 0
+		}
+
+		if !recovered {
+			t.Errorf("Public key %x, %x has not been recovered from signature %x", key.X, key.Y, signature)
+		}
+	}
+}
+
+func TestRecoverPublicKeys_InvalidSignature(t *testing.T) {
+	hash := hashMessage([]byte("message"))
+
+	if pubKeys := recoverPublicKeys(hash, make([]byte, 64)); len(pubKeys) != 0 {
+		t.Errorf("Expected no keys for a zero signature but got %v", len(pubKeys))
+	}
+}
@@ -0,0 +1,312 @@
+package vm
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Hooks which are called by Exec before and after every instruction. The after hook gets the step of the before hook
+// with the state after the instruction, it is called for the last instruction as well, i.e., when the contract halts
+// or fails. Returning an error from the before hook aborts the execution.
+type Tracer interface {
+	BeforeOp(step TraceStep) error
+	AfterOp(step TraceStep)
+}
+
+// State of the VM at an instruction
+type TraceStep struct {
+	Pc          int      `json:"pc"`
+	OpCode      string   `json:"opCode"`
+	Args        []string `json:"args"`
+	Stack       []string `json:"stack"` // Top of stack first, in hex
+	MemoryUsage uint32   `json:"memoryUsage"`
+	MemoryMax   uint32   `json:"memoryMax"`
+	Fee         uint64   `json:"fee"` // Remaining fee
+	Depth       int      `json:"depth"`
+	Error       string   `json:"error,omitempty"` // Set after the instruction if it failed
+}
+
+// Returns the step of the instruction at the program counter, nil if there is none
+func (vm *VM) traceBefore() *TraceStep {
+	if vm.pc >= len(vm.code) {
+		return nil
+	}
+
+	step := &TraceStep{Pc: vm.pc, Depth: vm.depth}
+	vm.traceState(step)
+
+	byteCode := int(vm.code[vm.pc])
+	if len(OpCodes) <= byteCode {
+		step.OpCode = "invalid opcode"
+		return step
+	}
+
+	opCode := OpCodes[byteCode]
+	step.OpCode = opCode.Name
+
+	counter := vm.pc + 1
+	for _, argType := range opCode.ArgTypes {
+		var size int
+		switch argType {
+		case BYTES:
+			if counter < len(vm.code) {
+				size = int(vm.code[counter]) + 1
+				counter++
+			}
+		case BYTE:
+			size = 1
+		case ADDR:
+			size = 32
+		case LABEL:
+			size = 2
+		}
+
+		if counter+size > len(vm.code) {
+			step.Args = append(step.Args, "out of bounds")
+			break
+		}
+
+		args := vm.code[counter : counter+size]
+		switch argType {
+		case BYTES, ADDR:
+			step.Args = append(step.Args, fmt.Sprintf("%x", args))
+		case BYTE:
+			step.Args = append(step.Args, fmt.Sprintf("%v", args[0]))
+		case LABEL:
+			step.Args = append(step.Args, fmt.Sprintf("%v", ByteArrayToInt(args)))
+		}
+		counter += size
+	}
+
+	return step
+}
+
+// Calls the after hook with the state after the instruction of the step
+func (vm *VM) traceAfter(step *TraceStep, success bool) {
+	after := *step
+	vm.traceState(&after)
+	if !success {
+		after.Error = vm.GetErrorMsg()
+	}
+	vm.tracer.AfterOp(after)
+}
+
+func (vm *VM) traceState(step *TraceStep) {
+	stack := vm.evaluationStack
+	step.Stack = nil
+	for i := len(stack.Stack) - 1; i >= 0; i-- {
+		step.Stack = append(step.Stack, fmt.Sprintf("%x", stack.Stack[i]))
+	}
+	step.MemoryUsage = stack.memoryUsage
+	step.MemoryMax = stack.memoryMax
+	step.Fee = vm.fee
+}
+
+// Writes every step as a JSON line, the event is either "before" or "after"
+type JSONTracer struct {
+	encoder *json.Encoder
+}
+
+type jsonTraceEvent struct {
+	Event string `json:"event"`
+	TraceStep
+}
+
+func NewJSONTracer(w io.Writer) *JSONTracer {
+	return &JSONTracer{json.NewEncoder(w)}
+}
+
+func (t *JSONTracer) BeforeOp(step TraceStep) error {
+	t.encoder.Encode(jsonTraceEvent{"before", step})
+	return nil
+}
+
+func (t *JSONTracer) AfterOp(step TraceStep) {
+	t.encoder.Encode(jsonTraceEvent{"after", step})
+}
+
+// Records all steps, e.g., to assert on the execution path in tests
+type TraceRecorder struct {
+	Steps   []TraceStep // State before the instructions
+	Results []TraceStep // State after the instructions
+}
+
+func (t *TraceRecorder) BeforeOp(step TraceStep) error {
+	t.Steps = append(t.Steps, step)
+	return nil
+}
+
+func (t *TraceRecorder) AfterOp(step TraceStep) {
+	t.Results = append(t.Results, step)
+}
+
+// Returns the program counters of the executed instructions
+func (t *TraceRecorder) Path() []int {
+	path := make([]int, len(t.Steps))
+	for i, step := range t.Steps {
+		path[i] = step.Pc
+	}
+	return path
+}
+
+// Stops before instructions at a breakpoint and before every instruction while single-stepping. While stopped, it reads
+// commands line by line:
+//	s, step          execute the next instruction and stop again
+//	c, continue      run until the next breakpoint
+//	b, break <pc>    add a breakpoint
+//	d, delete <pc>   remove a breakpoint
+//	p, print         print the current step as JSON
+//	q, quit          abort the execution
+// Breakpoints apply to called contracts as well. If the input ends, the execution continues without stopping.
+type Debugger struct {
+	in          *bufio.Scanner
+	out         io.Writer
+	breakpoints map[int]bool
+	stepping    bool
+	detached    bool
+}
+
+func NewDebugger(in io.Reader, out io.Writer) *Debugger {
+	return &Debugger{
+		in:          bufio.NewScanner(in),
+		out:         out,
+		breakpoints: make(map[int]bool),
+	}
+}
+
+func (d *Debugger) AddBreakpoint(pc int) {
+	d.breakpoints[pc] = true
+}
+
+func (d *Debugger) RemoveBreakpoint(pc int) {
+	delete(d.breakpoints, pc)
+}
+
+// Stops before the next instruction
+func (d *Debugger) Step() {
+	d.stepping = true
+}
+
+func (d *Debugger) BeforeOp(step TraceStep) error {
+	if d.detached || !d.stepping && !d.breakpoints[step.Pc] {
+		return nil
+	}
+
+	fmt.Fprintf(d.out, "%04d (depth %v): %v\n", step.Pc, step.Depth, strings.Join(append([]string{step.OpCode}, step.Args...), " "))
+	fmt.Fprintf(d.out, "\tstack: %v, fee: %v\n", step.Stack, step.Fee)
+
+	for {
+		fmt.Fprint(d.out, "> ")
+		if !d.in.Scan() {
+			d.detached = true
+			return nil
+		}
+
+		command := strings.Fields(d.in.Text())
+		if len(command) == 0 {
+			continue
+		}
+
+		switch command[0] {
+		case "s", "step":
+			d.stepping = true
+			return nil
+		case "c", "continue":
+			d.stepping = false
+			return nil
+		case "b", "break", "d", "delete":
+			if len(command) != 2 {
+				fmt.Fprintf(d.out, "usage: %v <pc>\n", command[0])
+				continue
+			}
+			pc, err := strconv.Atoi(command[1])
+			if err != nil {
+				fmt.Fprintf(d.out, "invalid pc: %v\n", command[1])
+				continue
+			}
+			if command[0] == "b" || command[0] == "break" {
+				d.AddBreakpoint(pc)
+			} else {
+				d.RemoveBreakpoint(pc)
+			}
+		case "p", "print":
+			encoded, _ := json.Marshal(step)
+			fmt.Fprintf(d.out, "%s\n", encoded)
+		case "q", "quit":
+			return errors.New("Aborted by debugger")
+		default:
+			fmt.Fprintf(d.out, "unknown command: %v\n", command[0])
+		}
+	}
+}
+
+func (d *Debugger) AfterOp(step TraceStep) {
+	if d.stepping && !d.detached {
+		if step.Error != "" {
+			fmt.Fprintf(d.out, "\terror: %v\n", step.Error)
+		}
+		fmt.Fprintf(d.out, "\t=> stack: %v, fee: %v\n", step.Stack, step.Fee)
+	}
+}
+
+// Gas used by all executions of an opcode
+type OpCodeGas struct {
+	Count int    `json:"count"`
+	Gas   uint64 `json:"gas"`
+}
+
+// Sums up the gas used per opcode. The gas of CALLEXT does not include the gas used by the called contract, the
+// instructions of the callee are accounted for themselves. Aborts the execution after maxSteps instructions, unless it
+// is 0.
+type GasProfiler struct {
+	OpCodes  map[string]OpCodeGas
+	Steps    int
+	maxSteps int
+	frames   []gasFrame
+}
+
+type gasFrame struct {
+	fee       uint64 // Remaining fee before the instruction
+	nestedGas uint64 // Gas used by the instructions of called contracts
+}
+
+func NewGasProfiler(maxSteps int) *GasProfiler {
+	return &GasProfiler{OpCodes: make(map[string]OpCodeGas), maxSteps: maxSteps}
+}
+
+func (p *GasProfiler) BeforeOp(step TraceStep) error {
+	if p.maxSteps > 0 && p.Steps >= p.maxSteps {
+		return errors.New(fmt.Sprintf("Execution exceeds %v instructions", p.maxSteps))
+	}
+	p.Steps++
+	p.frames = append(p.frames, gasFrame{fee: step.Fee})
+	return nil
+}
+
+func (p *GasProfiler) AfterOp(step TraceStep) {
+	frame := p.frames[len(p.frames)-1]
+	p.frames = p.frames[:len(p.frames)-1]
+
+	used := frame.fee - step.Fee
+	if len(p.frames) > 0 {
+		p.frames[len(p.frames)-1].nestedGas += used
+	}
+
+	gas := p.OpCodes[step.OpCode]
+	gas.Count++
+	gas.Gas += used - frame.nestedGas
+	p.OpCodes[step.OpCode] = gas
+}
+
+// Returns the gas used by all instructions
+func (p *GasProfiler) GasUsed() (total uint64) {
+	for _, gas := range p.OpCodes {
+		total += gas.Gas
+	}
+	return total
+}
@@ -0,0 +1,218 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+func TestTraceRecorder(t *testing.T) {
+	calleeAddress := [32]byte{2}
+	callee := &protocol.Account{Contract: []byte{PUSH, 0, 7, HALT}}
+
+	code := append(callExtCode(calleeAddress, 0, PUSH, 1, 0, 125, JMP, 0, 8, NOP), HALT)
+
+	vm := NewTestVM([]byte{})
+	mc := NewMockContext(code)
+	mc.Fee = 100000
+	mc.SetAccountReader(accountReader(map[[32]byte]*protocol.Account{calleeAddress: callee}))
+	vm.context = mc
+
+	recorder := new(TraceRecorder)
+	vm.SetTracer(recorder)
+	if !vm.Exec(false) {
+		t.Fatalf("Expected the execution to succeed but failed with '%v'", vm.GetErrorMsg())
+	}
+
+	expected := []TraceStep{
+		{Pc: 0, OpCode: "push", Args: []string{"007d"}, Fee: 100000},
+		{Pc: 4, OpCode: "jmp", Args: []string{"8"}, Stack: []string{"007d"}, MemoryUsage: 2, Fee: 99999},
+		{Pc: 8, OpCode: "callext", Args: []string{fmt.Sprintf("%x", calleeAddress), "1", "2", "3", "4", "0"}, Stack: []string{"007d"}, MemoryUsage: 2, Fee: 99998},
+		{Pc: 0, OpCode: "push", Args: []string{"07"}, Fee: 98998, Depth: 1},
+		{Pc: 3, OpCode: "halt", Stack: []string{"07"}, MemoryUsage: 1, Fee: 98997, Depth: 1},
+		{Pc: 46, OpCode: "halt", Stack: []string{"07", "007d"}, MemoryUsage: 3, Fee: 98997},
+	}
+
+	if len(recorder.Steps) != len(expected) || len(recorder.Results) != len(expected) {
+		t.Fatalf("Expected %v steps and results but were %v and %v", len(expected), len(recorder.Steps), len(recorder.Results))
+	}
+
+	for i, step := range recorder.Steps {
+		step.MemoryMax = 0
+		if !reflect.DeepEqual(expected[i], step) {
+			t.Errorf("Expected step %v to be '%v' but was '%v'", i, expected[i], step)
+		}
+	}
+
+	// The results are in the order in which the instructions finished
+	if result := recorder.Results[0]; result.Pc != 0 || !reflect.DeepEqual(result.Stack, []string{"007d"}) || result.Fee != 99999 {
+		t.Errorf("Unexpected result of the first instruction: %v", result)
+	}
+	if result := recorder.Results[4]; result.Pc != 8 || !reflect.DeepEqual(result.Stack, []string{"07", "007d"}) {
+		t.Errorf("Expected the callext result after the results of the callee but was %v", result)
+	}
+
+	if path := recorder.Path(); !reflect.DeepEqual(path, []int{0, 4, 8, 0, 3, 46}) {
+		t.Errorf("Unexpected execution path %v", path)
+	}
+}
+
+func TestTraceRecorder_Failure(t *testing.T) {
+	code := []byte{
+		PUSH, 0, 1,
+		ADD,
+		HALT,
+	}
+
+	vm := NewVM(NewMockContext(code))
+	recorder := new(TraceRecorder)
+	vm.SetTracer(recorder)
+
+	if vm.Exec(false) {
+		t.Fatal("Expected the execution to fail")
+	}
+
+	last := recorder.Results[len(recorder.Results)-1]
+	if last.OpCode != "add" || last.Error != vm.GetErrorMsg() {
+		t.Errorf("Expected the failing add with error '%v' as last result but was %v", vm.GetErrorMsg(), last)
+	}
+}
+
+func TestJSONTracer(t *testing.T) {
+	code := []byte{
+		PUSH, 0, 1,
+		HALT,
+	}
+
+	var out bytes.Buffer
+	vm := NewVM(NewMockContext(code))
+	vm.SetTracer(NewJSONTracer(&out))
+	vm.Exec(false)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 lines but were %v: %v", len(lines), out.String())
+	}
+
+	var event map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event["event"] != "after" || event["opCode"] != "push" || event["stack"].([]interface{})[0] != "01" {
+		t.Errorf("Unexpected event: %v", lines[1])
+	}
+}
+
+func TestDebugger(t *testing.T) {
+	code := []byte{
+		PUSH, 1, 0, 1,
+		PUSH, 1, 0, 2,
+		ADD,
+		PUSH, 1, 0, 3,
+		HALT,
+	}
+
+	// Stops at the breakpoint, steps once, adds a breakpoint and continues to it
+	var out bytes.Buffer
+	debugger := NewDebugger(strings.NewReader("p\ns\nb 13\nc\nc\n"), &out)
+	debugger.AddBreakpoint(4)
+
+	vm := NewVM(NewMockContext(code))
+	vm.SetTracer(debugger)
+	if !vm.Exec(false) {
+		t.Fatalf("Expected the execution to succeed but failed with '%v'", vm.GetErrorMsg())
+	}
+
+	stops := strings.Count(out.String(), "> ") - strings.Count(out.String(), "=> ")
+	if stops != 5 {
+		t.Errorf("Expected 5 prompts but were %v:\n%v", stops, out.String())
+	}
+	for _, expected := range []string{"0004 (depth 0): push 0002", "0008 (depth 0): add", "0013 (depth 0): halt", `"pc":4`} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected '%v' in output:\n%v", expected, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "0009 (depth 0)") {
+		t.Errorf("Debugger stopped after continue:\n%v", out.String())
+	}
+}
+
+func TestDebugger_Quit(t *testing.T) {
+	code := []byte{
+		PUSH, 0, 1,
+		HALT,
+	}
+
+	debugger := NewDebugger(strings.NewReader("q\n"), new(bytes.Buffer))
+	debugger.Step()
+
+	vm := NewVM(NewMockContext(code))
+	vm.SetTracer(debugger)
+	if vm.Exec(false) {
+		t.Fatal("Expected the aborted execution to fail")
+	}
+
+	if vm.GetErrorMsg() != "vm.exec(): Aborted by debugger" {
+		t.Errorf("Unexpected error '%v'", vm.GetErrorMsg())
+	}
+}
+
+func TestGasProfiler(t *testing.T) {
+	calleeAddress := [32]byte{2}
+	callee := &protocol.Account{Contract: []byte{PUSH, 1, 0, 7, HALT}}
+
+	code := append(callExtCode(calleeAddress, 1, PUSH, 1, 0, 125, PUSH, 1, 0, 1), ADD, HALT)
+
+	vm := NewTestVM([]byte{})
+	mc := NewMockContext(code)
+	mc.Fee = 100000
+	mc.SetAccountReader(accountReader(map[[32]byte]*protocol.Account{calleeAddress: callee}))
+	vm.context = mc
+
+	profiler := NewGasProfiler(0)
+	vm.SetTracer(profiler)
+	if !vm.Exec(false) {
+		t.Fatalf("Expected the execution to succeed but failed with '%v'", vm.GetErrorMsg())
+	}
+
+	// The callee's push is accounted for the push opcode, callext pops one argument
+	expected := map[string]OpCodeGas{
+		"push":    {3, 3},
+		"callext": {1, 1002},
+		"add":     {1, 5},
+		"halt":    {2, 0},
+	}
+	if !reflect.DeepEqual(expected, profiler.OpCodes) {
+		t.Errorf("Expected gas per opcode to be %v but was %v", expected, profiler.OpCodes)
+	}
+
+	if used := 100000 - vm.GetRemainingFee(); profiler.GasUsed() != used {
+		t.Errorf("Expected the total gas to be %v but was %v", used, profiler.GasUsed())
+	}
+}
+
+func TestGasProfiler_MaxSteps(t *testing.T) {
+	code := []byte{
+		PUSH, 0, 1,
+		POP,
+		JMP, 0, 0,
+		HALT,
+	}
+
+	vm := NewVM(NewMockContext(code))
+	vm.context.(*MockContext).Fee = 1 << 62
+	vm.SetTracer(NewGasProfiler(100))
+
+	if vm.Exec(false) {
+		t.Fatal("Expected the endless loop to be aborted")
+	}
+
+	if !strings.HasSuffix(vm.GetErrorMsg(), "Execution exceeds 100 instructions") {
+		t.Errorf("Unexpected error '%v'", vm.GetErrorMsg())
+	}
+}
@@ -0,0 +1,92 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+// An instruction of the bytecode with the arguments as they are laid out by the argument types of the opcode
+type instruction struct {
+	pc     int
+	opCode OpCode
+	args   [][]byte
+}
+
+// Checks the bytecode of a contract before it is deployed with the given contract variables. The bytecode must only
+// consist of known opcodes with all of their arguments, jumps and calls must go to the start of an instruction and
+// the contract variables accessed with SSTORE and SLOAD must exist. This does not guarantee that the execution
+// succeeds, but rejects contracts which can't be executed as they are.
+func Verify(code []byte, contractVariables []protocol.ByteArray) error {
+	instructions, err := decodeInstructions(code)
+	if err != nil {
+		return err
+	}
+
+	starts := make(map[int]bool)
+	for _, instr := range instructions {
+		starts[instr.pc] = true
+	}
+
+	for _, instr := range instructions {
+		switch instr.opCode.code {
+		case JMP, JMPIF, CALL, CALLIF:
+			target := ByteArrayToInt(instr.args[0])
+			if (instr.opCode.code == CALL || instr.opCode.code == CALLIF) && target == 0 {
+				return errors.New(fmt.Sprintf("%v at %04d: Address 0 can't be called", instr.opCode.Name, instr.pc))
+			}
+			if !starts[target] {
+				return errors.New(fmt.Sprintf("%v at %04d: Target %04d is not the start of an instruction", instr.opCode.Name, instr.pc, target))
+			}
+
+		case SSTORE, SLOAD:
+			if index := int(instr.args[0][0]); index >= len(contractVariables) {
+				return errors.New(fmt.Sprintf("%v at %04d: Contract variable %v does not exist, the contract has %v", instr.opCode.Name, instr.pc, index, len(contractVariables)))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Splits the bytecode into instructions
+func decodeInstructions(code []byte) (instructions []instruction, err error) {
+	for pc := 0; pc < len(code); {
+		start := pc
+		if int(code[pc]) >= len(OpCodes) {
+			return nil, errors.New(fmt.Sprintf("Invalid opcode %v at %04d", code[pc], start))
+		}
+		opCode := OpCodes[code[pc]]
+		pc++
+
+		var args [][]byte
+		for _, argType := range opCode.ArgTypes {
+			var size int
+			switch argType {
+			case BYTES:
+				if pc >= len(code) {
+					return nil, errors.New(fmt.Sprintf("%v at %04d: Arguments are truncated", opCode.Name, start))
+				}
+				size = int(code[pc]) + 1
+				pc++
+			case BYTE:
+				size = 1
+			case LABEL:
+				size = 2
+			case ADDR:
+				size = 32
+			}
+
+			if pc+size > len(code) {
+				return nil, errors.New(fmt.Sprintf("%v at %04d: Arguments are truncated", opCode.Name, start))
+			}
+			args = append(args, code[pc:pc+size])
+			pc += size
+		}
+
+		instructions = append(instructions, instruction{start, opCode, args})
+	}
+
+	return instructions, nil
+}
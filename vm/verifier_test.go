@@ -0,0 +1,63 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+func TestVerify(t *testing.T) {
+	code := []byte{
+		PUSH, 1, 0, 5,
+		SLOAD, 1,
+		ADD,
+		DUP,
+		SSTORE, 1,
+		JMPIF, 0, 19,
+		CALL, 0, 20, 0,
+		NOP, 0,
+		HALT,
+		RET,
+	}
+
+	if err := Verify(code, []protocol.ByteArray{{0}, {0, 1}}); err != nil {
+		t.Errorf("Expected the contract to be valid but got '%v'", err)
+	}
+
+	if err := Verify([]byte{}, nil); err != nil {
+		t.Errorf("Expected the empty contract to be valid but got '%v'", err)
+	}
+}
+
+func TestVerify_Invalid(t *testing.T) {
+	variables := []protocol.ByteArray{{0}}
+	codes := map[string][]byte{
+		"Invalid opcode":              {PUSH, 0, 1, byte(len(OpCodes))},
+		"push at 0000: Arguments are": {PUSH, 2, 0, 1},
+		"push at 0003: Arguments are": {PUSH, 0, 1, PUSH},
+		"jmp at 0000: Arguments are":  {JMP, 0},
+		"callext at 0000":             {CALLEXT, 1, 2, 3},
+		"jmp at 0000: Target 0004":    {JMP, 0, 4, PUSH, 0, 1, HALT},
+		"jmpif at 0000: Target 0010":  {JMPIF, 0, 10, HALT},
+		"call at 0000: Target 0002":   {CALL, 0, 2, 0, HALT},
+		"callif at 0000: Address 0":   {CALLIF, 0, 0, 0, HALT},
+		"sstore at 0003":              {PUSH, 0, 1, SSTORE, 1, HALT},
+		"sload at 0000":               {SLOAD, 5, HALT},
+	}
+
+	for expected, code := range codes {
+		err := Verify(code, variables)
+		if err == nil {
+			t.Errorf("Expected '%v' to be rejected", code)
+		} else if !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("Expected error '%v...' for '%v' but was '%v'", expected, code, err)
+		}
+	}
+}
+
+func TestVerify_NoContractVariables(t *testing.T) {
+	if err := Verify([]byte{SLOAD, 0, HALT}, nil); err == nil {
+		t.Errorf("Expected SLOAD to be rejected without contract variables")
+	}
+}
@@ -1,31 +1,29 @@
 package vm
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"encoding/binary"
 	"errors"
-	"fmt"
 	"math/big"
+	"os"
+	"strconv"
 
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 
 	"golang.org/x/crypto/sha3"
 )
 
-type Context interface {
-	GetContract() []byte
-	GetContractVariable(index int) ([]byte, error)
-	SetContractVariable(index int, value []byte) error
-	GetAddress() [64]byte
-	GetIssuer() [32]byte
-	GetBalance() uint64
-	GetSender() [32]byte
-	GetAmount() uint64
-	GetTransactionData() []byte
-	GetFee() uint64
-	GetSig1() [64]byte
-}
+// The context a contract is executed in. It is declared in the protocol package, which cannot import this one, so
+// that protocol.Context can return it from NewCallContext.
+type Context = protocol.VMContext
+
+// Maximum size of an event topic
+const MAX_TOPIC_SIZE = 32
+
+// Maximum number of nested CALLEXT calls
+const MAX_CALL_DEPTH = 16
 
 type VM struct {
 	code            []byte
@@ -34,6 +32,8 @@ type VM struct {
 	evaluationStack *Stack
 	callStack       *CallStack
 	context         Context
+	depth           int // Number of CALLEXT calls this VM is nested in
+	tracer          Tracer
 }
 
 func NewVM(context Context) VM {
@@ -58,84 +58,51 @@ func NewTestVM(byteCode []byte) VM {
 	}
 }
 
-// Private function, that can be activated by Exec call, useful for debugging
-func (vm *VM) trace() {
-	stack := vm.evaluationStack
-	addr := vm.pc
-
-	byteCode := int(vm.code[vm.pc])
-	if len(OpCodes) <= byteCode {
-		stack.Push([]byte("Trace: invalid opcode "))
-		return
-	}
-	opCode := OpCodes[byteCode]
-
-	var args []byte
-	var formattedArgs string
-	var counter int
-
-	for _, argType := range opCode.ArgTypes {
-		switch argType {
-		case BYTES:
-			if len(vm.code)-vm.pc > 0 {
-				nargs := int(vm.code[vm.pc+1])
-
-				if nargs < (len(vm.code)-vm.pc)-1 {
-					args = vm.code[vm.pc+2+counter : vm.pc+nargs+3+counter]
-					counter += nargs
-					formattedArgs = formattedArgs + fmt.Sprintf("%v (bytes) ", args[:])
-				}
-			}
-
-		case BYTE:
-			if len(vm.code)-vm.pc > 0 {
-				args = []byte{vm.code[vm.pc+1+counter]}
-				counter += 1
-				formattedArgs += fmt.Sprintf("%v (byte) ", args[:])
-			}
-
-		case ADDR:
-			if len(vm.code)-vm.pc > 31 {
-				args = vm.code[vm.pc+1+counter : vm.pc+33+counter]
-				counter += 32
-				formattedArgs += fmt.Sprintf("%v (bazo address) ", args[:])
-			}
-
-		case LABEL:
-			if len(vm.code)-vm.pc > 1 {
-				args = vm.code[vm.pc+1+counter : vm.pc+3+counter]
-				counter += 2
-				formattedArgs += fmt.Sprintf("%v (address) ", ByteArrayToInt(args[:]))
-			}
-		}
-	}
-
-	reversedStack := make([]protocol.ByteArray, stack.GetLength())
-	maxIndex := len(stack.Stack) - 1
-	for i := maxIndex; i >= 0; i-- {
-		reversedStack[maxIndex-i] = stack.Stack[i]
-	}
-
-	fmt.Printf("\t  Stack: %v \n", reversedStack)
-	fmt.Printf("\t  %v of max. %v Bytes in use \n", stack.memoryUsage, stack.memoryMax)
-	fmt.Printf("⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅\n")
-	fmt.Printf("%04d: %-6s %v \n", addr, opCode.Name, formattedArgs)
+// Sets the tracer whose hooks are called before and after every instruction, including those of called contracts
+func (vm *VM) SetTracer(tracer Tracer) {
+	vm.tracer = tracer
 }
 
-func (vm *VM) Exec(trace bool) bool {
+// Executes the contract, trace prints every instruction as JSON lines to stdout if no tracer has been set
+func (vm *VM) Exec(trace bool) (success bool) {
 
 	vm.code = vm.context.GetContract()
 	vm.fee = vm.context.GetFee()
 
+	if trace && vm.tracer == nil {
+		vm.tracer = NewJSONTracer(os.Stdout)
+	}
+
 	if len(vm.code) > 100000 {
 		vm.evaluationStack.Push([]byte("vm.exec(): Instruction set to big"))
 		return false
 	}
 
+	// The after hook of the last instruction is called when it halts or fails
+	var step *TraceStep
+	if vm.tracer != nil {
+		defer func() {
+			if step != nil {
+				vm.traceAfter(step, success)
+			}
+		}()
+	}
+
 	// Infinite Loop until return called
 	for {
-		if trace {
-			vm.trace()
+		if vm.tracer != nil {
+			if step != nil {
+				vm.traceAfter(step, true)
+			}
+
+			step = vm.traceBefore()
+			if step != nil {
+				if err := vm.tracer.BeforeOp(*step); err != nil {
+					vm.evaluationStack.Push([]byte("vm.exec(): " + err.Error()))
+					step = nil
+					return false
+				}
+			}
 		}
 
 		// Fetch
@@ -529,8 +496,56 @@ func (vm *VM) Exec(trace bool) bool {
 				return false
 			}
 
-			fmt.Sprint("CALLEXT", transactionAddress, functionHash, argsToLoad)
-			//TODO: Invoke new transaction with function hash and arguments, waiting for integration in bazo blockchain to finish
+			if vm.depth >= MAX_CALL_DEPTH {
+				vm.evaluationStack.Push([]byte(opCode.Name + ": Maximum call depth reached"))
+				return false
+			}
+
+			// Arguments are encoded like the transaction data read by CALLDATA, the function hash comes last
+			args := make([][]byte, argsToLoad)
+			for i := int(argsToLoad) - 1; i >= 0; i-- {
+				arg, err := vm.PopBytes(opCode)
+				if !vm.checkErrors(opCode.Name, err) {
+					return false
+				}
+				if len(arg) == 0 || len(arg) > 256 {
+					vm.evaluationStack.Push([]byte(opCode.Name + ": Invalid argument size"))
+					return false
+				}
+				args[i] = arg
+			}
+
+			var data []byte
+			for _, arg := range append(args, functionHash) {
+				data = append(data, byte(len(arg)-1))
+				data = append(data, arg...)
+			}
+
+			var address [32]byte
+			copy(address[:], transactionAddress)
+			callContext, err := vm.context.NewCallContext(address, data, vm.fee)
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+			callee := NewVM(callContext)
+			callee.depth = vm.depth + 1
+			callee.tracer = vm.tracer
+			calleeSuccess := callee.Exec(trace)
+			vm.fee = callee.fee
+
+			if !calleeSuccess {
+				vm.evaluationStack.Push([]byte(opCode.Name + ": " + callee.GetErrorMsg()))
+				return false
+			}
+
+			// The return values of the callee are pushed in the same order
+			for _, element := range callee.evaluationStack.Stack {
+				err = vm.evaluationStack.Push(element)
+				if !vm.checkErrors(opCode.Name, err) {
+					return false
+				}
+			}
 
 		case RET:
 			callstackTos, err := vm.callStack.Peek()
@@ -671,6 +686,27 @@ func (vm *VM) Exec(trace bool) bool {
 				return false
 			}
 
+		case HEIGHT, TIMESTAMP:
+			// Pushed as signed integers, such that they can be compared and computed with
+			var value big.Int
+			if opCode.code == HEIGHT {
+				value.SetUint64(uint64(vm.context.GetBlockHeight()))
+			} else {
+				value.SetInt64(vm.context.GetBlockTimestamp())
+			}
+
+			err := vm.evaluationStack.Push(SignedByteArrayConversion(value))
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+		case PREVHASH:
+			prevHash := vm.context.GetPrevBlockHash()
+			err := vm.evaluationStack.Push(prevHash[:])
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
 		case CALLDATA:
 			td := vm.context.GetTransactionData()
 			for i := 0; i < len(td); i++ {
@@ -1080,6 +1116,245 @@ func (vm *VM) Exec(trace bool) bool {
 			result := ecdsa.Verify(&pubKey, hash, r, s)
 			vm.evaluationStack.Push(BoolToByteArray(result))
 
+		case LOG0, LOG1, LOG2, LOG3:
+			data, err := vm.PopBytes(opCode)
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+			// The topics are passed in the order in which they were pushed
+			topics := make([][]byte, opCode.code-LOG0)
+			for i := len(topics) - 1; i >= 0; i-- {
+				topic, err := vm.PopBytes(opCode)
+				if !vm.checkErrors(opCode.Name, err) {
+					return false
+				}
+				if len(topic) > MAX_TOPIC_SIZE {
+					vm.evaluationStack.Push([]byte(opCode.Name + ": Topic exceeds 32 bytes"))
+					return false
+				}
+				topics[i] = topic
+			}
+
+			err = vm.context.EmitEvent(topics, data)
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+		case TRANSFER:
+			amount, errAmount := vm.PopSignedBigInt(opCode)
+			address, errAddress := vm.PopBytes(opCode)
+			if !vm.checkErrors(opCode.Name, errAmount, errAddress) {
+				return false
+			}
+
+			if amount.Sign() < 0 || !amount.IsUint64() {
+				vm.evaluationStack.Push([]byte(opCode.Name + ": Invalid amount"))
+				return false
+			}
+
+			if len(address) != 32 {
+				vm.evaluationStack.Push([]byte(opCode.Name + ": Not a valid address"))
+				return false
+			}
+
+			var to [32]byte
+			copy(to[:], address)
+			err := vm.context.Transfer(to, amount.Uint64())
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+		case CONCAT:
+			right, rerr := vm.PopBytes(opCode)
+			left, lerr := vm.PopBytes(opCode)
+			if !vm.checkErrors(opCode.Name, rerr, lerr) {
+				return false
+			}
+
+			result := make([]byte, 0, len(left)+len(right))
+			result = append(append(result, left...), right...)
+			err := vm.evaluationStack.Push(result)
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+		case SLICE:
+			length, errLength := vm.PopSignedBigInt(opCode)
+			start, errStart := vm.PopSignedBigInt(opCode)
+			element, errElement := vm.PopBytes(opCode)
+			if !vm.checkErrors(opCode.Name, errLength, errStart, errElement) {
+				return false
+			}
+
+			// Compared as big ints, such that huge values don't overflow
+			size := big.NewInt(int64(len(element)))
+			end := new(big.Int).Add(&start, &length)
+			if start.Sign() < 0 || length.Sign() < 0 || end.Cmp(size) > 0 {
+				vm.evaluationStack.Push([]byte(opCode.Name + ": Index out of bounds"))
+				return false
+			}
+
+			result := make([]byte, length.Int64())
+			copy(result, element[start.Int64():end.Int64()])
+			err := vm.evaluationStack.Push(result)
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+		case CMP:
+			right, rerr := vm.PopBytes(opCode)
+			left, lerr := vm.PopBytes(opCode)
+			if !vm.checkErrors(opCode.Name, rerr, lerr) {
+				return false
+			}
+
+			// -1, 0 or 1 as signed integer, a prefix is less than the longer element
+			result := big.NewInt(int64(bytes.Compare(left, right)))
+			err := vm.evaluationStack.Push(SignedByteArrayConversion(*result))
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+		case ITOB:
+			width, errArg := vm.fetch(opCode.Name)
+			value, errStack := vm.PopSignedBigInt(opCode)
+			if !vm.checkErrors(opCode.Name, errArg, errStack) {
+				return false
+			}
+
+			if width == 0 {
+				vm.evaluationStack.Push([]byte(opCode.Name + ": Width must be positive"))
+				return false
+			}
+
+			if value.Sign() < 0 || len(value.Bytes()) > int(width) {
+				vm.evaluationStack.Push([]byte(opCode.Name + ": Value does not fit into " + strconv.Itoa(int(width)) + " bytes"))
+				return false
+			}
+
+			result := make([]byte, width)
+			copy(result[int(width)-len(value.Bytes()):], value.Bytes())
+			err := vm.evaluationStack.Push(result)
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+		case BTOI:
+			element, err := vm.PopBytes(opCode)
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+			value := new(big.Int).SetBytes(element)
+			err = vm.evaluationStack.Push(SignedByteArrayConversion(*value))
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+		case CHECKMULTISIG:
+			n, err := vm.PopSignedBigInt(opCode)
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+			if n.Sign() <= 0 || n.Cmp(big.NewInt(MAX_MULTISIG_KEYS)) > 0 {
+				vm.evaluationStack.Push([]byte(opCode.Name + ": Number of keys must be between 1 and " + strconv.Itoa(MAX_MULTISIG_KEYS)))
+				return false
+			}
+
+			// The keys and signatures are passed in the order in which they were pushed
+			addresses := make([][]byte, n.Int64())
+			for i := len(addresses) - 1; i >= 0; i-- {
+				address, err := vm.PopBytes(opCode)
+				if !vm.checkErrors(opCode.Name, err) {
+					return false
+				}
+				if len(address) != 64 {
+					vm.evaluationStack.Push([]byte(opCode.Name + ": Not a valid address"))
+					return false
+				}
+				addresses[i] = address
+			}
+
+			m, err := vm.PopSignedBigInt(opCode)
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+			if m.Sign() <= 0 || m.Cmp(&n) > 0 {
+				vm.evaluationStack.Push([]byte(opCode.Name + ": Number of signatures must be between 1 and the number of keys"))
+				return false
+			}
+
+			signatures := make([][]byte, m.Int64())
+			for i := len(signatures) - 1; i >= 0; i-- {
+				signature, err := vm.PopBytes(opCode)
+				if !vm.checkErrors(opCode.Name, err) {
+					return false
+				}
+				if len(signature) != 64 {
+					vm.evaluationStack.Push([]byte(opCode.Name + ": Not a valid signature"))
+					return false
+				}
+				signatures[i] = signature
+			}
+
+			message, err := vm.PopBytes(opCode)
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+			result := verifyMultiSig(addresses, hashMessage(message), signatures)
+			err = vm.evaluationStack.Push(BoolToByteArray(result))
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+		case CHECKSIGADDR:
+			account, errAccount := vm.PopBytes(opCode)
+			signature, errSignature := vm.PopBytes(opCode)
+			message, errMessage := vm.PopBytes(opCode)
+
+			if !vm.checkErrors(opCode.Name, errAccount, errSignature, errMessage) {
+				return false
+			}
+
+			if len(account) != 32 {
+				vm.evaluationStack.Push([]byte(opCode.Name + ": Not a valid account hash"))
+				return false
+			}
+
+			if len(signature) != 64 {
+				vm.evaluationStack.Push([]byte(opCode.Name + ": Not a valid signature"))
+				return false
+			}
+
+			result := verifySignatureOfAccount(account, hashMessage(message), signature)
+			err := vm.evaluationStack.Push(BoolToByteArray(result))
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+		case SELFDESTRUCT:
+			address, err := vm.PopBytes(opCode)
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+			if len(address) != 32 {
+				vm.evaluationStack.Push([]byte(opCode.Name + ": Not a valid address"))
+				return false
+			}
+
+			var beneficiary [32]byte
+			copy(beneficiary[:], address)
+			err = vm.context.SelfDestruct(beneficiary)
+			if !vm.checkErrors(opCode.Name, err) {
+				return false
+			}
+
+			// The contract is gone, like HALT the remaining stack is the result
+			return true
+
 		case ERRHALT:
 			return false
 
@@ -1175,6 +1450,18 @@ func (vm *VM) PopUnsignedBigInt(opCode OpCode) (bigInt big.Int, err error) {
 	return result, err
 }
 
+// Returns the fee which has not been used by the executed instructions
+func (vm *VM) GetRemainingFee() uint64 {
+	return vm.fee
+}
+
+// Returns the elements on the evaluation stack, top of stack last
+func (vm *VM) GetStack() []protocol.ByteArray {
+	stack := make([]protocol.ByteArray, len(vm.evaluationStack.Stack))
+	copy(stack, vm.evaluationStack.Stack)
+	return stack
+}
+
 func (vm *VM) GetErrorMsg() string {
 	tos, err := vm.evaluationStack.PeekBytes()
 	if err != nil {
@@ -2,12 +2,19 @@ package vm
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"math/big"
+	"reflect"
+	"strings"
 	"testing"
 
 	"fmt"
 
+	"github.com/bazo-blockchain/bazo-miner/crypto"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 )
 
@@ -678,18 +685,174 @@ func TestVM_Exec_TosSize(t *testing.T) {
 	}
 }
 
+// Returns the code of a contract which calls the contract at address with the function hash and argsToLoad arguments
+func callExtCode(address [32]byte, argsToLoad byte, code ...byte) []byte {
+	call := append(code, CALLEXT)
+	call = append(call, address[:]...)
+	return append(call, 0x01, 0x02, 0x03, 0x04, argsToLoad)
+}
+
+// Returns a reader for the contract accounts in the given map
+func accountReader(accounts map[[32]byte]*protocol.Account) func(address [32]byte) (*protocol.Account, error) {
+	return func(address [32]byte) (*protocol.Account, error) {
+		if acc, exists := accounts[address]; exists {
+			return acc, nil
+		}
+		return nil, errors.New("Account not found")
+	}
+}
+
 func TestVM_Exec_CallExt(t *testing.T) {
-	code := []byte{
-		PUSH, 0, 10,
-		PUSH, 0, 8,
-		CALLEXT, 227, 237, 86, 189, 8, 109, 137, 88, 72, 58, 18, 115, 79, 160, 174, 127, 92, 139, 177, 96, 239, 144, 146, 198, 126, 130, 237, 155, 25, 228, 199, 178, 41, 24, 45, 14, 2,
-		HALT,
+	calleeAddress := [32]byte{2}
+	callee := &protocol.Account{
+		Contract: []byte{
+			CALLDATA,
+			POP, // Function hash
+			ADD,
+			DUP,
+			SSTORE, 0,
+			HALT,
+		},
+		ContractVariables: []protocol.ByteArray{[]byte{0}},
 	}
 
+	code := append(callExtCode(calleeAddress, 2, PUSH, 1, 0, 10, PUSH, 1, 0, 8), HALT)
+
 	vm := NewTestVM([]byte{})
 	mc := NewMockContext(code)
+	mc.To = [32]byte{1}
+	mc.Fee = 100000
+	mc.SetAccountReader(accountReader(map[[32]byte]*protocol.Account{calleeAddress: callee}))
 	vm.context = mc
-	vm.Exec(false)
+
+	if !vm.Exec(false) {
+		t.Fatalf("Expected the call to succeed but failed with '%v'", vm.GetErrorMsg())
+	}
+
+	expected := []byte{0, 18}
+	actual, _ := vm.evaluationStack.Pop()
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("Expected result to be '%v' but was '%v'", expected, actual)
+	}
+
+	if vm.fee >= 100000-2*1000 {
+		t.Errorf("Expected the gas used by the callee to be charged, but the remaining fee was %v", vm.fee)
+	}
+
+	changes := mc.GetChanges()
+	if len(changes) != 1 || changes[0].GetAccount() != calleeAddress {
+		t.Fatalf("Expected the change of the callee to be recorded for the callee, but the changes were %v", changes)
+	}
+
+	if !bytes.Equal(callee.ContractVariables[0], []byte{0}) {
+		t.Errorf("Contract variable of the callee has been changed before the changes were persisted")
+	}
+
+	mc.PersistChanges()
+	if !bytes.Equal(callee.ContractVariables[0], expected) {
+		t.Errorf("Expected contract variable of the callee to be '%v' but was '%v'", expected, callee.ContractVariables[0])
+	}
+}
+
+func TestVM_Exec_CallExt_Failure(t *testing.T) {
+	calleeAddress := [32]byte{2}
+	callee := &protocol.Account{
+		Contract: []byte{
+			PUSH, 0, 5,
+			SSTORE, 0,
+			ERRHALT,
+		},
+		ContractVariables: []protocol.ByteArray{[]byte{0}},
+	}
+
+	code := append(callExtCode(calleeAddress, 0, PUSH, 0, 7, SSTORE, 0), HALT)
+
+	vm := NewTestVM([]byte{})
+	mc := NewMockContext(code)
+	mc.To = [32]byte{1}
+	mc.Fee = 100000
+	mc.ContractVariables = []protocol.ByteArray{[]byte{0}}
+	mc.SetAccountReader(accountReader(map[[32]byte]*protocol.Account{calleeAddress: callee}))
+	vm.context = mc
+
+	if vm.Exec(false) {
+		t.Fatal("Expected the call of a failing contract to fail")
+	}
+
+	if !strings.HasPrefix(vm.GetErrorMsg(), "callext: ") {
+		t.Errorf("Expected a callext error but was '%v'", vm.GetErrorMsg())
+	}
+
+	// The caller does not persist the changes of a failed execution, neither its own nor those of the callee
+	if len(mc.GetChanges()) != 2 {
+		t.Errorf("Expected the changes of caller and callee to be collected together, but were %v", mc.GetChanges())
+	}
+}
+
+func TestVM_Exec_CallExt_MissingContract(t *testing.T) {
+	code := append(callExtCode([32]byte{3}, 0), HALT)
+
+	vm := NewTestVM([]byte{})
+	mc := NewMockContext(code)
+	mc.Fee = 100000
+	vm.context = mc
+
+	if vm.Exec(false) {
+		t.Error("Expected the call to fail without accounts")
+	}
+
+	mc.SetAccountReader(accountReader(map[[32]byte]*protocol.Account{{3}: {}}))
+	vm = NewVM(mc)
+
+	if vm.Exec(false) {
+		t.Error("Expected the call of an account without contract to fail")
+	}
+}
+
+func TestVM_Exec_CallExt_MaxDepth(t *testing.T) {
+	address := [32]byte{1}
+	contract := &protocol.Account{Contract: append(callExtCode(address, 0), HALT)}
+
+	context := protocol.NewContext(*contract, protocol.FundsTx{To: address, Fee: 1000000})
+	context.SetAccountReader(accountReader(map[[32]byte]*protocol.Account{address: contract}))
+	vm := NewVM(context)
+
+	if vm.Exec(false) {
+		t.Fatal("Expected recursive calls to fail")
+	}
+
+	if !strings.HasSuffix(vm.GetErrorMsg(), "Maximum call depth reached") {
+		t.Errorf("Expected call depth error but was '%v'", vm.GetErrorMsg())
+	}
+
+	if depth := strings.Count(vm.GetErrorMsg(), "callext: "); depth != MAX_CALL_DEPTH+1 {
+		t.Errorf("Expected %v nested calls but were %v", MAX_CALL_DEPTH, depth-1)
+	}
+}
+
+func TestVM_Exec_CallExt_OutOfGas(t *testing.T) {
+	calleeAddress := [32]byte{2}
+	callee := &protocol.Account{Contract: []byte{
+		PUSH, 0, 1,
+		JMP, 0, 0,
+		HALT,
+	}}
+
+	code := append(callExtCode(calleeAddress, 0), HALT)
+
+	vm := NewTestVM([]byte{})
+	mc := NewMockContext(code)
+	mc.Fee = 2000
+	mc.SetAccountReader(accountReader(map[[32]byte]*protocol.Account{calleeAddress: callee}))
+	vm.context = mc
+
+	if vm.Exec(false) {
+		t.Fatal("Expected the endless callee to run out of gas")
+	}
+
+	if vm.GetErrorMsg() != "callext: vm.exec(): out of gas" {
+		t.Errorf("Expected out of gas error but was '%v'", vm.GetErrorMsg())
+	}
 }
 
 func TestVM_Exec_Sload(t *testing.T) {
@@ -1743,7 +1906,7 @@ func TestVM_Exec_FuzzReproduction_ContextOpCode2(t *testing.T) {
 
 func TestVM_Exec_FuzzReproduction_EdgecaseLastOpcodePlusOne(t *testing.T) {
 	code := []byte{
-		HALT + 1,
+		byte(len(OpCodes)),
 	}
 
 	vm := NewTestVM([]byte{})
@@ -2148,3 +2311,539 @@ func TestVm_Exec_ModularExponentiation_ContractImplementation(t *testing.T) {
 		t.Errorf("Expected actual result to be '%v' but was '%v'", expected, actual)
 	}
 }
+
+func TestVM_Exec_Log(t *testing.T) {
+	code := []byte{
+		PUSH, 0, 'a',
+		PUSH, 1, 'b', 'c',
+		PUSH, 2, 1, 2, 3,
+		LOG2,
+		PUSH, 0, 4,
+		LOG0,
+		HALT,
+	}
+
+	vm := NewTestVM([]byte{})
+	mc := NewMockContext(code)
+	mc.To = [32]byte{1}
+	mc.Fee = 100000
+	vm.context = mc
+
+	if !vm.Exec(false) {
+		t.Fatalf("Expected the contract to succeed but failed with '%v'", vm.GetErrorMsg())
+	}
+
+	if len(vm.evaluationStack.Stack) != 0 {
+		t.Errorf("Expected the topics and the data to be popped, but the stack was %v", vm.evaluationStack.Stack)
+	}
+
+	expected := []protocol.Event{
+		{Account: [32]byte{1}, Topics: []protocol.ByteArray{{'a'}, {'b', 'c'}}, Data: protocol.ByteArray{1, 2, 3}},
+		{Account: [32]byte{1}, Data: protocol.ByteArray{4}},
+	}
+	if events := mc.GetEvents(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected events '%v' but were '%v'", expected, events)
+	}
+}
+
+func TestVM_Exec_Log_Errors(t *testing.T) {
+	codes := map[string][]byte{
+		"log1: pop() on empty stack":   {PUSH, 0, 1, LOG1, HALT},
+		"log1: Topic exceeds 32 bytes": append(append([]byte{PUSH, 32}, make([]byte, 33)...), PUSH, 0, 1, LOG1, HALT),
+	}
+
+	for expected, code := range codes {
+		vm := NewTestVM([]byte{})
+		mc := NewMockContext(code)
+		mc.Fee = 100000
+		vm.context = mc
+
+		if vm.Exec(false) {
+			t.Errorf("Expected '%v' to fail", code)
+		}
+		if actual := vm.GetErrorMsg(); actual != expected {
+			t.Errorf("Expected error '%v' but was '%v'", expected, actual)
+		}
+		if len(mc.GetEvents()) != 0 {
+			t.Errorf("Expected no event to be emitted, but got %v", mc.GetEvents())
+		}
+	}
+}
+
+func TestVM_Exec_Nop(t *testing.T) {
+	code := []byte{
+		PUSH, 0, 3,
+		NOP, HALT, // The argument of NOP is skipped
+		HALT,
+	}
+
+	vm := NewTestVM([]byte{})
+	mc := NewMockContext(code)
+	vm.context = mc
+
+	if !vm.Exec(false) {
+		t.Errorf("Expected NOP to skip its argument but failed with '%v'", vm.GetErrorMsg())
+	}
+
+	vm = NewTestVM([]byte{})
+	vm.context = NewMockContext([]byte{NOP})
+	if vm.Exec(false) {
+		t.Errorf("Expected NOP without an argument to fail")
+	}
+}
+
+func TestVM_Exec_BlockContext(t *testing.T) {
+	code := []byte{
+		HEIGHT,
+		TIMESTAMP,
+		PUSH, 1, 0, 100,
+		GT, // Timestamp > 100
+		PREVHASH,
+		HALT,
+	}
+
+	vm := NewTestVM([]byte{})
+	mc := NewMockContext(code)
+	mc.SetBlock(258, 1000, [32]byte{9})
+	vm.context = mc
+
+	if !vm.Exec(false) {
+		t.Fatalf("Expected the contract to succeed but failed with '%v'", vm.GetErrorMsg())
+	}
+
+	expected := []protocol.ByteArray{{0, 1, 2}, {1}, append([]byte{9}, make([]byte, 31)...)}
+	if actual := vm.GetStack(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected stack '%v' but was '%v'", expected, actual)
+	}
+}
+
+func TestVM_Exec_Transfer(t *testing.T) {
+	receiver := [32]byte{2}
+	code := append(append([]byte{PUSH, 31}, receiver[:]...), PUSH, 1, 0, 60, TRANSFER, HALT)
+
+	vm := NewTestVM([]byte{})
+	mc := NewMockContext(code)
+	mc.To = [32]byte{1}
+	mc.Balance = 50
+	mc.Amount = 10
+	mc.Fee = 100000
+	vm.context = mc
+
+	if !vm.Exec(false) {
+		t.Fatalf("Expected the transfer of the balance and the amount to succeed but failed with '%v'", vm.GetErrorMsg())
+	}
+
+	expected := []protocol.Transfer{{From: [32]byte{1}, To: receiver, Amount: 60}}
+	if transfers := mc.GetTransfers(); !reflect.DeepEqual(transfers, expected) {
+		t.Errorf("Expected transfers '%v' but were '%v'", expected, transfers)
+	}
+}
+
+func TestVM_Exec_Transfer_Errors(t *testing.T) {
+	receiver := make([]byte, 32)
+	codes := map[string][]byte{
+		"transfer: Insufficient balance: 50 available, 51 to transfer": append(append([]byte{PUSH, 31}, receiver...), PUSH, 1, 0, 51, TRANSFER, HALT),
+		"transfer: Invalid amount":                                     append(append([]byte{PUSH, 31}, receiver...), PUSH, 1, 1, 5, TRANSFER, HALT),
+		"transfer: Not a valid address":                                {PUSH, 0, 2, PUSH, 1, 0, 5, TRANSFER, HALT},
+		"transfer: pop() on empty stack":                               {PUSH, 1, 0, 5, TRANSFER, HALT},
+	}
+
+	for expected, code := range codes {
+		vm := NewTestVM([]byte{})
+		mc := NewMockContext(code)
+		mc.Balance = 50
+		mc.Fee = 100000
+		vm.context = mc
+
+		if vm.Exec(false) {
+			t.Errorf("Expected '%v' to fail", code)
+		}
+		if actual := vm.GetErrorMsg(); actual != expected {
+			t.Errorf("Expected error '%v' but was '%v'", expected, actual)
+		}
+		if len(mc.GetTransfers()) != 0 {
+			t.Errorf("Expected no transfer, but got %v", mc.GetTransfers())
+		}
+	}
+}
+
+func TestVM_Exec_Concat(t *testing.T) {
+	code := []byte{
+		PUSH, 1, 'a', 'b',
+		PUSH, 0, 'c',
+		CONCAT,
+		HALT,
+	}
+
+	vm := NewTestVM([]byte{})
+	mc := NewMockContext(code)
+	vm.context = mc
+	vm.Exec(false)
+
+	tos, _ := vm.evaluationStack.Pop()
+
+	expected := "abc"
+	actual := string(tos)
+	if actual != expected {
+		t.Errorf("Expected result to be '%v' but was '%v'", expected, actual)
+	}
+}
+
+func TestVM_Exec_Slice(t *testing.T) {
+	code := []byte{
+		PUSH, 4, 'h', 'e', 'l', 'l', 'o',
+		PUSH, 1, 0, 1,
+		PUSH, 1, 0, 3,
+		SLICE,
+		HALT,
+	}
+
+	vm := NewTestVM([]byte{})
+	mc := NewMockContext(code)
+	vm.context = mc
+	vm.Exec(false)
+
+	tos, _ := vm.evaluationStack.Pop()
+
+	expected := "ell"
+	actual := string(tos)
+	if actual != expected {
+		t.Errorf("Expected result to be '%v' but was '%v'", expected, actual)
+	}
+}
+
+func TestVM_Exec_SliceOutOfBounds(t *testing.T) {
+	codes := [][]byte{
+		{PUSH, 1, 'a', 'b', PUSH, 1, 0, 1, PUSH, 1, 0, 2, SLICE, HALT},
+		{PUSH, 1, 'a', 'b', PUSH, 1, 1, 1, PUSH, 1, 0, 1, SLICE, HALT},
+		{PUSH, 1, 'a', 'b', PUSH, 1, 0, 1, PUSH, 1, 1, 1, SLICE, HALT},
+		{PUSH, 1, 'a', 'b', PUSH, 8, 0, 255, 255, 255, 255, 255, 255, 255, 255, PUSH, 1, 0, 1, SLICE, HALT},
+	}
+
+	for _, code := range codes {
+		vm := NewTestVM([]byte{})
+		mc := NewMockContext(code)
+		vm.context = mc
+		vm.Exec(false)
+
+		expected := "slice: Index out of bounds"
+		actual := vm.GetErrorMsg()
+		if actual != expected {
+			t.Errorf("Expected error message of '%v' to be '%v' but was '%v'", code, expected, actual)
+		}
+	}
+}
+
+func TestVM_Exec_Cmp(t *testing.T) {
+	tests := []struct {
+		left, right []byte
+		expected    []byte
+	}{
+		{[]byte{0, 1}, []byte{1}, []byte{0x01, 1}}, // EQ would consider them equal
+		{[]byte{1}, []byte{0, 1}, []byte{0x00, 1}},
+		{[]byte("ab"), []byte("abc"), []byte{0x01, 1}},
+		{[]byte("abc"), []byte("abc"), []byte{0x00}},
+	}
+
+	for _, test := range tests {
+		code := append([]byte{PUSH, byte(len(test.left) - 1)}, test.left...)
+		code = append(append(code, PUSH, byte(len(test.right)-1)), test.right...)
+		code = append(code, CMP, HALT)
+
+		vm := NewTestVM([]byte{})
+		mc := NewMockContext(code)
+		vm.context = mc
+		vm.Exec(false)
+
+		actual, _ := vm.evaluationStack.Pop()
+		if !bytes.Equal(test.expected, actual) {
+			t.Errorf("Expected comparison of '%v' and '%v' to be '%v' but was '%v'", test.left, test.right, test.expected, actual)
+		}
+	}
+}
+
+func TestVM_Exec_Itob(t *testing.T) {
+	code := []byte{
+		PUSH, 2, 0, 1, 2,
+		ITOB, 4,
+		HALT,
+	}
+
+	vm := NewTestVM([]byte{})
+	mc := NewMockContext(code)
+	vm.context = mc
+	vm.Exec(false)
+
+	expected := []byte{0, 0, 1, 2}
+	actual, _ := vm.evaluationStack.Pop()
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("Expected result to be '%v' but was '%v'", expected, actual)
+	}
+}
+
+func TestVM_Exec_ItobErrors(t *testing.T) {
+	codes := map[string][]byte{
+		"itob: Value does not fit into 1 bytes": {PUSH, 2, 0, 1, 2, ITOB, 1, HALT},
+		"itob: Value does not fit into 2 bytes": {PUSH, 1, 1, 5, ITOB, 2, HALT},
+		"itob: Width must be positive":          {PUSH, 1, 0, 5, ITOB, 0, HALT},
+	}
+
+	for expected, code := range codes {
+		vm := NewTestVM([]byte{})
+		mc := NewMockContext(code)
+		vm.context = mc
+		vm.Exec(false)
+
+		if actual := vm.GetErrorMsg(); actual != expected {
+			t.Errorf("Expected error message to be '%v' but was '%v'", expected, actual)
+		}
+	}
+}
+
+func TestVM_Exec_Btoi(t *testing.T) {
+	code := []byte{
+		PUSH, 3, 0, 0, 1, 2,
+		BTOI,
+		PUSH, 1, 0, 1,
+		ADD,
+		HALT,
+	}
+
+	vm := NewTestVM([]byte{})
+	mc := NewMockContext(code)
+	vm.context = mc
+	vm.Exec(false)
+
+	expected := []byte{0, 1, 3}
+	actual, _ := vm.evaluationStack.Pop()
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("Expected result to be '%v' but was '%v'", expected, actual)
+	}
+}
+
+func pushCode(element []byte) []byte {
+	return append([]byte{PUSH, byte(len(element) - 1)}, element...)
+}
+
+// crypto.GetAddressFromPubKey does not pad the coordinates, keys with a coordinate shorter than 32 bytes would get a
+// wrong address.
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	for {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("Could not generate key: %v", err)
+		}
+		if len(key.X.Bytes()) == 32 && len(key.Y.Bytes()) == 32 {
+			return key
+		}
+	}
+}
+
+func signMessage(t *testing.T, key *ecdsa.PrivateKey, message []byte) []byte {
+	r, s, err := ecdsa.Sign(rand.Reader, key, hashMessage(message))
+	if err != nil {
+		t.Fatalf("Could not sign: %v", err)
+	}
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signature
+}
+
+func multiSigCode(message []byte, signatures [][]byte, addresses [][]byte) []byte {
+	code := pushCode(message)
+	for _, signature := range signatures {
+		code = append(code, pushCode(signature)...)
+	}
+	code = append(code, PUSH, 1, 0, byte(len(signatures)))
+	for _, address := range addresses {
+		code = append(code, pushCode(address)...)
+	}
+	code = append(code, PUSH, 1, 0, byte(len(addresses)))
+	return append(code, CHECKMULTISIG, HALT)
+}
+
+func TestVM_Exec_CheckMultiSig(t *testing.T) {
+	var keys []*ecdsa.PrivateKey
+	var addresses [][]byte
+	for i := 0; i < 3; i++ {
+		key := generateKey(t)
+		address := crypto.GetAddressFromPubKey(&key.PublicKey)
+		keys = append(keys, key)
+		addresses = append(addresses, address[:])
+	}
+
+	message := []byte("withdraw 100")
+	sig0, sig1, sig2 := signMessage(t, keys[0], message), signMessage(t, keys[1], message), signMessage(t, keys[2], message)
+
+	tests := []struct {
+		name       string
+		signatures [][]byte
+		expected   bool
+	}{
+		{"first and last key", [][]byte{sig0, sig2}, true},
+		{"last two keys", [][]byte{sig1, sig2}, true},
+		{"all keys", [][]byte{sig0, sig1, sig2}, true},
+		{"wrong order", [][]byte{sig2, sig0}, false},
+		{"same signature twice", [][]byte{sig1, sig1}, false},
+		{"other message", [][]byte{sig0, signMessage(t, keys[1], []byte("withdraw 1000"))}, false},
+	}
+
+	for _, test := range tests {
+		vm := NewTestVM([]byte{})
+		mc := NewMockContext(multiSigCode(message, test.signatures, addresses))
+		mc.Fee = 100000
+		vm.context = mc
+
+		if !vm.Exec(false) {
+			t.Fatalf("%v: Execution failed: %v", test.name, vm.GetErrorMsg())
+		}
+
+		tos, _ := vm.evaluationStack.Pop()
+		if actual := ByteArrayToBool(tos); actual != test.expected {
+			t.Errorf("%v: Expected result to be '%v' but was '%v'", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestVM_Exec_CheckMultiSigErrors(t *testing.T) {
+	address := bytes.Repeat([]byte{1}, 64)
+	signature := bytes.Repeat([]byte{2}, 64)
+
+	codes := map[string][]byte{
+		"checkmultisig: Number of keys must be between 1 and 16":                       multiSigCode([]byte{0}, [][]byte{signature}, nil),
+		"checkmultisig: Number of signatures must be between 1 and the number of keys": multiSigCode([]byte{0}, [][]byte{signature, signature}, [][]byte{address}),
+		"checkmultisig: Not a valid address":                                           multiSigCode([]byte{0}, [][]byte{signature}, [][]byte{address[:32]}),
+		"checkmultisig: Not a valid signature":                                         multiSigCode([]byte{0}, [][]byte{signature[:63]}, [][]byte{address}),
+	}
+
+	for expected, code := range codes {
+		vm := NewTestVM([]byte{})
+		mc := NewMockContext(code)
+		mc.Fee = 100000
+		vm.context = mc
+
+		if vm.Exec(false) {
+			t.Errorf("Expected execution of '%v' to fail", code)
+		}
+		if actual := vm.GetErrorMsg(); actual != expected {
+			t.Errorf("Expected error message to be '%v' but was '%v'", expected, actual)
+		}
+	}
+}
+
+func TestVM_Exec_CheckSigAddr(t *testing.T) {
+	key, other := generateKey(t), generateKey(t)
+	account := protocol.SerializeHashContent(crypto.GetAddressFromPubKey(&key.PublicKey))
+	otherAccount := protocol.SerializeHashContent(crypto.GetAddressFromPubKey(&other.PublicKey))
+
+	message := []byte("withdraw 100")
+	signature := signMessage(t, key, message)
+
+	tests := []struct {
+		name      string
+		message   []byte
+		signature []byte
+		account   [32]byte
+		expected  bool
+	}{
+		{"signer", message, signature, account, true},
+		{"other account", message, signature, otherAccount, false},
+		{"other message", []byte("withdraw 1000"), signature, account, false},
+		{"signature of other key", message, signMessage(t, other, message), account, false},
+		{"zero signature", message, make([]byte, 64), account, false},
+	}
+
+	for _, test := range tests {
+		code := pushCode(test.message)
+		code = append(code, pushCode(test.signature)...)
+		code = append(code, pushCode(test.account[:])...)
+		code = append(code, CHECKSIGADDR, HALT)
+
+		vm := NewTestVM([]byte{})
+		mc := NewMockContext(code)
+		mc.Fee = 100000
+		vm.context = mc
+
+		if !vm.Exec(false) {
+			t.Fatalf("%v: Execution failed: %v", test.name, vm.GetErrorMsg())
+		}
+
+		tos, _ := vm.evaluationStack.Pop()
+		if actual := ByteArrayToBool(tos); actual != test.expected {
+			t.Errorf("%v: Expected result to be '%v' but was '%v'", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestVM_Exec_CheckSigAddrErrors(t *testing.T) {
+	codes := map[string][]byte{
+		"checksigaddr: Not a valid account hash": {PUSH, 0, 1, PUSH, 0, 2, PUSH, 0, 3, CHECKSIGADDR, HALT},
+		"checksigaddr: Not a valid signature":    append(append([]byte{PUSH, 0, 1, PUSH, 0, 2}, pushCode(make([]byte, 32))...), CHECKSIGADDR, HALT),
+	}
+
+	for expected, code := range codes {
+		vm := NewTestVM([]byte{})
+		mc := NewMockContext(code)
+		mc.Fee = 100000
+		vm.context = mc
+
+		vm.Exec(false)
+		if actual := vm.GetErrorMsg(); actual != expected {
+			t.Errorf("Expected error message to be '%v' but was '%v'", expected, actual)
+		}
+	}
+}
+
+func TestVM_Exec_SelfDestruct(t *testing.T) {
+	beneficiary := [32]byte{2}
+	code := append(append([]byte{PUSH, 0, 7, PUSH, 31}, beneficiary[:]...), SELFDESTRUCT, PUSH, 0, 8, HALT)
+
+	vm := NewTestVM([]byte{})
+	mc := NewMockContext(code)
+	mc.To = [32]byte{1}
+	mc.Fee = 100000
+	vm.context = mc
+
+	if !vm.Exec(false) {
+		t.Fatalf("Expected the self-destruct to succeed but failed with '%v'", vm.GetErrorMsg())
+	}
+
+	expected := []protocol.SelfDestruct{{Account: [32]byte{1}, Beneficiary: beneficiary}}
+	if selfDestructs := mc.GetSelfDestructs(); !reflect.DeepEqual(selfDestructs, expected) {
+		t.Errorf("Expected self-destructs '%v' but were '%v'", expected, selfDestructs)
+	}
+
+	// The execution ends with the self-destruct
+	if stack := vm.GetStack(); !reflect.DeepEqual(stack, []protocol.ByteArray{{7}}) {
+		t.Errorf("Expected the stack from before the self-destruct but was '%v'", stack)
+	}
+}
+
+func TestVM_Exec_SelfDestruct_Errors(t *testing.T) {
+	self := [32]byte{1}
+	codes := map[string][]byte{
+		"selfdestruct: Contract can't be its own beneficiary": append(append([]byte{PUSH, 31}, self[:]...), SELFDESTRUCT),
+		"selfdestruct: Not a valid address":                   {PUSH, 0, 2, SELFDESTRUCT},
+		"selfdestruct: pop() on empty stack":                  {SELFDESTRUCT},
+	}
+
+	for expected, code := range codes {
+		vm := NewTestVM([]byte{})
+		mc := NewMockContext(code)
+		mc.To = self
+		mc.Fee = 100000
+		vm.context = mc
+
+		if vm.Exec(false) {
+			t.Errorf("Expected '%v' to fail", code)
+		}
+		if actual := vm.GetErrorMsg(); actual != expected {
+			t.Errorf("Expected error '%v' but was '%v'", expected, actual)
+		}
+		if len(mc.GetSelfDestructs()) != 0 {
+			t.Errorf("Expected no self-destruct, but got %v", mc.GetSelfDestructs())
+		}
+	}
+}